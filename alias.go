@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// expandAliases rewrites args when the command names an alias from the
+// config file's [alias] table, like git's: the alias's words replace the name.
+// They go first, so flags given on the command line win over the alias's own.
+// An alias may refer to other aliases; one whose expansion names itself is
+// expanded only once, so `home = "--limit 5 home"` sets defaults for a
+// command.
+func expandAliases(cfg *Config, args []string) ([]string, error) {
+	aliases := cfg.Table("alias")
+	seen := make(map[string]bool)
+	var chain []string
+	for {
+		i := commandIndex(args)
+		if i < 0 {
+			return args, nil
+		}
+		name := args[i]
+		value, ok := aliases[name]
+		if !ok {
+			return args, nil
+		}
+		if seen[name] {
+			if name == chain[len(chain)-1] {
+				return args, nil
+			}
+			return nil, fmt.Errorf("alias loop: %s → %s", strings.Join(chain, " → "), name)
+		}
+		seen[name] = true
+		chain = append(chain, name)
+
+		words, err := splitWords(value)
+		if err != nil {
+			return nil, fmt.Errorf("alias %s: %w", name, err)
+		}
+		if commandIndex(words) < 0 {
+			return nil, fmt.Errorf("alias %s does not name a command", name)
+		}
+		expanded := append(words, args[:i]...)
+		args = append(expanded, args[i+1:]...)
+	}
+}
+
+// splitWords splits s into words on whitespace, honoring single and double
+// quotes and backslash escapes the way a shell would.
+func splitWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord, escaped := false, false
+	var quote rune
+	for _, ch := range s {
+		switch {
+		case escaped:
+			word.WriteRune(ch)
+			escaped = false
+		case ch == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			} else {
+				word.WriteRune(ch)
+			}
+		case ch == '"' || ch == '\'':
+			quote, inWord = ch, true
+		case ch == ' ' || ch == '\t' || ch == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(ch)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
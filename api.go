@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// apiMethods are the HTTP methods the api command sends.
+var apiMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// callAPI sends a request the CLI has no command for, authenticated like
+// every other, and returns the response body. --param values go in the
+// query string, or in a form body for POST, PUT, and PATCH without --data.
+func callAPI(ctx context.Context, client *mastodon.Client, method, path string) (interface{}, error) {
+	method = strings.ToUpper(method)
+	valid := false
+	for _, m := range apiMethods {
+		valid = valid || m == method
+	}
+	if !valid {
+		return nil, fmt.Errorf("invalid method %q (want %s)", method, strings.Join(apiMethods, ", "))
+	}
+	if strings.Contains(path, "://") {
+		return nil, errors.New("give the path of the endpoint, such as /api/v1/instance; requests always go to --instance")
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	params := url.Values{}
+	for _, p := range flagParams {
+		key, value, ok := strings.Cut(p, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --param %q (want key=value)", p)
+		}
+		params.Add(key, value)
+	}
+	body, err := apiData(*flagData)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *mastodon.Response
+	switch {
+	case body != nil:
+		resp, err = client.DoJSON(ctx, method, withParams(path, params), body)
+	case method == http.MethodGet || method == http.MethodDelete:
+		resp, err = client.Do(ctx, method, withParams(path, params), nil)
+	default:
+		resp, err = client.Do(ctx, method, path, params)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Body) == 0 {
+		return nil, nil
+	}
+	if !json.Valid(resp.Body) {
+		return string(resp.Body), nil
+	}
+	return json.RawMessage(resp.Body), nil
+}
+
+// apiData reads --data: inline JSON, or @file, or @- for stdin. It returns
+// nil when there is none.
+func apiData(data string) ([]byte, error) {
+	if data == "" {
+		return nil, nil
+	}
+	body := []byte(data)
+	if name, ok := strings.CutPrefix(data, "@"); ok {
+		var err error
+		if name == "-" {
+			body, err = io.ReadAll(os.Stdin)
+		} else {
+			body, err = os.ReadFile(name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading --data: %w", err)
+		}
+	}
+	if !json.Valid(body) {
+		return nil, errors.New("--data is not valid JSON")
+	}
+	return body, nil
+}
+
+// withParams adds params to the query string of path.
+func withParams(path string, params url.Values) string {
+	if len(params) == 0 {
+		return path
+	}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + params.Encode()
+}
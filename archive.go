@@ -0,0 +1,523 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// archiveSchema creates the archive's tables. Posts and accounts are keyed
+// by their URI and URL, which are the same from every instance, so a post
+// seen on two instances is stored once; notifications only exist on the
+// instance that sent them. data holds the item's JSON as last fetched, and
+// first_seen and last_seen when it was fetched.
+const archiveSchema = `
+CREATE TABLE IF NOT EXISTS statuses (
+	uri        TEXT PRIMARY KEY,
+	instance   TEXT NOT NULL,
+	id         TEXT NOT NULL,
+	acct       TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	data       TEXT NOT NULL,
+	first_seen TEXT NOT NULL,
+	last_seen  TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS statuses_created_at ON statuses (created_at);
+CREATE INDEX IF NOT EXISTS statuses_acct ON statuses (acct);
+CREATE TABLE IF NOT EXISTS accounts (
+	url        TEXT PRIMARY KEY,
+	instance   TEXT NOT NULL,
+	id         TEXT NOT NULL,
+	acct       TEXT NOT NULL,
+	data       TEXT NOT NULL,
+	first_seen TEXT NOT NULL,
+	last_seen  TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS notifications (
+	instance   TEXT NOT NULL,
+	id         TEXT NOT NULL,
+	type       TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	data       TEXT NOT NULL,
+	first_seen TEXT NOT NULL,
+	last_seen  TEXT NOT NULL,
+	PRIMARY KEY (instance, id)
+);
+CREATE INDEX IF NOT EXISTS notifications_created_at ON notifications (created_at);
+`
+
+// archiveSearchSchema creates the full-text index of archived posts, one
+// row per post under the rowid of its statuses row. Boosts aren't indexed,
+// since the boosted post is archived, and indexed, in its own right.
+const archiveSearchSchema = `
+CREATE VIRTUAL TABLE IF NOT EXISTS statuses_fts USING fts5(
+	content, spoiler_text, alt_text, author,
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+`
+
+// archive is the local SQLite database that --archive stores every fetched
+// post, account, and notification in.
+type archive struct {
+	db   *sql.DB
+	path string
+	// instance is the host items are fetched from, which completes the
+	// addresses of its local accounts.
+	instance string
+}
+
+// activeArchive is where this run stores what it fetches, or nil without
+// --archive.
+var activeArchive *archive
+
+// archivePath is archive.db in $XDG_DATA_HOME/mastodon-scout, or
+// ~/.local/share/mastodon-scout.
+func archivePath() (string, error) {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("locating home directory: %w", err)
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, appName, "archive.db"), nil
+}
+
+// openArchive opens the archive, creating it readable only by you if it
+// doesn't exist yet.
+func openArchive(instance string) (*archive, error) {
+	if archiveDriver == "" {
+		return nil, errArchiveUnsupported
+	}
+	path, err := archivePath()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("creating archive directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening archive: %w", err)
+	}
+	f.Close()
+	// A watch and a one-off command may write at once; WAL lets readers
+	// carry on, and the busy timeout makes writers wait their turn.
+	db, err := sql.Open(archiveDriver, path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("opening archive: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(archiveSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("opening archive %s: %w", path, err)
+	}
+	a := &archive{db: db, path: path, instance: instanceHost(instance)}
+	if err := a.createSearchIndex(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("indexing archive %s: %w", path, err)
+	}
+	return a, nil
+}
+
+// createSearchIndex creates the full-text index if the archive doesn't
+// have one yet, indexing the posts archived before it existed.
+func (a *archive) createSearchIndex() error {
+	var n int
+	if err := a.db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE name = 'statuses_fts'`).Scan(&n); err != nil || n > 0 {
+		return err
+	}
+	tx, err := a.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(archiveSearchSchema); err != nil {
+		return err
+	}
+	rows, err := tx.Query(`SELECT rowid, data FROM statuses`)
+	if err != nil {
+		return err
+	}
+	type post struct {
+		rowid int64
+		s     mastodon.Status
+	}
+	var posts []post
+	for rows.Next() {
+		var p post
+		var data string
+		if err := rows.Scan(&p.rowid, &data); err != nil {
+			rows.Close()
+			return err
+		}
+		if json.Unmarshal([]byte(data), &p.s) == nil {
+			posts = append(posts, p)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, p := range posts {
+		if err := indexStatus(tx, p.rowid, p.s); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// indexStatus replaces the full-text index entry of the post stored under
+// rowid.
+func indexStatus(tx *sql.Tx, rowid int64, s mastodon.Status) error {
+	if _, err := tx.Exec(`DELETE FROM statuses_fts WHERE rowid = ?`, rowid); err != nil || s.Reblog != nil {
+		return err
+	}
+	var alt []string
+	for _, m := range s.MediaAttachments {
+		if m.Description != nil && *m.Description != "" {
+			alt = append(alt, *m.Description)
+		}
+	}
+	_, err := tx.Exec(`INSERT INTO statuses_fts (rowid, content, spoiler_text, alt_text, author) VALUES (?, ?, ?, ?, ?)`,
+		rowid, s.ContentText(), s.SpoilerText, strings.Join(alt, "\n"), s.Account.Acct+" "+s.Account.DisplayName)
+	return err
+}
+
+// archiveItems stores the posts, accounts, and notifications in data in
+// the active archive, if there is one. A failure is only a warning, since
+// the command itself worked.
+func archiveItems(data interface{}) {
+	if activeArchive == nil || data == nil {
+		return
+	}
+	if err := activeArchive.store(data); err != nil {
+		notef("Warning: archiving: %v\n", err)
+	}
+}
+
+// store adds or updates everything in data in one transaction.
+func (a *archive) store(data interface{}) error {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return err
+	}
+	w := archiveWriter{archive: a, tx: tx, now: time.Now().UTC().Format(time.RFC3339)}
+	if err := w.add(data); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// archiveWriter stores items within one transaction.
+type archiveWriter struct {
+	*archive
+	tx  *sql.Tx
+	now string
+}
+
+// add stores the items data holds, along with the accounts and posts they
+// carry: a boost's post, a notification's account and post, and the like.
+// Other results are ignored.
+func (w *archiveWriter) add(data interface{}) error {
+	switch v := data.(type) {
+	case mastodon.Status:
+		return w.status(v)
+	case *mastodon.Status:
+		if v != nil {
+			return w.status(*v)
+		}
+	case []mastodon.Status:
+		for _, s := range v {
+			if err := w.status(s); err != nil {
+				return err
+			}
+		}
+	case mastodon.Account:
+		return w.account(v)
+	case []mastodon.Account:
+		for _, acc := range v {
+			if err := w.account(acc); err != nil {
+				return err
+			}
+		}
+	case mastodon.Notification:
+		return w.notification(v)
+	case []mastodon.Notification:
+		for _, n := range v {
+			if err := w.notification(n); err != nil {
+				return err
+			}
+		}
+	case []mastodon.Conversation:
+		for _, c := range v {
+			if err := w.add(c.Accounts); err != nil {
+				return err
+			}
+			if err := w.add(c.LastStatus); err != nil {
+				return err
+			}
+		}
+	case mastodon.SearchResult:
+		if err := w.add(v.Statuses); err != nil {
+			return err
+		}
+		return w.add(v.Accounts)
+	case AccountProfile:
+		if err := w.account(v.Account); err != nil {
+			return err
+		}
+		return w.add(v.Pinned)
+	}
+	return nil
+}
+
+func (w *archiveWriter) status(s mastodon.Status) error {
+	if s.Reblog != nil {
+		if err := w.status(*s.Reblog); err != nil {
+			return err
+		}
+	}
+	if err := w.account(s.Account); err != nil {
+		return err
+	}
+	uri := s.URI
+	if uri == "" && s.Reblog == nil {
+		// A boost's URL, where there is one, is the boosted post's.
+		uri = s.URL
+	}
+	if uri == "" {
+		uri = w.instance + "/" + s.ID
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	var rowid int64
+	err = w.tx.QueryRow(`INSERT INTO statuses (uri, instance, id, acct, created_at, data, first_seen, last_seen)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (uri) DO UPDATE SET data = excluded.data, last_seen = excluded.last_seen
+		RETURNING rowid`,
+		uri, w.instance, s.ID, w.qualify(s.Account.Acct), s.CreatedAt, string(data), w.now, w.now).Scan(&rowid)
+	if err != nil {
+		return err
+	}
+	// An edited post is reindexed with its new text.
+	return indexStatus(w.tx, rowid, s)
+}
+
+func (w *archiveWriter) account(acc mastodon.Account) error {
+	key := acc.URL
+	if key == "" {
+		key = w.qualify(acc.Acct)
+	}
+	data, err := json.Marshal(acc)
+	if err != nil {
+		return err
+	}
+	_, err = w.tx.Exec(`INSERT INTO accounts (url, instance, id, acct, data, first_seen, last_seen)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (url) DO UPDATE SET data = excluded.data, last_seen = excluded.last_seen`,
+		key, w.instance, acc.ID, w.qualify(acc.Acct), string(data), w.now, w.now)
+	return err
+}
+
+func (w *archiveWriter) notification(n mastodon.Notification) error {
+	if err := w.account(n.Account); err != nil {
+		return err
+	}
+	if err := w.add(n.Status); err != nil {
+		return err
+	}
+	data, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	_, err = w.tx.Exec(`INSERT INTO notifications (instance, id, type, created_at, data, first_seen, last_seen)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (instance, id) DO UPDATE SET data = excluded.data, last_seen = excluded.last_seen`,
+		w.instance, n.ID, n.Type, n.CreatedAt, string(data), w.now, w.now)
+	return err
+}
+
+// qualify completes the address of a local account, which the API gives
+// without a domain.
+func (a *archive) qualify(acct string) string {
+	if acct == "" || strings.Contains(acct, "@") || a.instance == "" {
+		return acct
+	}
+	return acct + "@" + a.instance
+}
+
+// ArchiveStats is the result of archive stats.
+type ArchiveStats struct {
+	Path          string `json:"path"`
+	Size          int64  `json:"size"`
+	Posts         int    `json:"posts"`
+	Accounts      int    `json:"accounts"`
+	Notifications int    `json:"notifications"`
+	// Oldest and Newest are the creation times of the archived posts.
+	Oldest string `json:"oldest,omitempty"`
+	Newest string `json:"newest,omitempty"`
+}
+
+// runArchive handles "archive stats", "archive posts [account]", "archive
+// accounts", "archive notifications", and "archive search <query>". They
+// read the archive only, so they work offline and without a token.
+func runArchive(ctx context.Context, args []string) (interface{}, error) {
+	sub := "stats"
+	if len(args) > 0 {
+		sub, args = args[0], args[1:]
+	}
+	a, err := openArchive(*flagInstanceURL)
+	if err != nil {
+		return nil, err
+	}
+	defer a.db.Close()
+	switch sub {
+	case "stats":
+		return a.stats(ctx)
+	case "posts":
+		if len(args) > 1 {
+			return nil, fmt.Errorf("archive posts takes one account; unexpected %q", args[1])
+		}
+		var acct string
+		if len(args) == 1 {
+			acct = strings.TrimPrefix(args[0], "@")
+		}
+		return a.posts(ctx, acct)
+	case "accounts":
+		return archiveRows[mastodon.Account](ctx, a, `SELECT data FROM accounts ORDER BY last_seen DESC, acct`)
+	case "notifications":
+		return archiveRows[mastodon.Notification](ctx, a, `SELECT data FROM notifications ORDER BY created_at DESC, id DESC`)
+	case "search":
+		if len(args) == 0 {
+			return nil, errors.New("archive search requires a query")
+		}
+		return a.search(ctx, strings.Join(args, " "))
+	default:
+		return nil, fmt.Errorf("unknown archive subcommand: %s", sub)
+	}
+}
+
+func (a *archive) stats(ctx context.Context) (ArchiveStats, error) {
+	stats := ArchiveStats{Path: a.path}
+	// Recent writes sit in the write-ahead log until SQLite checkpoints.
+	for _, path := range []string{a.path, a.path + "-wal"} {
+		if fi, err := os.Stat(path); err == nil {
+			stats.Size += fi.Size()
+		}
+	}
+	var oldest, newest sql.NullString
+	err := a.db.QueryRowContext(ctx, `SELECT
+		(SELECT count(*) FROM statuses), (SELECT count(*) FROM accounts), (SELECT count(*) FROM notifications),
+		(SELECT min(created_at) FROM statuses), (SELECT max(created_at) FROM statuses)`).
+		Scan(&stats.Posts, &stats.Accounts, &stats.Notifications, &oldest, &newest)
+	stats.Oldest, stats.Newest = oldest.String, newest.String
+	return stats, err
+}
+
+// posts lists archived posts, newest first, by acct if it is given. An
+// address without a domain matches the account on any instance.
+func (a *archive) posts(ctx context.Context, acct string) ([]mastodon.Status, error) {
+	if acct == "" {
+		return archiveRows[mastodon.Status](ctx, a, `SELECT data FROM statuses ORDER BY created_at DESC, id DESC`)
+	}
+	return archiveRows[mastodon.Status](ctx, a, `SELECT data FROM statuses WHERE acct = ? OR acct LIKE ? ESCAPE '\'
+		ORDER BY created_at DESC, id DESC`, acct, likePrefix(acct)+"@%")
+}
+
+// search finds archived posts whose text, content warning, alt text, or
+// author matches query, best matches first.
+func (a *archive) search(ctx context.Context, query string) ([]mastodon.Status, error) {
+	match := searchMatch(query)
+	if match == "" {
+		return nil, errors.New("archive search requires a query")
+	}
+	return archiveRows[mastodon.Status](ctx, a, `SELECT s.data FROM statuses_fts f JOIN statuses s ON s.rowid = f.rowid
+		WHERE statuses_fts MATCH ? ORDER BY f.rank, s.created_at DESC`, match)
+}
+
+// searchMatch turns a query into an FTS5 expression that every word, or
+// "quoted phrase", must match. Words are quoted, so punctuation in them is
+// searched for rather than read as query syntax; a trailing * still matches
+// any word the rest begins.
+func searchMatch(query string) string {
+	var terms []string
+	for len(query) > 0 {
+		query = strings.TrimLeft(query, " \t\n")
+		if query == "" {
+			break
+		}
+		var term string
+		if query[0] == '"' {
+			end := strings.IndexByte(query[1:], '"')
+			if end < 0 {
+				end = len(query) - 1
+			}
+			term, query = query[1:end+1], query[min(end+2, len(query)):]
+		} else {
+			end := strings.IndexAny(query, " \t\n")
+			if end < 0 {
+				end = len(query)
+			}
+			term, query = query[:end], query[end:]
+		}
+		prefix := strings.HasSuffix(term, "*")
+		term = strings.TrimRight(term, "*")
+		if strings.TrimSpace(term) == "" {
+			continue
+		}
+		term = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+		if prefix {
+			term += "*"
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, " ")
+}
+
+// likePrefix escapes the wildcards of a LIKE pattern in s.
+func likePrefix(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// archiveRows decodes the data column of query's rows, up to --limit of
+// them unless --all is set.
+func archiveRows[T any](ctx context.Context, a *archive, query string, args ...interface{}) ([]T, error) {
+	limit := pageOptions().Limit
+	if *flagAll || limit <= 0 {
+		limit = -1
+	}
+	rows, err := a.db.QueryContext(ctx, query+" LIMIT ?", append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []T{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var item T
+		if err := json.Unmarshal([]byte(data), &item); err != nil {
+			return nil, fmt.Errorf("reading archive: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// errArchiveUnsupported is returned where no SQLite driver builds.
+var errArchiveUnsupported = errors.New("the archive isn't available on this platform")
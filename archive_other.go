@@ -0,0 +1,7 @@
+//go:build !darwin && !windows && !linux && !freebsd && !openbsd && !netbsd
+
+package main
+
+// archiveDriver is empty where the SQLite driver doesn't build, and
+// --archive and the archive command fail.
+const archiveDriver = ""
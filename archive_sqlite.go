@@ -0,0 +1,8 @@
+//go:build darwin || windows || linux || freebsd || openbsd || netbsd
+
+package main
+
+import _ "modernc.org/sqlite" // pure Go, so builds stay CGO_ENABLED=0
+
+// archiveDriver is the database/sql driver the archive is opened with.
+const archiveDriver = "sqlite"
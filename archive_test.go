@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+func TestArchiveReplay(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	a, err := openArchive("https://example.social")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.db.Close()
+	// The same posts fetched twice are stored once.
+	for _, args := range [][]string{{"home"}, {"home"}, {"notifications"}} {
+		if err := a.store(runCommand(t, args...)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+	stats, err := a.stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 20 posts from home, the post one of them boosts, and the one a
+	// mention is about.
+	if stats.Posts != 22 || stats.Accounts != 1 || stats.Notifications != 2 {
+		t.Errorf("got %+v, want 22 posts, 1 account, and 2 notifications", stats)
+	}
+
+	setFlags(t, map[string]string{"limit": "3"})
+	for _, acct := range []string{"", "alice", "alice@example.social"} {
+		posts, err := a.posts(ctx, acct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(posts) != 3 || posts[0].Account.Acct != "alice" {
+			t.Errorf("posts(%q) = %d posts, want alice's newest 3", acct, len(posts))
+		}
+	}
+	if posts, err := a.posts(ctx, "bob"); err != nil || len(posts) != 0 {
+		t.Errorf("posts(bob) = %d posts, %v; want none", len(posts), err)
+	}
+	accounts, err := archiveRows[mastodon.Account](ctx, a, `SELECT data FROM accounts`)
+	if err != nil || len(accounts) != 1 || accounts[0].Acct != "alice" {
+		t.Errorf("got accounts %+v, %v; want alice", accounts, err)
+	}
+}
+
+func TestArchiveSearchReplay(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	a, err := openArchive("https://example.social")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.store(runCommand(t, "home")); err != nil {
+		t.Fatal(err)
+	}
+	// An archive from before the index existed is indexed when opened.
+	if _, err := a.db.Exec(`DROP TABLE statuses_fts`); err != nil {
+		t.Fatal(err)
+	}
+	a.db.Close()
+	if a, err = openArchive("https://example.social"); err != nil {
+		t.Fatal(err)
+	}
+	defer a.db.Close()
+
+	tests := []struct {
+		query   string
+		wantIDs []string
+	}{
+		{"hallo", []string{"997"}},
+		{"RED squ*", []string{"998"}},   // alt text, by prefix
+		{`"Post 998"`, []string{"998"}}, // a phrase
+		{"post 998 more", []string{"998"}},
+		{"boosted spam", []string{"5"}}, // the boosted post, not the boost
+		{"kubernetes", nil},
+		{"foo-bar (", nil}, // punctuation isn't query syntax
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			posts, err := a.search(context.Background(), tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, s := range posts {
+				ids = append(ids, s.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.wantIDs) {
+				t.Errorf("got %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestSearchMatch(t *testing.T) {
+	tests := []struct{ query, want string }{
+		{"kubernetes outage", `"kubernetes" "outage"`},
+		{`"kubernetes outage" postmortem`, `"kubernetes outage" "postmortem"`},
+		{"upgrad* ", `"upgrad"*`},
+		{`say "hi`, `"say" "hi"`},
+		{`a"b`, `"a""b"`},
+		{" * ", ""},
+	}
+	for _, tt := range tests {
+		if got := searchMatch(tt.query); got != tt.want {
+			t.Errorf("searchMatch(%q) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
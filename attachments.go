@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // decoders for kitty previews
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+const (
+	// maxMediaSize bounds a single download so a huge video can't fill
+	// the disk unnoticed.
+	maxMediaSize = 200 << 20
+	// maxPreviewSize bounds the thumbnails fetched for inline previews.
+	maxPreviewSize = 5 << 20
+	// previewColumns is the width of inline previews in terminal cells.
+	previewColumns = 40
+)
+
+// downloadMedia saves every attachment of the posts in data to dir as
+// <status id>-<attachment id>.<ext>. Files that already exist are skipped,
+// so repeating a command only fetches what is new.
+func downloadMedia(ctx context.Context, data interface{}, dir string) error {
+	statuses, ok := resultStatuses(data)
+	if !ok {
+		return errors.New("--download-media needs a command that returns posts")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating media directory: %w", err)
+	}
+	saved := 0
+	for _, s := range statuses {
+		post, _ := resolvePost(s)
+		for _, m := range post.MediaAttachments {
+			if m.URL == "" {
+				continue
+			}
+			name := filepath.Join(dir, post.ID+"-"+m.ID+mediaExt(m.URL))
+			if _, err := os.Stat(name); err == nil {
+				continue
+			}
+			if err := saveMedia(ctx, m.URL, name); err != nil {
+				return err
+			}
+			saved++
+		}
+	}
+	notef("Saved %d attachment(s) to %s\n", saved, dir)
+	return nil
+}
+
+// saveMedia downloads rawURL to name, writing through a temporary file so an
+// interrupted download leaves nothing behind.
+func saveMedia(ctx context.Context, rawURL, name string) error {
+	body, err := fetchMedia(ctx, rawURL, maxMediaSize)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".download-*")
+	if err != nil {
+		return fmt.Errorf("saving %s: %w", rawURL, err)
+	}
+	// Attachments are public, so they get ordinary file permissions.
+	err = tmp.Chmod(0o644)
+	var n int64
+	if err == nil {
+		n, err = io.Copy(tmp, io.LimitReader(body, maxMediaSize+1))
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > maxMediaSize {
+		err = fmt.Errorf("larger than %d MB", maxMediaSize>>20)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("saving %s: %w", rawURL, err)
+	}
+	return nil
+}
+
+// fetchMedia starts a GET of a media file with the shared HTTP client, so
+// downloads honor --proxy and the TLS flags.
+func fetchMedia(ctx context.Context, rawURL string, limit int64) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("downloading %s: %w", rawURL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("downloading %s: %s", rawURL, resp.Status)
+	}
+	if resp.ContentLength > limit {
+		resp.Body.Close()
+		return nil, fmt.Errorf("downloading %s: larger than %d MB", rawURL, limit>>20)
+	}
+	return resp.Body, nil
+}
+
+// mediaExt returns the file extension of a media URL, or "" if it has none.
+func mediaExt(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	ext := path.Ext(u.Path)
+	if len(ext) > 6 {
+		return ""
+	}
+	return ext
+}
+
+// previewProtocol names the inline image protocol the terminal speaks, or ""
+// when previews are off or unsupported.
+func previewProtocol() string {
+	if !*flagPreview || !isTerminal(os.Stdout) {
+		return ""
+	}
+	switch {
+	case os.Getenv("KITTY_WINDOW_ID") != "" || os.Getenv("TERM") == "xterm-kitty":
+		return "kitty"
+	case os.Getenv("TERM_PROGRAM") == "iTerm.app" || os.Getenv("TERM_PROGRAM") == "WezTerm":
+		return "iterm2"
+	}
+	return ""
+}
+
+// printPreview draws an image attachment's thumbnail inline. Failures are
+// silent: a preview is a nicety and the attachment is listed either way.
+func printPreview(m mastodon.MediaAttachment) {
+	proto := previewProtocol()
+	if proto == "" || (m.Type != "image" && m.Type != "gifv" && m.Type != "video") || m.PreviewURL == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(runContext, 10*time.Second)
+	defer cancel()
+	body, err := fetchMedia(ctx, m.PreviewURL, maxPreviewSize)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+	data, err := io.ReadAll(io.LimitReader(body, maxPreviewSize))
+	if err != nil {
+		return
+	}
+
+	if proto == "iterm2" {
+		fmt.Printf("\x1b]1337;File=inline=1;width=%d;preserveAspectRatio=1:%s\a\n",
+			previewColumns, base64.StdEncoding.EncodeToString(data))
+		return
+	}
+	// kitty only takes PNG, so decode and re-encode the thumbnail.
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	var buf bytes.Buffer
+	if png.Encode(&buf, img) != nil {
+		return
+	}
+	payload := base64.StdEncoding.EncodeToString(buf.Bytes())
+	var b strings.Builder
+	for first := true; len(payload) > 0; first = false {
+		chunk := payload[:min(4096, len(payload))]
+		payload = payload[len(chunk):]
+		more := 0
+		if len(payload) > 0 {
+			more = 1
+		}
+		if first {
+			fmt.Fprintf(&b, "\x1b_Gf=100,a=T,c=%d,m=%d;%s\x1b\\", previewColumns, more, chunk)
+		} else {
+			fmt.Fprintf(&b, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	fmt.Println(b.String())
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// AltTextAudit reports which of the user's recent posts have media without
+// a description.
+type AltTextAudit struct {
+	Posts       int            `json:"posts"`
+	Attachments int            `json:"attachments"`
+	Missing     int            `json:"missing"`
+	Issues      []AltTextIssue `json:"issues"`
+}
+
+// AltTextIssue is a post with attachments that lack alt text.
+type AltTextIssue struct {
+	ID        string `json:"id"`
+	URL       string `json:"url"`
+	CreatedAt string `json:"created_at"`
+	Text      string `json:"text"`
+	// Media are the attachments without a description.
+	Media []mastodon.MediaAttachment `json:"media"`
+	Total int                        `json:"total"`
+}
+
+// runAudit handles "audit [alt-text]".
+func runAudit(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+	if len(args) > 0 && args[0] != "alt-text" {
+		return nil, fmt.Errorf("unknown audit: %s (want alt-text)", args[0])
+	}
+	return auditAltText(ctx, client)
+}
+
+// auditAltText checks the user's recent posts with media (as many as
+// --limit or --all ask for, boosts left out) for attachments without alt
+// text.
+func auditAltText(ctx context.Context, client *mastodon.Client) (interface{}, error) {
+	me, err := client.VerifyCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := client.AccountStatuses(ctx, me.ID, mastodon.AccountStatusFilter{OnlyMedia: true, ExcludeReblogs: true}, pageOptions())
+	if err != nil {
+		return nil, err
+	}
+	audit := AltTextAudit{Posts: len(posts), Issues: []AltTextIssue{}}
+	for _, post := range posts {
+		issue := AltTextIssue{ID: post.ID, URL: post.URL, CreatedAt: post.CreatedAt, Text: post.ContentText(), Total: len(post.MediaAttachments)}
+		for _, m := range post.MediaAttachments {
+			if m.Description == nil || strings.TrimSpace(*m.Description) == "" {
+				issue.Media = append(issue.Media, m)
+			}
+		}
+		audit.Attachments += issue.Total
+		if len(issue.Media) > 0 {
+			audit.Missing += len(issue.Media)
+			audit.Issues = append(audit.Issues, issue)
+		}
+	}
+	return audit, nil
+}
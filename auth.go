@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+const (
+	appName       = "mastodon-scout"
+	appWebsite    = "https://github.com/patelhiren/mastodon-scout"
+	loginDeadline = 5 * time.Minute
+)
+
+// LoginResult is what the login command reports; the token itself is never
+// printed.
+type LoginResult struct {
+	Profile  string           `json:"profile,omitempty"`
+	Instance string           `json:"instance"`
+	Account  mastodon.Account `json:"account"`
+	Scope    string           `json:"scope"`
+	SavedTo  string           `json:"saved_to"`
+}
+
+// login registers an application, walks the user through the authorization
+// code flow (with PKCE), and stores the resulting token.
+func login(ctx context.Context) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, loginDeadline)
+	defer cancel()
+
+	redirectURI := mastodon.OOBRedirectURI
+	var listener net.Listener
+	if !*flagNoBrowser {
+		var err error
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, fmt.Errorf("starting callback listener: %w", err)
+		}
+		defer listener.Close()
+		redirectURI = fmt.Sprintf("http://%s/callback", listener.Addr())
+	}
+
+	if err := probeInstance(ctx); err != nil {
+		return nil, err
+	}
+	anon := newClient("")
+	app, err := anon.RegisterApp(ctx, appName, redirectURI, *flagScopes, appWebsite)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier, challenge, err := pkcePair()
+	if err != nil {
+		return nil, err
+	}
+	state, _, err := pkcePair()
+	if err != nil {
+		return nil, err
+	}
+
+	authURL := anon.AuthorizeURL(app, redirectURI, *flagScopes, state, challenge)
+
+	var code string
+	if listener != nil {
+		fmt.Fprintf(os.Stderr, "Opening your browser to authorize mastodon-scout. If it does not open, visit:\n\n  %s\n\n", authURL)
+		openBrowser(authURL)
+		code, err = awaitCallback(ctx, listener, state)
+	} else {
+		fmt.Fprintf(os.Stderr, "Visit this URL to authorize mastodon-scout:\n\n  %s\n\nPaste the authorization code: ", authURL)
+		code, err = readLine()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tok, err := anon.ExchangeCode(ctx, app, redirectURI, *flagScopes, code, verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := newClient(tok.AccessToken).VerifyCredentials(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("verifying new token: %w", err)
+	}
+
+	// A new --account name becomes a config entry so later runs can select it.
+	if activeAccount == nil && *flagAccount != "" {
+		if _, err := setConfigValue("accounts."+*flagAccount, "instance", *flagInstanceURL); err != nil {
+			return nil, err
+		}
+		activeAccount = &AccountConfig{Name: *flagAccount, Instance: *flagInstanceURL}
+	}
+
+	path, err := saveCredential(credentialKey(), Credential{
+		AccessToken: tok.AccessToken,
+		Scope:       tok.Scope,
+		Account:     account.Acct,
+		CreatedAt:   time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := LoginResult{Instance: *flagInstanceURL, Account: *account, Scope: tok.Scope, SavedTo: path}
+	if activeAccount != nil {
+		result.Profile = activeAccount.Name
+	}
+	return result, nil
+}
+
+// pkcePair returns a random PKCE verifier and its S256 challenge.
+func pkcePair() (verifier, challenge string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("generating PKCE verifier: %w", err)
+	}
+	verifier = base64.RawURLEncoding.EncodeToString(buf)
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// awaitCallback serves the loopback redirect and returns the authorization
+// code once the browser is sent back.
+func awaitCallback(ctx context.Context, listener net.Listener, state string) (string, error) {
+	type result struct {
+		code string
+		err  error
+	}
+	results := make(chan result, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/callback" {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		var res result
+		switch {
+		case q.Get("error") != "":
+			res.err = fmt.Errorf("authorization denied: %s", q.Get("error_description"))
+		case q.Get("state") != state:
+			res.err = errors.New("authorization callback state mismatch")
+		case q.Get("code") == "":
+			res.err = errors.New("authorization callback did not include a code")
+		default:
+			res.code = q.Get("code")
+		}
+		if res.err != nil {
+			http.Error(w, res.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "mastodon-scout is authorized. You can close this window.")
+		}
+		select {
+		case results <- res:
+		default:
+		}
+	})}
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	select {
+	case res := <-results:
+		return res.code, res.err
+	case <-ctx.Done():
+		return "", errors.New("timed out waiting for authorization")
+	}
+}
+
+// WhoAmI is the identity and permissions behind the current token.
+type WhoAmI struct {
+	ID          string `json:"id"`
+	Acct        string `json:"acct"`
+	DisplayName string `json:"display_name"`
+	URL         string `json:"url"`
+	Instance    string `json:"instance"`
+	Profile     string `json:"profile,omitempty"`
+	App         string `json:"app,omitempty"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// whoami reports the account the token belongs to and the scopes it was
+// granted. Instances before Mastodon 4.3 don't report scopes, so for those
+// they come from the record `login` saved, when the token is a stored one.
+func whoami(ctx context.Context, client *mastodon.Client) (interface{}, error) {
+	account, err := client.VerifyCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := WhoAmI{
+		ID:          account.ID,
+		Acct:        account.Acct,
+		DisplayName: account.DisplayName,
+		URL:         account.URL,
+		Instance:    client.BaseURL(),
+	}
+	if !strings.Contains(result.Acct, "@") {
+		if u, err := url.Parse(result.Instance); err == nil && u.Host != "" {
+			result.Acct += "@" + u.Host
+		}
+	}
+	if activeAccount != nil {
+		result.Profile = activeAccount.Name
+	}
+	if app, err := client.VerifyAppCredentials(ctx); err == nil {
+		result.App = app.Name
+		result.Scope = strings.Join(app.Scopes, " ")
+	}
+	if result.Scope == "" {
+		result.Scope = storedScope()
+	}
+	return result, nil
+}
+
+// storedScope returns the scopes login recorded for the token this run
+// uses, if it is a stored one.
+func storedScope() string {
+	creds, err := loadCredentials()
+	if err != nil {
+		return ""
+	}
+	token := activeToken
+	keys := []string{*flagInstanceURL}
+	if activeAccount != nil {
+		keys = append([]string{activeAccount.Name}, keys...)
+	}
+	for _, key := range keys {
+		if cred, ok := creds[key]; ok && token != "" && storedToken(key) == token {
+			return cred.Scope
+		}
+	}
+	return ""
+}
+
+func readLine() (string, error) {
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	line = strings.TrimSpace(line)
+	if line == "" {
+		if err != nil {
+			return "", fmt.Errorf("reading authorization code: %w", err)
+		}
+		return "", errors.New("no authorization code entered")
+	}
+	return line, nil
+}
+
+// openBrowser makes a best-effort attempt to open u in the default browser.
+func openBrowser(u string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", u)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+	default:
+		cmd = exec.Command("xdg-open", u)
+	}
+	if err := cmd.Start(); err == nil {
+		go cmd.Wait()
+	}
+}
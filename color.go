@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultTheme gives each highlighted element of text output its style. The
+// [theme] table in config.toml overrides entries with the same names:
+//
+//	[theme]
+//	username = "bold green"
+//	link = "38;5;75"     # raw SGR parameters work too
+//	count = "none"       # leave counts uncolored
+var defaultTheme = map[string]string{
+	"header":    "bold",
+	"username":  "bold cyan",
+	"hashtag":   "magenta",
+	"mention":   "cyan",
+	"link":      "blue underline",
+	"cw":        "bold yellow",
+	"count":     "green",
+	"timestamp": "dim",
+}
+
+// sgrCodes maps style words to ANSI SGR parameters.
+var sgrCodes = map[string]string{
+	"bold": "1", "dim": "2", "italic": "3", "underline": "4",
+	"black": "30", "red": "31", "green": "32", "yellow": "33",
+	"blue": "34", "magenta": "35", "cyan": "36", "white": "37",
+	"bright-black": "90", "bright-red": "91", "bright-green": "92", "bright-yellow": "93",
+	"bright-blue": "94", "bright-magenta": "95", "bright-cyan": "96", "bright-white": "97",
+}
+
+// colorTheme holds the escape sequence for each theme entry; nil when output
+// is not colored.
+var colorTheme map[string]string
+
+// configureColor decides from --color, NO_COLOR, and whether stdout is a
+// terminal if text output is colored, and loads the theme.
+func configureColor(cfg *Config) error {
+	var on bool
+	switch *flagColor {
+	case "always":
+		on = true
+	case "never":
+		on = false
+	case "auto":
+		on = os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" && isTerminal(os.Stdout)
+	default:
+		return fmt.Errorf("invalid --color %q (want auto, always, or never)", *flagColor)
+	}
+	if !on || outputFormat() != "text" || outputTemplate != nil {
+		return nil
+	}
+
+	styles := make(map[string]string, len(defaultTheme))
+	for name, style := range defaultTheme {
+		styles[name] = style
+	}
+	for name, style := range cfg.Table("theme") {
+		if _, ok := defaultTheme[name]; !ok {
+			return fmt.Errorf("unknown theme entry %q (known: %s)", name, strings.Join(themeNames(), ", "))
+		}
+		styles[name] = style
+	}
+	colorTheme = make(map[string]string, len(styles))
+	for name, style := range styles {
+		seq, err := sgrSequence(style)
+		if err != nil {
+			return fmt.Errorf("theme %s: %w", name, err)
+		}
+		colorTheme[name] = seq
+	}
+	return nil
+}
+
+func themeNames() []string {
+	names := make([]string, 0, len(defaultTheme))
+	for name := range defaultTheme {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sgrSequence turns a style such as "bold cyan" or "38;5;208" into an
+// escape sequence. "none" and "" give no styling.
+func sgrSequence(style string) (string, error) {
+	var params []string
+	for _, word := range strings.Fields(strings.ToLower(style)) {
+		if word == "none" {
+			continue
+		}
+		if code, ok := sgrCodes[word]; ok {
+			params = append(params, code)
+			continue
+		}
+		for _, p := range strings.Split(word, ";") {
+			if _, err := strconv.ParseUint(p, 10, 8); err != nil {
+				return "", fmt.Errorf("unknown style %q", word)
+			}
+		}
+		params = append(params, word)
+	}
+	if len(params) == 0 {
+		return "", nil
+	}
+	return "\x1b[" + strings.Join(params, ";") + "m", nil
+}
+
+// isTerminal reports whether f is a character device such as a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// paint styles s as the theme entry name, or returns it unchanged when
+// output is not colored.
+func paint(name, s string) string {
+	seq := colorTheme[name]
+	if seq == "" || s == "" {
+		return s
+	}
+	return seq + s + "\x1b[0m"
+}
+
+// highlightPattern finds links, hashtags, and mentions in plain-text content.
+var highlightPattern = regexp.MustCompile(`https?://\S+|\B#[\p{L}\p{N}_]+|\B@[\w.]+(?:@[\w.-]*\w)?`)
+
+// highlight colors the links, hashtags, and mentions in post text.
+func highlight(text string) string {
+	if colorTheme == nil {
+		return text
+	}
+	return highlightPattern.ReplaceAllStringFunc(text, func(m string) string {
+		switch m[0] {
+		case '#':
+			return paint("hashtag", m)
+		case '@':
+			return paint("mention", m)
+		}
+		return paint("link", m)
+	})
+}
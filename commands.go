@@ -0,0 +1,951 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// command describes one subcommand: its arguments, the flags it accepts, and
+// how it runs.
+type command struct {
+	Name    string
+	Aliases []string
+	Args    string // argument synopsis for usage output
+	Summary string
+	Help    string // extra detail for `help <command>`, if any
+
+	// Examples are command lines for `help <command>` and the man pages,
+	// without the leading "mastodon-scout".
+	Examples []string
+
+	// Flags lists the command's own flags; the global flags are always
+	// accepted.
+	Flags []string
+
+	// MinArgs is the number of arguments required, described by Requires
+	// in the error when they are missing.
+	MinArgs  int
+	Requires string
+
+	// Subcommands take their first argument as an action name, which
+	// selects the output format ("lists add"); DefaultSub applies when it
+	// is omitted.
+	Subcommands bool
+	DefaultSub  string
+
+	NoAuth      bool // runs before a token is required, with no --timeout
+	Anonymous   bool // works without a token on most instances
+	Streaming   bool // runs until interrupted, with no --timeout, printing as it goes
+	LongRunning bool // makes as many requests as it takes, with no --timeout
+	Listing     bool // returns a listing as fetched, so --all can print it as it is decoded
+	ReadOnly    bool // only reads, so serve answers it on GET
+
+	// ReadActions are the actions of a command with subcommands that only
+	// read, "" being the command alone; its other actions need POST.
+	ReadActions []string
+
+	Run func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error)
+}
+
+// maxArgs is the most arguments the command takes, counted from Args: one
+// per word, or any number (-1) when a word repeats ("<file...>") or is
+// text, or when the command has subcommands, which take their own.
+func (c *command) maxArgs() int {
+	if c.Subcommands {
+		return -1
+	}
+	words := strings.Fields(c.Args)
+	for _, w := range words {
+		if strings.Contains(w, "...") || strings.Contains(w, "text") {
+			return -1
+		}
+	}
+	return len(words)
+}
+
+// checkArgs reports a missing or unexpected argument.
+func (c *command) checkArgs(args []string) error {
+	if len(args) < c.MinArgs {
+		return fmt.Errorf("%s command requires %s", c.Name, c.Requires)
+	}
+	if max := c.maxArgs(); max >= 0 && len(args) > max {
+		if max == 0 {
+			return fmt.Errorf("%s command takes no arguments; unexpected %q", c.Name, args[0])
+		}
+		return fmt.Errorf("%s command takes %s; unexpected %q (quote text with spaces)", c.Name, c.Args, args[max])
+	}
+	return nil
+}
+
+// readOnly reports whether this invocation only reads, going by ReadOnly
+// or, for a command with ReadActions, by the action args select.
+func (c *command) readOnly(args []string) bool {
+	if c.ReadActions == nil {
+		return c.ReadOnly
+	}
+	sub := c.DefaultSub
+	if len(args) > 0 {
+		sub = args[0]
+	}
+	for _, a := range c.ReadActions {
+		if a == sub {
+			return true
+		}
+	}
+	return false
+}
+
+// formatKey returns the name formatText knows this invocation's output by.
+func (c *command) formatKey(args []string) string {
+	if !c.Subcommands {
+		return c.Name
+	}
+	sub := c.DefaultSub
+	if len(args) > 0 {
+		sub = args[0]
+	}
+	if sub == "" {
+		return c.Name
+	}
+	return c.Name + " " + sub
+}
+
+var (
+	globalFlags  = []string{"instance", "account", "timeout", "connect-timeout", "response-timeout", "retries", "no-retry", "concurrency", "no-cache", "proxy", "ca-cert", "client-cert", "client-key", "allow-insecure", "insecure-skip-verify", "output", "json", "fields", "format", "quiet", "verbose", "debug", "trace-file", "har", "replay", "color", "timestamps", "timezone", "show-cw", "cw-only", "include", "exclude", "lang", "mute-file", "no-boosts", "no-replies", "only-media", "min-boosts", "min-favs", "min-replies", "sort", "download-media", "preview", "token", "token-file", "no-keyring", "archive", "dry-run", "yes"}
+	pagingFlags  = []string{"limit", "all", "max-pages", "since-id", "max-id", "min-id"}
+	composeFlags = []string{"visibility", "spoiler", "language", "schedule", "media", "alt", "focus", "max-pixels", "strip-exif", "require-alt-text", "poll-option", "poll-expires", "poll-multiple"}
+)
+
+// withFlags concatenates flag name groups.
+func withFlags(groups ...[]string) []string {
+	var names []string
+	for _, g := range groups {
+		names = append(names, g...)
+	}
+	return names
+}
+
+// optionalArg returns args[0], or "" when there are no arguments.
+func optionalArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+func timelineCommand(name, summary string) *command {
+	return &command{
+		Name: name, Summary: summary, Flags: pagingFlags, Anonymous: true, Listing: true, ReadOnly: true,
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return getPublicTimeline(ctx, client, name)
+		},
+	}
+}
+
+func statusActionCommand(name, summary string) *command {
+	return &command{
+		Name: name, Args: "<id|url>", Summary: summary, MinArgs: 1, Requires: "a status ID or URL",
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return statusAction(ctx, client, args[0], name)
+		},
+	}
+}
+
+func accountActionCommand(name, summary string) *command {
+	return &command{
+		Name: name, Args: "<@user@instance>", Summary: summary, MinArgs: 1, Requires: "an account (@user@instance)",
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return accountAction(ctx, client, args[0], name)
+		},
+	}
+}
+
+func audienceCommand(name, summary string) *command {
+	return &command{
+		Name: name, Args: "<id|url>", Summary: summary, Flags: pagingFlags, MinArgs: 1, Requires: "a status ID or URL",
+		Listing: true, ReadOnly: true,
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return getStatusAudience(ctx, client, args[0], name)
+		},
+	}
+}
+
+func followGraphCommand(name, summary string) *command {
+	return &command{
+		Name: name, Args: "[account]", Summary: summary, Flags: pagingFlags, Listing: true, ReadOnly: true,
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return getFollowGraph(ctx, client, optionalArg(args), name)
+		},
+	}
+}
+
+func domainBlockCommand(name, summary string) *command {
+	return &command{
+		Name: name, Args: "<domain>", Summary: summary, MinArgs: 1, Requires: "a domain",
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return blockDomain(ctx, client, args[0], name == "domain-block")
+		},
+	}
+}
+
+func listingCommand[T any](name, summary string, list func(*mastodon.Client, context.Context, mastodon.PageOptions) ([]T, error)) *command {
+	return &command{
+		Name: name, Summary: summary, Flags: pagingFlags, Listing: true, ReadOnly: true,
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return list(client, ctx, pageOptions())
+		},
+	}
+}
+
+// commands is every subcommand, in the order usage lists them.
+var commands = []*command{
+	{
+		Name: "home", Summary: "Get home timeline", Flags: withFlags(pagingFlags, []string{"unread", "watch", "exec", "webhook-url", "webhook-format", "webhook-secret"}),
+		Listing: true, ReadOnly: true,
+		Examples: []string{
+			"home --limit 40",
+			"home --unread",
+			"home --all --max-pages 5 --output csv > home.csv",
+			`home --watch=1m --exec 'notify-send "$MASTODON_ACCT"'`,
+		},
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return getHomeTimeline(ctx, client)
+		},
+	},
+	{
+		Name: "posts", Aliases: []string{"statuses", "user-tweets"}, Args: "[account]",
+		Summary: "List an account's posts (default: you)",
+		Flags:   withFlags(pagingFlags, []string{"exclude-replies", "exclude-reblogs", "pinned", "tagged"}),
+		Listing: true, ReadOnly: true,
+		Examples: []string{
+			"posts",
+			"posts @gopher@fosstodon.org --exclude-replies --exclude-reblogs",
+			"posts --pinned",
+		},
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return getAccountPosts(ctx, client, optionalArg(args))
+		},
+	},
+	{
+		Name: "mentions", Summary: "Get mentions", Flags: withFlags(pagingFlags, []string{"watch", "exec", "webhook-url", "webhook-format", "webhook-secret"}),
+		Listing: true, ReadOnly: true,
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return getMentions(ctx, client)
+		},
+	},
+	{
+		Name: "notifications", Summary: "Get notifications",
+		Flags:   withFlags(pagingFlags, []string{"types", "exclude-types"}),
+		Listing: true, ReadOnly: true,
+		Examples: []string{
+			"notifications --types mention,follow",
+			"notifications --exclude-types favourite,reblog --limit 50",
+		},
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return getNotifications(ctx, client)
+		},
+	},
+	timelineCommand("public", "Get the public timeline (local and remote posts)"),
+	timelineCommand("local", "Get posts from this instance only"),
+	timelineCommand("federated", "Get posts from other instances only"),
+	{
+		Name: "tag", Args: "<hashtag>", Summary: "Get the public timeline for a hashtag",
+		Flags: withFlags(pagingFlags, []string{"instances", "watch", "exec", "webhook-url", "webhook-format", "webhook-secret"}), MinArgs: 1, Requires: "a hashtag", Anonymous: true,
+		Listing: true, ReadOnly: true,
+		Examples: []string{
+			"tag golang --limit 40",
+			"tag rust --watch=5m --webhook-url https://example.com/hook",
+			"tag golang --instances mastodon.social,fosstodon.org",
+		},
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return getTagTimeline(ctx, client, args[0])
+		},
+	},
+	{
+		Name: "search", Args: "<query>", Summary: "Search for posts, accounts, or hashtags", ReadOnly: true,
+		Flags:   withFlags(pagingFlags, []string{"offset", "type", "resolve", "account-id", "following", "instances", "watch", "exec", "webhook-url", "webhook-format", "webhook-secret"}),
+		MinArgs: 1, Requires: "a query argument", Listing: true,
+		Examples: []string{
+			"search golang",
+			"search --type accounts gopher",
+			"search --resolve https://fosstodon.org/@gopher/109876543210",
+			"search --instances tech golang",
+		},
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return search(ctx, client, args[0])
+		},
+	},
+	{
+		Name: "search-accounts", Args: "<query>", Summary: "Find accounts by name or address", ReadOnly: true,
+		Flags:   withFlags(pagingFlags, []string{"offset", "resolve", "following"}),
+		MinArgs: 1, Requires: "a query argument", Anonymous: true, Listing: true,
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return searchAccounts(ctx, client, args[0])
+		},
+	},
+	{
+		Name: "post", Args: "[text]", Summary: "Publish a new post (reads stdin if text is omitted)",
+		Help:  "Use -- before text that starts with a dash. --thread splits long text into numbered replies: post --thread < essay.txt\n--file posts a Markdown or text file; YAML front matter can set visibility, cw, language, schedule, thread, and media, and flags override it.",
+		Flags: withFlags(composeFlags, []string{"thread", "file", "idempotency-key"}),
+		Examples: []string{
+			`post "Hello, fediverse!"`,
+			`post --visibility unlisted --spoiler "Spoilers" "It was the butler"`,
+			`post --media cat.jpg --alt "A cat asleep on a keyboard" "Help"`,
+			"post --thread < essay.txt",
+			"post --file draft.md",
+			`post --schedule 2026-01-01T09:00:00Z "Happy new year"`,
+		},
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			var text string
+			var err error
+			switch {
+			case *flagPostFile != "":
+				if len(args) > 0 {
+					return nil, errors.New("give the text or --file, not both")
+				}
+				if text, err = readPostFile(*flagPostFile); err != nil {
+					return nil, err
+				}
+			// A post with attachments may have no text, so don't wait on stdin.
+			case len(args) > 0 || *flagMedia == "" || *flagThread:
+				if text, err = readPostText(args); err != nil {
+					return nil, err
+				}
+			}
+			if *flagThread {
+				return postThread(ctx, client, text)
+			}
+			return createPost(ctx, client, text)
+		},
+	},
+	{
+		Name: "reply", Args: "<id|url> [text]", Summary: "Reply to a post, keeping its visibility and CW",
+		Flags: []string{"visibility", "spoiler", "language", "idempotency-key"}, MinArgs: 1, Requires: "a status ID or URL",
+		Examples: []string{
+			`reply 109876543210 "Thanks!"`,
+			`reply https://fosstodon.org/@gopher/109876543210 "Agreed"`,
+		},
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			text, err := readPostText(args[1:])
+			if err != nil {
+				return nil, err
+			}
+			return replyToPost(ctx, client, args[0], text)
+		},
+	},
+	{
+		Name: "dm", Args: "<@user@instance> [text]", Summary: "Send a direct message",
+		Flags: []string{"spoiler", "language", "idempotency-key"}, MinArgs: 1, Requires: "a recipient (@user@instance)",
+		Examples: []string{
+			`dm @friend@example.social "Lunch tomorrow?"`,
+		},
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			text, err := readPostText(args[1:])
+			if err != nil {
+				return nil, err
+			}
+			return sendDirectMessage(ctx, client, args[0], text)
+		},
+	},
+	{
+		Name: "conversations", Summary: "List direct-message conversations", ReadOnly: true,
+		Flags: withFlags(pagingFlags, []string{"mark-read"}),
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return getConversations(ctx, client)
+		},
+	},
+	{
+		Name: "status", Args: "<id|url>", Summary: "Show full details of a post from any instance", ReadOnly: true,
+		MinArgs: 1, Requires: "a status ID or URL",
+		Examples: []string{
+			"status 109876543210",
+			"status https://fosstodon.org/@gopher/109876543210 --json",
+		},
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return getStatus(ctx, client, args[0])
+		},
+	},
+	{
+		Name: "count", Args: "[text]", Summary: "Count a post's characters against the instance's limit (reads stdin if text is omitted)", ReadOnly: true,
+		Help:  "Links count as 23 characters and mentions as just @user, as Mastodon counts them. --spoiler counts toward the limit too.",
+		Flags: []string{"spoiler"}, Anonymous: true,
+		Examples: []string{
+			`count "Is this too long?"`,
+			"count < draft.txt",
+		},
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			text, err := readPostText(args)
+			if err != nil {
+				return nil, err
+			}
+			return countPost(ctx, client, text)
+		},
+	},
+	{
+		Name: "translate", Args: "<id|url>", Summary: "Translate a post with the instance's translation service", ReadOnly: true,
+		Help:    "Only public and unlisted posts can be translated, on instances with translation enabled.",
+		Flags:   []string{"to"},
+		MinArgs: 1, Requires: "a status ID or URL",
+		Examples: []string{
+			"translate 109876543210 --to en",
+		},
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return translateStatus(ctx, client, args[0])
+		},
+	},
+	audienceCommand("boosters", "List who boosted a post"),
+	audienceCommand("favouriters", "List who favourited a post"),
+	{
+		Name: "delete", Args: "<id|url>", Summary: "Delete one of your posts",
+		MinArgs: 1, Requires: "a status ID or URL",
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return deletePost(ctx, client, args[0])
+		},
+	},
+	{
+		Name: "redraft", Args: "<id|url>", Summary: "Delete a post and repost it after editing in $EDITOR",
+		MinArgs: 1, Requires: "a status ID or URL",
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return redraftPost(ctx, client, args[0])
+		},
+	},
+	{
+		Name: "edit", Args: "<id|url>", Summary: "Edit one of your posts in $EDITOR",
+		Flags: []string{"spoiler"}, MinArgs: 1, Requires: "a status ID or URL",
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return editPost(ctx, client, args[0])
+		},
+	},
+	{
+		Name: "history", Args: "<id|url>", Summary: "Show a post's edit history", ReadOnly: true,
+		MinArgs: 1, Requires: "a status ID or URL",
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return statusHistory(ctx, client, args[0])
+		},
+	},
+	{
+		Name: "vote", Args: "<id|url> <choice...>", Summary: "Vote in a poll (choices are option numbers or titles)",
+		MinArgs: 2, Requires: "a status ID or URL and at least one choice",
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return votePoll(ctx, client, args[0], args[1:])
+		},
+	},
+	{
+		Name: "upload", Args: "<file...>", Summary: "Upload media and print the media IDs",
+		Flags: []string{"alt", "focus", "max-pixels", "strip-exif", "require-alt-text"},
+		Examples: []string{
+			`upload --alt "A sunset over the bay" sunset.jpg`,
+			"upload --strip-exif --max-pixels 3840x2160 photo.jpg",
+		},
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return uploadMedia(ctx, client, args)
+		},
+	},
+	{
+		Name: "api", Args: "<method> <path>", Summary: "Send a raw API request and print the response",
+		Help: `For endpoints without a command of their own, e.g.:
+  api get /api/v1/followed_tags --param limit=5
+  api post /api/v1/tags/golang/follow
+  api put /api/v1/filters/42 --data @filter.json
+--param goes in the query string, or in a form body for post, put, and patch without --data.`,
+		Flags:   []string{"param", "data"},
+		MinArgs: 2, Requires: "a method and a path", Anonymous: true,
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return callAPI(ctx, client, args[0], args[1])
+		},
+	},
+	{
+		Name: "instance", Args: "[peers|activity|rules]", Summary: "Show server version, limits, and activity", ReadOnly: true,
+		Subcommands: true, Anonymous: true,
+		Run: runInstance,
+	},
+	{
+		Name: "compare-instances", Args: "<domains...>", Summary: "Compare instances side by side to choose one to join", ReadOnly: true,
+		Help:    "Shows each instance's size, registrations, post and upload limits, rules, and how many servers it limits or suspends, where it publishes them. Nothing is sent with your token; everything compared is public.",
+		MinArgs: 2, Requires: "two or more instances", Anonymous: true,
+		Examples: []string{
+			"compare-instances mastodon.social fosstodon.org hachyderm.io",
+			"--json compare-instances mastodon.social fosstodon.org",
+		},
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return compareInstances(ctx, args)
+		},
+	},
+	{
+		Name: "trends", Args: "[tags|posts|links]", Summary: "Show what's trending on the instance",
+		Flags: withFlags(pagingFlags, []string{"offset", "instances"}), Subcommands: true, DefaultSub: "tags",
+		Listing: true, ReadOnly: true,
+		Examples: []string{
+			"trends posts",
+			"trends tags --instances mastodon.social,fosstodon.org",
+		},
+		Run: runTrends,
+	},
+	{
+		Name: "lists", Args: "[action]", Summary: "Manage lists",
+		ReadActions: []string{"", "members", "timeline"},
+		Help: `Actions:
+  lists                              Show your lists
+  lists create <title>               Create a list
+  lists rename <list> <title>        Rename a list
+  lists delete <list>                Delete a list
+  lists add|remove <list> <account...>  Change a list's members
+  lists members <list>               List a list's members
+  lists timeline <list>              Show a list's timeline
+Lists are named by ID or title.`,
+		Flags: pagingFlags, Subcommands: true, Listing: true,
+		Examples: []string{
+			"lists",
+			"lists create Friends",
+			"lists add Friends @friend@example.social",
+			"lists timeline Friends",
+		},
+		Run: runLists,
+	},
+	{
+		Name: "markers", Args: "[get|set]", Summary: "Show or save how far you've read",
+		ReadActions: []string{"get"},
+		Help: `Actions:
+  markers [get] [home|notifications]   Show your read markers
+  markers set <home|notifications> [id]  Mark everything up to id (default: the newest) as read
+Markers are shared with other apps; home --unread starts after the home marker.`,
+		Subcommands: true, DefaultSub: "get",
+		Run: runMarkers,
+	},
+	{
+		Name: "audit", Args: "[alt-text]", Summary: "Find your recent posts with media missing alt text",
+		ReadActions: []string{"alt-text"},
+		Help:        "Checks as many of your latest posts with media as --limit asks for (--all for every one). Boosts are skipped.",
+		Flags:       pagingFlags,
+		Subcommands: true, DefaultSub: "alt-text",
+		Run: runAudit,
+	},
+	{
+		Name: "filters", Args: "[action]", Summary: "Manage keyword filters",
+		ReadActions: []string{"", "list"},
+		Help: `Actions:
+  filters [list]                     Show your filters
+  filters create <title>             Create a filter
+  filters edit <filter> [title]      Change a filter
+  filters delete <filter>            Delete a filter`,
+		Flags:       []string{"context", "filter-action", "expires", "keyword", "remove-keyword", "whole-word"},
+		Subcommands: true,
+		Examples: []string{
+			`filters create Spoilers --keyword "season finale" --context home,public --filter-action hide`,
+			"filters delete Spoilers",
+		},
+		Run: runFilters,
+	},
+	{
+		Name: "scheduled", Args: "<action>", Summary: "Manage posts queued with --schedule",
+		ReadActions: []string{"", "list"},
+		Help: `Actions:
+  scheduled list                     Show queued posts
+  scheduled cancel <id>              Cancel a queued post
+  scheduled reschedule <id> <time>   Move a queued post to a new RFC 3339 time`,
+		Flags: pagingFlags, Subcommands: true,
+		Examples: []string{
+			"scheduled list",
+			"scheduled reschedule 42 2026-01-02T09:00:00Z",
+		},
+		Run: runScheduled,
+	},
+	{
+		Name: "profile", Args: "<action>", Summary: "Update your profile",
+		Help: `Actions:
+  profile set                        Change the profile fields given as flags
+  profile avatar|header <file>       Upload a new avatar or header image
+Boolean flags can be turned off with --locked=false and the like.`,
+		Flags:       []string{"display-name", "bio", "field", "locked", "bot", "discoverable"},
+		Subcommands: true,
+		Examples: []string{
+			`profile set --display-name "Gopher" --bio "I dig Go"`,
+			"profile avatar me.png",
+		},
+		Run: runProfile,
+	},
+	{
+		Name: "preferences", Summary: "Show your posting and reading defaults", ReadOnly: true,
+		Help: "post, reply, and scheduled posts use the posting defaults unless --visibility or --language is given.",
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			prefs, err := client.Preferences(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return *prefs, nil
+		},
+	},
+	{
+		Name: "account", Args: "<@user@instance|URL>", Summary: "Show an account's profile and pinned posts", ReadOnly: true,
+		MinArgs: 1, Requires: "an account (@user@instance or URL)",
+		Examples: []string{
+			"account @gopher@fosstodon.org",
+		},
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return getAccountProfile(ctx, client, args[0])
+		},
+	},
+	followGraphCommand("followers", "List followers (default: you)"),
+	followGraphCommand("following", "List accounts followed (default: you)"),
+	accountActionCommand("follow", "Follow an account"),
+	accountActionCommand("unfollow", "Unfollow an account"),
+	accountActionCommand("mute", "Mute an account"),
+	accountActionCommand("unmute", "Unmute an account"),
+	accountActionCommand("block", "Block an account"),
+	accountActionCommand("unblock", "Unblock an account"),
+	domainBlockCommand("domain-block", "Block a whole domain"),
+	domainBlockCommand("domain-unblock", "Unblock a domain"),
+	{
+		Name: "relationship", Args: "<@user@instance...>", Summary: "Show how you're connected to accounts", ReadOnly: true,
+		MinArgs: 1, Requires: "at least one account (@user@instance)",
+		Run: getRelationships,
+	},
+	{
+		Name: "report", Args: "<@user@instance> [id|url...]", Summary: "Report an account (and posts) to your moderators",
+		Flags: []string{"category", "comment", "forward", "rule-id"}, MinArgs: 1, Requires: "an account (@user@instance)",
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return reportAccount(ctx, client, args[0], args[1:])
+		},
+	},
+	listingCommand("mutes", "List muted accounts", (*mastodon.Client).Mutes),
+	listingCommand("blocks", "List blocked accounts", (*mastodon.Client).Blocks),
+	listingCommand("domain-blocks", "List blocked domains", (*mastodon.Client).DomainBlocks),
+	statusActionCommand("boost", "Boost a post"),
+	statusActionCommand("unboost", "Undo a boost"),
+	statusActionCommand("fav", "Favourite a post"),
+	statusActionCommand("unfav", "Remove a favourite"),
+	statusActionCommand("bookmark", "Bookmark a post"),
+	statusActionCommand("unbookmark", "Remove a bookmark"),
+	{
+		Name: "bookmarks", Summary: "List bookmarked posts", Flags: pagingFlags, Listing: true,
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return getBookmarks(ctx, client)
+		},
+	},
+	statusActionCommand("pin", "Pin one of your posts on your profile"),
+	statusActionCommand("unpin", "Unpin a post from your profile"),
+	{
+		Name: "pinned", Summary: "List your pinned posts",
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return getPinned(ctx, client)
+		},
+	},
+	accountActionCommand("endorse", "Feature an account on your profile"),
+	accountActionCommand("unendorse", "Stop featuring an account on your profile"),
+	listingCommand("endorsements", "List accounts featured on your profile", (*mastodon.Client).Endorsements),
+	{
+		Name: "whoami", Summary: "Show the account, instance, and scopes of the current token", ReadOnly: true,
+		Examples: []string{
+			"whoami",
+			"--account work whoami --json",
+		},
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return whoami(ctx, client)
+		},
+	},
+	{
+		Name: "rate-limit", Summary: "Show how many API requests your token has left", ReadOnly: true,
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return getRateLimit(ctx, client)
+		},
+	},
+	{
+		Name: "login", Summary: "Authorize with the instance and store the token",
+		Flags: []string{"scopes", "no-browser"}, NoAuth: true,
+		Examples: []string{
+			"--instance fosstodon.org login",
+			`--scopes "read write" login --no-browser`,
+		},
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return login(ctx)
+		},
+	},
+	{
+		Name: "auth", Args: "<list|remove [name]>", Summary: "List or delete stored tokens",
+		Subcommands: true, NoAuth: true,
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return runAuth(args)
+		},
+	},
+	{
+		Name: "config", Args: "[list|get <key>|set <key> <value>]", Summary: "Show or change settings in config.toml",
+		Help: `Actions:
+  config [list]                      Show every setting, its value, and where the value came from
+  config get <key>                   Show one setting
+  config set <key> <value>           Save a setting to config.toml
+Settings (instance, limit, output, timezone, color) are kept in [defaults]; a flag beats
+the environment (MASTODON_INSTANCE, MASTODON_LIMIT, MASTODON_OUTPUT, MASTODON_TIMEZONE,
+MASTODON_COLOR), which beats config.toml; without a timezone setting, TZ applies. Other
+keys are default_account, require_alt_text, and table.key, such as alias.h or
+accounts.work.instance.`,
+		Subcommands: true, DefaultSub: "list", NoAuth: true,
+		Examples: []string{
+			"config set limit 40",
+			"config set timezone America/Toronto",
+			"config get instance",
+			"--account work config list",
+		},
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return runConfig(args)
+		},
+	},
+	{
+		Name: "version", Summary: "Show the version, commit, and build details",
+		Flags: []string{"check"}, NoAuth: true,
+		Examples: []string{
+			"version",
+			"version --check",
+		},
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return runVersion()
+		},
+	},
+	{
+		Name: "export", Args: "<dir>", Summary: "Back up your posts, bookmarks, favourites, follows, mutes, and blocks",
+		Help:    "Writes JSON and Mastodon-compatible CSV files to <dir>. --with-media also saves your posts' attachments to <dir>/media.",
+		Flags:   []string{"with-media"},
+		MinArgs: 1, Requires: "a directory", LongRunning: true,
+		Examples: []string{
+			"export backup",
+			"export backup --with-media",
+		},
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return runExport(ctx, client, args[0])
+		},
+	},
+	{
+		Name: "archive", Args: "[stats|posts [account]|accounts|notifications|search <query>]", Summary: "Read and search the posts, accounts, and notifications stored by --archive",
+		Help: `Actions:
+  archive [stats]                    Show how much the archive holds and where it is
+  archive posts [account]            Archived posts, newest first, optionally by one account
+  archive accounts                   Archived accounts, most recently seen first
+  archive notifications              Archived notifications, newest first
+  archive search <query>             Archived posts whose text, content warning, alt text, or
+                                     author has every word of the query, best matches first;
+                                     "quote" phrases, and end a word with * to match its prefix
+Run any command with --archive to store what it fetches in
+$XDG_DATA_HOME/mastodon-scout/archive.db. Reading the archive needs neither a
+token nor the network.`,
+		Flags: []string{"limit", "all"}, Subcommands: true, DefaultSub: "stats", NoAuth: true,
+		Examples: []string{
+			"--archive --all home",
+			"archive posts @alice@example.social",
+			`archive search "kubernetes outage"`,
+			`archive search 'postgres upgrad*'`,
+			"archive notifications --limit 50",
+		},
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return runArchive(ctx, args)
+		},
+	},
+	{
+		Name: "import", Args: "<file.csv>", Summary: "Follow, mute, block, bookmark, or list the entries of a CSV export",
+		Help:    "Reads the CSV files of Mastodon's export (and of export). --type is inferred from names such as following_accounts.csv.",
+		Flags:   []string{"type"},
+		MinArgs: 1, Requires: "a CSV file", LongRunning: true,
+		Examples: []string{
+			"import following_accounts.csv --dry-run",
+			"import following_accounts.csv",
+		},
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return runImport(ctx, client, args[0])
+		},
+	},
+	{
+		Name: "metrics", Summary: "Export account and rate-limit metrics for Prometheus",
+		Help:      "Serves follower, following, and post counts, notification counts, and the rate limit at http://<listen>/metrics until interrupted.",
+		Flags:     []string{"listen", "interval"},
+		Streaming: true,
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return nil, runMetrics(ctx, client)
+		},
+	},
+	{
+		Name: "serve", Summary: "Serve the commands as a local HTTP API",
+		Help:      "GET /home?limit=5, GET /search?q=golang, POST /post with {\"text\": \"Hello\"}. Responses are the --json output.",
+		Flags:     []string{"listen"},
+		Streaming: true,
+		Examples: []string{
+			"serve --listen 127.0.0.1:9877",
+		},
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return nil, runServe(ctx, client)
+		},
+	},
+	{
+		Name: "stream", Args: "<timeline> [name|id]", Summary: "Stream events live",
+		Help:      "Timelines: user, public, local, federated, tag <name>, list <id>, direct, notifications.",
+		Flags:     []string{"exec", "webhook-url", "webhook-format", "webhook-secret"},
+		Streaming: true,
+		Examples: []string{
+			"stream user",
+			"stream tag golang --exec ./on-post.sh",
+		},
+		Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+			return nil, runStream(ctx, client, args)
+		},
+	},
+}
+
+// lookupCommand finds a command by name or alias.
+func lookupCommand(name string) *command {
+	for _, c := range commands {
+		if c.Name == name {
+			return c
+		}
+		for _, alias := range c.Aliases {
+			if alias == name {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// flagSet returns a flag set holding the named flags. The flags share their
+// values with the package-level definitions, so parsing sets those.
+func flagSet(name string, names []string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	for _, n := range names {
+		f := flag.Lookup(n)
+		fs.Var(f.Value, f.Name, f.Usage)
+	}
+	return fs
+}
+
+// cmdFlags is the flag set parsed for this run; flagWasSet consults it.
+var cmdFlags = flag.NewFlagSet("", flag.ContinueOnError)
+
+// parseCommandLine parses the command's flags and the global flags wherever
+// they appear among args, returning the remaining arguments. Everything
+// after "--" is an argument.
+func parseCommandLine(cmd *command, args []string) ([]string, error) {
+	cmdFlags = flagSet(cmd.Name, withFlags(globalFlags, cmd.Flags))
+	var positional []string
+	for {
+		if err := cmdFlags.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := cmdFlags.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		if i := len(args) - len(rest); i > 0 && args[i-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// commandIndex returns the position of the command name in args, skipping
+// any flags before it, or -1 if there is none.
+func commandIndex(args []string) int {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			if i+1 < len(args) {
+				return i + 1
+			}
+			return -1
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			return i
+		}
+		name := strings.TrimLeft(arg, "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		if f := flag.Lookup(name); f != nil && !isBoolFlag(f) {
+			i++
+		}
+	}
+	return -1
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// isHelpFlag reports whether arg asks for help.
+func isHelpFlag(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+// printUsage lists every command and the global flags.
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: mastodon-scout <command> [args] [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", strings.TrimSpace(c.Name+" "+c.Args), c.Summary)
+	}
+	fmt.Fprintf(tw, "  help [command]\tShow help for a command\n")
+	tw.Flush()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Global flags:")
+	printFlags(w, globalFlags)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run `mastodon-scout help <command>` for a command's flags. Flags may appear anywhere on the line.")
+	fmt.Fprintln(w, "Aliases from the [alias] table in config.toml expand like git aliases.")
+}
+
+// printCommandHelp describes one command and its flags.
+func printCommandHelp(w io.Writer, c *command) {
+	fmt.Fprintf(w, "Usage: mastodon-scout %s [flags]\n\n", strings.TrimSpace(c.Name+" "+c.Args))
+	fmt.Fprintln(w, c.Summary)
+	if len(c.Aliases) > 0 {
+		fmt.Fprintf(w, "\nAliases: %s\n", strings.Join(c.Aliases, ", "))
+	}
+	if c.Help != "" {
+		fmt.Fprintf(w, "\n%s\n", c.Help)
+	}
+	if len(c.Examples) > 0 {
+		fmt.Fprintln(w, "\nExamples:")
+		for _, example := range c.Examples {
+			fmt.Fprintf(w, "  mastodon-scout %s\n", example)
+		}
+	}
+	if len(c.Flags) > 0 {
+		fmt.Fprintln(w, "\nFlags:")
+		printFlags(w, c.Flags)
+	}
+	fmt.Fprintln(w, "\nGlobal flags:")
+	printFlags(w, globalFlags)
+}
+
+func printFlags(w io.Writer, names []string) {
+	fs := flagSet("", names)
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+}
+
+// runHelp handles `help [command]`.
+func runHelp(args []string) error {
+	if len(args) == 0 {
+		printUsage(os.Stdout)
+		return nil
+	}
+	c := lookupCommand(args[0])
+	if c == nil {
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+	printCommandHelp(os.Stdout, c)
+	return nil
+}
+
+// usageError explains a flag parsing failure and where to find help.
+func usageError(c *command, err error) error {
+	return errors.New(err.Error() + " (see `mastodon-scout help " + c.Name + "`)")
+}
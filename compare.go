@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// ComparedInstance is one column of compare-instances: what an instance
+// says about itself, or why it couldn't be reached.
+type ComparedInstance struct {
+	Domain   string                  `json:"domain"`
+	Instance *mastodon.Instance      `json:"instance,omitempty"`
+	Stats    *mastodon.InstanceStats `json:"stats,omitempty"`
+	// DomainBlocksPublished is false when the instance keeps its list of
+	// moderated servers to itself, which is not the same as an empty list.
+	DomainBlocksPublished bool                           `json:"domain_blocks_published"`
+	DomainBlocks          []mastodon.InstanceDomainBlock `json:"domain_blocks,omitempty"`
+	Error                 string                         `json:"error,omitempty"`
+}
+
+// compareInstances fetches the details of each instance at once, anonymously,
+// since everything compared is public. An instance that can't be reached
+// gets a column with its error; only when none can be does the command fail.
+func compareInstances(ctx context.Context, domains []string) ([]ComparedInstance, error) {
+	instances := make([]string, len(domains))
+	for i, domain := range domains {
+		instance, err := normalizeInstanceURL(domain)
+		if err != nil {
+			return nil, err
+		}
+		instances[i] = instance
+	}
+
+	compared := make([]ComparedInstance, len(instances))
+	errs := make([]error, len(instances))
+	var wg sync.WaitGroup
+	for i, instance := range instances {
+		wg.Add(1)
+		go func(i int, instance string) {
+			defer wg.Done()
+			compared[i], errs[i] = compareInstance(ctx, newInstanceClient(instance, ""), instanceHost(instance))
+		}(i, instance)
+	}
+	wg.Wait()
+
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			errs[i] = fmt.Errorf("%s: %w", compared[i].Domain, err)
+			compared[i].Error = err.Error()
+			failed++
+		}
+	}
+	if failed == len(compared) {
+		return nil, errors.Join(errs...)
+	}
+	return compared, nil
+}
+
+// compareInstance fetches one instance's details. Only the instance
+// endpoint itself is required; the totals and the moderated servers are
+// left out where the server doesn't offer them.
+func compareInstance(ctx context.Context, client *mastodon.Client, host string) (ComparedInstance, error) {
+	c := ComparedInstance{Domain: host}
+	instance, err := client.Instance(ctx)
+	if err != nil {
+		return c, err
+	}
+	c.Instance = instance
+	if instance.Domain != "" {
+		c.Domain = instance.Domain
+	}
+	if stats, err := client.InstanceStats(ctx); err == nil {
+		c.Stats = stats
+	}
+	if blocks, err := client.InstanceDomainBlocks(ctx); err == nil {
+		c.DomainBlocksPublished = true
+		c.DomainBlocks = blocks
+	}
+	return c, nil
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Config is the parsed ~/.config/mastodon-scout/config.toml.
+//
+//	default_account = "work"
+//	require_alt_text = true  # refuse uploads without --alt
+//
+//	[defaults]             # see settings
+//	limit = 40
+//	timezone = "Europe/Berlin"
+//
+//	[accounts.work]
+//	instance = "https://hachyderm.io"
+//	token = "..."        # optional; falls back to the token saved by login
+//	token_file = "/run/secrets/mastodon"  # or read it from a file
+//
+//	[groups]               # names for --instances
+//	tech = "fosstodon.org, hachyderm.io"
+type Config struct {
+	DefaultAccount string
+	Accounts       map[string]AccountConfig
+	RequireAltText bool
+
+	// tables holds every parsed table so later sections can be read without
+	// extending the parser.
+	tables map[string]map[string]string
+}
+
+// AccountConfig is one named identity from the [accounts.<name>] tables.
+type AccountConfig struct {
+	Name      string
+	Instance  string
+	Token     string
+	TokenFile string
+}
+
+func configPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.toml"), nil
+}
+
+// loadConfig reads the config file. A missing file yields an empty config.
+func loadConfig() (*Config, error) {
+	cfg := &Config{Accounts: make(map[string]AccountConfig), tables: make(map[string]map[string]string)}
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	tables, err := parseTOML(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	cfg.tables = tables
+	cfg.DefaultAccount = tables[""]["default_account"]
+	if v, ok := tables[""]["require_alt_text"]; ok {
+		if cfg.RequireAltText, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("parsing %s: require_alt_text must be true or false", path)
+		}
+	}
+	for name, values := range tables {
+		account, ok := strings.CutPrefix(name, "accounts.")
+		if !ok {
+			continue
+		}
+		cfg.Accounts[account] = AccountConfig{
+			Name:      account,
+			Instance:  values["instance"],
+			Token:     values["token"],
+			TokenFile: values["token_file"],
+		}
+	}
+	return cfg, nil
+}
+
+// Table returns the key/value pairs of the named table ("" for top level).
+func (c *Config) Table(name string) map[string]string {
+	return c.tables[name]
+}
+
+// selectAccount returns the account named by --account, or the default
+// account when none was requested. It returns nil when no account applies.
+func (c *Config) selectAccount(name string) (*AccountConfig, error) {
+	if name == "" {
+		name = c.DefaultAccount
+		if name == "" {
+			return nil, nil
+		}
+	}
+	account, ok := c.Accounts[name]
+	if !ok {
+		return nil, fmt.Errorf("account %q is not defined in the config file (known: %s)", name, strings.Join(c.accountNames(), ", "))
+	}
+	if account.Instance == "" {
+		return nil, fmt.Errorf("account %q has no instance configured", name)
+	}
+	return &account, nil
+}
+
+func (c *Config) accountNames() []string {
+	names := make([]string, 0, len(c.Accounts))
+	for name := range c.Accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return []string{"none"}
+	}
+	return names
+}
+
+// parseTOML parses the subset of TOML the config file uses: [table] headers
+// (dotted names allowed), key = value pairs with string, integer, float, and
+// boolean values, and # comments. Values are returned in their string form,
+// keyed by table name ("" for top-level keys).
+func parseTOML(data string) (map[string]map[string]string, error) {
+	tables := map[string]map[string]string{"": {}}
+	current := ""
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(stripTOMLComment(line))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("line %d: unsupported table header %q", i+1, line)
+			}
+			current = strings.TrimSpace(line[1 : len(line)-1])
+			if current == "" {
+				return nil, fmt.Errorf("line %d: empty table name", i+1)
+			}
+			if tables[current] == nil {
+				tables[current] = make(map[string]string)
+			}
+			continue
+		}
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", i+1)
+		}
+		key = strings.Trim(strings.TrimSpace(key), `"`)
+		value, err := parseTOMLValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		tables[current][key] = value
+	}
+	return tables, nil
+}
+
+// stripTOMLComment removes a trailing # comment that is not inside a string.
+func stripTOMLComment(line string) string {
+	var quote rune
+	escaped := false
+	for i, ch := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case ch == '\\' && quote == '"':
+			escaped = true
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func parseTOMLValue(raw string) (string, error) {
+	switch {
+	case raw == "":
+		return "", errors.New("missing value")
+	case strings.HasPrefix(raw, `"`):
+		value, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return value, nil
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return raw[1 : len(raw)-1], nil
+	case raw == "true" || raw == "false":
+		return raw, nil
+	}
+	if _, err := strconv.ParseFloat(strings.ReplaceAll(raw, "_", ""), 64); err == nil {
+		return strings.ReplaceAll(raw, "_", ""), nil
+	}
+	return "", fmt.Errorf("unsupported value %s", raw)
+}
+
+// setConfigValue writes key = "value" into table, editing the file in place so
+// existing comments and ordering survive. The table is created if needed.
+func setConfigValue(table, key, value string) (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("reading config: %w", err)
+	}
+	if _, err := parseTOML(string(data)); err != nil {
+		return "", fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	assignment := fmt.Sprintf("%s = %s", key, strconv.Quote(value))
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(data) == 0 {
+		lines = nil
+	}
+
+	current := ""
+	insertAt := -1
+	if table == "" {
+		insertAt = 0
+	}
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(stripTOMLComment(line))
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			if current == table && insertAt >= 0 && table == "" {
+				break
+			}
+			current = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			if current == table {
+				insertAt = i + 1
+			}
+			continue
+		}
+		if current != table {
+			continue
+		}
+		if k, _, ok := strings.Cut(trimmed, "="); ok && strings.Trim(strings.TrimSpace(k), `"`) == key {
+			lines[i] = assignment
+			return path, writeConfig(path, lines)
+		}
+		if trimmed != "" {
+			insertAt = i + 1
+		}
+	}
+
+	switch {
+	case insertAt >= 0:
+		lines = append(lines[:insertAt], append([]string{assignment}, lines[insertAt:]...)...)
+	default:
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "["+table+"]", assignment)
+	}
+	return path, writeConfig(path, lines)
+}
+
+func writeConfig(path string, lines []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		return fmt.Errorf("writing config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// errDeclined is the answer no to a confirmation prompt.
+var errDeclined = errors.New("cancelled; nothing was changed")
+
+// prompting reports whether confirm would ask. Only people at a terminal
+// are asked: --yes, --dry-run, and runs without a terminal on stdin and
+// stderr, such as scripts and cron jobs, go ahead.
+func prompting() bool {
+	return !*flagYes && !*flagDryRun && isTerminal(os.Stdin) && isTerminal(os.Stderr)
+}
+
+// confirm asks before an action that can't be undone, and returns
+// errDeclined unless the answer is yes.
+func confirm(question string) error {
+	if !prompting() {
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return nil
+	}
+	return errDeclined
+}
+
+// confirmRequest asks question like confirm. Once it is answered it returns
+// a fresh request context to go ahead with, since time spent answering
+// shouldn't count against --timeout; without a prompt ctx is kept.
+func confirmRequest(ctx context.Context, question string) (context.Context, context.CancelFunc, error) {
+	if !prompting() {
+		return ctx, func() {}, nil
+	}
+	if err := confirm(question); err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := requestContext()
+	return ctx, cancel, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+const (
+	// defaultMaxCharacters and defaultURLLength are Mastodon's limits, for
+	// instances that don't report their own.
+	defaultMaxCharacters = 500
+	defaultURLLength     = 23
+)
+
+var (
+	lengthURLPattern     = regexp.MustCompile(`https?://\S+`)
+	lengthMentionPattern = regexp.MustCompile(`@(\w+)@[\w.-]+\w`)
+)
+
+// CountResult is the length of a post as the instance counts it.
+type CountResult struct {
+	Characters int `json:"characters"`
+	Limit      int `json:"limit"`
+	Remaining  int `json:"remaining"`
+	// Parts is how many posts --thread would split the text into.
+	Parts int `json:"parts"`
+}
+
+// countPost measures text, with the --spoiler content warning, against the
+// instance's character limit.
+func countPost(ctx context.Context, client *mastodon.Client, text string) (interface{}, error) {
+	limit, urlLength, _ := postLimits(ctx, client)
+	n := postLength(*flagSpoiler, urlLength) + postLength(text, urlLength)
+	result := CountResult{Characters: n, Limit: limit, Remaining: limit - n, Parts: 1}
+	if n > limit {
+		parts, err := splitThread(text, limit-postLength(*flagSpoiler, urlLength), urlLength)
+		if err != nil {
+			return nil, err
+		}
+		result.Parts = len(parts)
+	}
+	return result, nil
+}
+
+// checkPostLength rejects a post the instance would refuse as too long, so
+// media isn't uploaded for nothing. Instances that don't say what their
+// limit is get the benefit of the doubt.
+func checkPostLength(ctx context.Context, client *mastodon.Client, text string) error {
+	limit, urlLength, ok := postLimits(ctx, client)
+	if !ok {
+		return nil
+	}
+	if n := postLength(*flagSpoiler, urlLength) + postLength(text, urlLength); n > limit {
+		return fmt.Errorf("the post is %d characters, over the instance's limit of %d; shorten it or use --thread to split it into replies", n, limit)
+	}
+	return nil
+}
+
+// postLimits returns the instance's character limit for posts and how many
+// characters a link counts as. ok is false when the instance couldn't be
+// asked, and the limits are Mastodon's defaults.
+func postLimits(ctx context.Context, client *mastodon.Client) (limit, urlLength int, ok bool) {
+	instance, err := client.Instance(ctx)
+	if err != nil {
+		return defaultMaxCharacters, defaultURLLength, false
+	}
+	limit = instance.Configuration.Statuses.MaxCharacters
+	if limit <= 0 {
+		// Forks such as glitch-soc and Pleroma report only max_toot_chars.
+		limit = instance.MaxTootChars
+	}
+	if limit <= 0 {
+		limit = defaultMaxCharacters
+	}
+	urlLength = instance.Configuration.Statuses.CharactersReservedPerURL
+	if urlLength <= 0 {
+		urlLength = defaultURLLength
+	}
+	return limit, urlLength, true
+}
+
+// postLength counts characters the way Mastodon does: every URL counts as
+// urlLength, and mentions count only their username.
+func postLength(s string, urlLength int) int {
+	s = lengthMentionPattern.ReplaceAllString(s, "@$1")
+	urls := len(lengthURLPattern.FindAllStringIndex(s, -1))
+	return utf8.RuneCountInString(lengthURLPattern.ReplaceAllString(s, "")) + urls*urlLength
+}
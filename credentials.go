@@ -0,0 +1,330 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Credential is the stored record for one token. When the token lives in
+// the OS keyring, AccessToken is empty and only the metadata is on disk.
+type Credential struct {
+	AccessToken string `json:"access_token,omitempty"`
+	Scope       string `json:"scope,omitempty"`
+	Account     string `json:"account,omitempty"`
+	CreatedAt   string `json:"created_at,omitempty"`
+	Storage     string `json:"storage,omitempty"`
+}
+
+const (
+	storageKeyring = "keyring"
+	storageFile    = "file"
+)
+
+// configDir returns $XDG_CONFIG_HOME/mastodon-scout, defaulting to
+// ~/.config/mastodon-scout on every platform.
+func configDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, appName), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("locating home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", appName), nil
+}
+
+func credentialsPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "credentials.json"), nil
+}
+
+// loadCredentials reads the credentials file, keyed by account name or
+// instance URL. A missing file yields an empty map.
+func loadCredentials() (map[string]Credential, error) {
+	creds := make(map[string]Credential)
+	path, err := credentialsPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return creds, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading credentials: %w", err)
+	}
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return creds, nil
+}
+
+// credentialKey names the stored credential for the active account, or for
+// the instance when no account is selected.
+func credentialKey() string {
+	if activeAccount != nil {
+		return activeAccount.Name
+	}
+	return *flagInstanceURL
+}
+
+// saveCredential stores the token in the OS keyring when one is available,
+// falling back to the credentials file. Metadata always goes to the file,
+// which is readable only by the current user. It returns where the token
+// ended up.
+func saveCredential(key string, cred Credential) (string, error) {
+	creds, err := loadCredentials()
+	if err != nil {
+		return "", err
+	}
+	cred.Storage = storageFile
+	if kr := osKeyring(); kr != nil {
+		if err := kr.Set(key, cred.AccessToken); err == nil {
+			cred.AccessToken = ""
+			cred.Storage = storageKeyring
+		} else {
+			notef("Warning: %v; storing token in the credentials file\n", err)
+		}
+	}
+	creds[key] = cred
+	path, err := writeCredentials(creds)
+	if err != nil {
+		return "", err
+	}
+	if cred.Storage == storageKeyring {
+		return "OS keyring", nil
+	}
+	return path, nil
+}
+
+func writeCredentials(creds map[string]Credential) (string, error) {
+	path, err := credentialsPath()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", fmt.Errorf("creating config directory: %w", err)
+	}
+	data, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("encoding credentials: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", fmt.Errorf("writing credentials: %w", err)
+	}
+	return path, nil
+}
+
+// storedToken returns the token saved by `login` under key, if any.
+func storedToken(key string) string {
+	creds, err := loadCredentials()
+	if err != nil {
+		return ""
+	}
+	cred, ok := creds[key]
+	if !ok {
+		return ""
+	}
+	if cred.Storage == storageKeyring {
+		kr := systemKeyring()
+		if kr == nil {
+			return ""
+		}
+		token, err := kr.Get(key)
+		if err != nil {
+			return ""
+		}
+		return token
+	}
+	return cred.AccessToken
+}
+
+// StoredCredential describes a saved token for `auth list`.
+type StoredCredential struct {
+	Name      string `json:"name"`
+	Account   string `json:"account,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	Storage   string `json:"storage"`
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+// runAuth implements `auth list` and `auth remove <name>`.
+func runAuth(args []string) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, errors.New("auth command requires a subcommand: list, remove <name>")
+	}
+	switch args[0] {
+	case "list":
+		return listCredentials()
+	case "remove":
+		key := credentialKey()
+		if len(args) > 1 {
+			key = args[1]
+		}
+		return removeCredential(key)
+	default:
+		return nil, fmt.Errorf("unknown auth subcommand: %s", args[0])
+	}
+}
+
+func listCredentials() ([]StoredCredential, error) {
+	creds, err := loadCredentials()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]StoredCredential, 0, len(creds))
+	for name, cred := range creds {
+		storage := cred.Storage
+		if storage == "" {
+			storage = storageFile
+		}
+		list = append(list, StoredCredential{
+			Name:      name,
+			Account:   cred.Account,
+			Scope:     cred.Scope,
+			Storage:   storage,
+			CreatedAt: cred.CreatedAt,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// removeCredential deletes the token stored under key from both the keyring
+// and the credentials file.
+func removeCredential(key string) (StoredCredential, error) {
+	creds, err := loadCredentials()
+	if err != nil {
+		return StoredCredential{}, err
+	}
+	cred, ok := creds[key]
+	if !ok {
+		return StoredCredential{}, fmt.Errorf("no stored token named %q (see `auth list`)", key)
+	}
+	if cred.Storage == storageKeyring {
+		if kr := systemKeyring(); kr != nil {
+			if err := kr.Delete(key); err != nil && !errors.Is(err, errKeyringNotFound) {
+				return StoredCredential{}, err
+			}
+		}
+	}
+	delete(creds, key)
+	if _, err := writeCredentials(creds); err != nil {
+		return StoredCredential{}, err
+	}
+	return StoredCredential{Name: key, Account: cred.Account, Scope: cred.Scope, Storage: cred.Storage}, nil
+}
+
+// resolveToken picks the access token for this run. --token or
+// --token-file wins, then an account chosen with --account, then
+// MASTODON_TOKEN, then the default account, then a token stored for the
+// bare instance. MASTODON_TOKEN only goes with an instance given by
+// --instance, MASTODON_INSTANCE, or [defaults], never to the default
+// account's, which may be another server. Tokens are checked as they are
+// found, so a mangled one fails here rather than as a 401.
+func resolveToken() (string, error) {
+	switch {
+	case *flagToken != "" && *flagTokenFile != "":
+		return "", errors.New("give --token or --token-file, not both")
+	case *flagToken == "-":
+		line, err := readTokenLine()
+		if err != nil {
+			return "", err
+		}
+		return normalizeToken(line, "the token on stdin")
+	case *flagToken != "":
+		return normalizeToken(*flagToken, "--token")
+	case *flagTokenFile != "":
+		return readTokenFile(*flagTokenFile, "--token-file")
+	}
+
+	accountToken := func() (string, error) {
+		switch {
+		case activeAccount == nil:
+			return "", nil
+		case activeAccount.Token != "":
+			return normalizeToken(activeAccount.Token, fmt.Sprintf("the token of account %q", activeAccount.Name))
+		case activeAccount.TokenFile != "":
+			return readTokenFile(activeAccount.TokenFile, fmt.Sprintf("the token_file of account %q", activeAccount.Name))
+		}
+		return storedToken(activeAccount.Name), nil
+	}
+	if flagWasSet("account") {
+		if token, err := accountToken(); token != "" || err != nil {
+			return token, err
+		}
+	}
+	envToken := os.Getenv("MASTODON_TOKEN")
+	if envToken != "" && settingSources["instance"] != sourceAccount {
+		return normalizeToken(envToken, "MASTODON_TOKEN")
+	}
+	if token, err := accountToken(); token != "" || err != nil {
+		return token, err
+	}
+	if token := storedToken(*flagInstanceURL); token != "" || envToken == "" {
+		return token, nil
+	}
+	return "", fmt.Errorf("MASTODON_TOKEN isn't sent to %s, the instance of account %q; set MASTODON_INSTANCE or --instance to the token's instance", *flagInstanceURL, activeAccount.Name)
+}
+
+// readTokenFile reads a token kept in a file, such as a CI secret or a
+// systemd credential.
+func readTokenFile(path, source string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", source, err)
+	}
+	return normalizeToken(string(data), source)
+}
+
+// readTokenLine reads the first line of stdin for --token -. It reads a
+// byte at a time so that the rest, such as the text of a post, is left
+// for the command.
+func readTokenLine() (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(b)
+		if n == 1 {
+			if b[0] == '\n' {
+				break
+			}
+			line = append(line, b[0])
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("reading the token from stdin: %w", err)
+		}
+	}
+	return string(line), nil
+}
+
+// normalizeToken trims the whitespace and "Bearer " prefix that copying a
+// token tends to pick up, and rejects what can't be a token. source names
+// where it came from, for the error.
+func normalizeToken(token, source string) (string, error) {
+	token = strings.TrimSpace(token)
+	if len(token) > 7 && strings.EqualFold(token[:7], "bearer ") {
+		token = strings.TrimSpace(token[7:])
+	}
+	if token == "" {
+		return "", fmt.Errorf("%s is empty", source)
+	}
+	for _, r := range token {
+		if r <= ' ' || r > '~' {
+			return "", fmt.Errorf("%s is not an access token: it contains %q", source, r)
+		}
+	}
+	return token, nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DryRun is what --dry-run held back: every request that would have
+// changed something, in the order the command made them.
+type DryRun struct {
+	Requests []DryRunRequest `json:"requests"`
+}
+
+// DryRunRequest is a request that wasn't sent. Body holds the parameters
+// of a form or multipart body, by name, or a JSON body as it is; files are
+// described rather than included.
+type DryRunRequest struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	ContentType string      `json:"content_type,omitempty"`
+	Body        interface{} `json:"body,omitempty"`
+}
+
+// dryRunPrefix marks the IDs of the objects that held-back requests would
+// have created, so that requests about them are answered without the
+// network too.
+const dryRunPrefix = "dry-run-"
+
+// dryRunTransport answers requests that change something, and requests
+// about objects they would have created, without sending them. Reads go
+// through, so a dry run still resolves accounts and posts and fails where
+// the real run would.
+type dryRunTransport struct {
+	next http.RoundTripper
+	// live writes each held-back request to stderr as it happens, for
+	// commands that run until interrupted.
+	live bool
+
+	mu       sync.Mutex
+	requests []DryRunRequest
+}
+
+// dryRun is the transport of a --dry-run, or nil.
+var dryRun *dryRunTransport
+
+// configureDryRun installs the --dry-run transport. It runs after
+// configureTracing, so that traces only show requests that were sent.
+func configureDryRun(live bool) {
+	if !*flagDryRun {
+		return
+	}
+	dryRun = &dryRunTransport{next: httpClient.Transport, live: live}
+	httpClient.Transport = dryRun
+}
+
+func (t *dryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	write := req.Method != http.MethodGet && req.Method != http.MethodHead
+	if !write && !strings.Contains(req.URL.Path, dryRunPrefix) {
+		return t.next.RoundTrip(req)
+	}
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+		req.Body.Close()
+	}
+
+	t.mu.Lock()
+	id := fmt.Sprintf("%s%d", dryRunPrefix, len(t.requests)+1)
+	if !write {
+		// A read of something a held-back request made; echo its ID.
+		for _, segment := range strings.Split(req.URL.Path, "/") {
+			if strings.HasPrefix(segment, dryRunPrefix) {
+				id = segment
+			}
+		}
+	} else {
+		held := DryRunRequest{
+			Method:      req.Method,
+			URL:         redactURL(req.URL),
+			ContentType: req.Header.Get("Content-Type"),
+			Body:        dryRunBody(req.Header.Get("Content-Type"), body),
+		}
+		t.requests = append(t.requests, held)
+		if t.live {
+			var b strings.Builder
+			writeDryRunRequest(&b, held)
+			notef("Dry run, not sent: %s", b.String())
+		}
+	}
+	t.mu.Unlock()
+
+	// Commands read an ID from what they create, to reply to it or attach
+	// it, so the stand-in response has one.
+	resp := fmt.Sprintf(`{"id":%q}`, id)
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(strings.NewReader(resp)),
+		ContentLength: int64(len(resp)),
+		Request:       req,
+	}, nil
+}
+
+// heldRequests returns the requests held back so far.
+func (t *dryRunTransport) heldRequests() []DryRunRequest {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]DryRunRequest{}, t.requests...)
+}
+
+// dryRunBody decodes a request body for display, with credentials
+// redacted.
+func dryRunBody(contentType string, body []byte) interface{} {
+	if len(body) == 0 {
+		return nil
+	}
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(redactBody(string(body)))
+		if err == nil {
+			return form
+		}
+	case strings.HasSuffix(mediaType, "json"):
+		redactedBody := []byte(redactBody(string(body)))
+		if json.Valid(redactedBody) {
+			return json.RawMessage(redactedBody)
+		}
+	case mediaType == "multipart/form-data":
+		if fields, err := multipartFields(body, params["boundary"]); err == nil {
+			return fields
+		}
+	}
+	return fmt.Sprintf("[%d bytes of %s]", len(body), contentType)
+}
+
+// multipartFields lists the fields of a multipart body, with files
+// described by name, size, and type.
+func multipartFields(body []byte, boundary string) (url.Values, error) {
+	fields := url.Values{}
+	r := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			return fields, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		value := string(data)
+		if part.FileName() != "" {
+			value = fmt.Sprintf("[file %s, %d bytes of %s]", part.FileName(), len(data), part.Header.Get("Content-Type"))
+		}
+		fields.Add(part.FormName(), value)
+	}
+}
+
+// writeDryRunRequest writes a held-back request as text: the method and
+// URL, then its parameters one per line.
+func writeDryRunRequest(b *strings.Builder, req DryRunRequest) {
+	fmt.Fprintf(b, "%s %s\n", req.Method, req.URL)
+	switch body := req.Body.(type) {
+	case url.Values:
+		names := make([]string, 0, len(body))
+		for name := range body {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, value := range body[name] {
+				fmt.Fprintf(b, "  %s: %s\n", name, value)
+			}
+		}
+	case json.RawMessage:
+		var indented bytes.Buffer
+		if json.Indent(&indented, body, "  ", "  ") == nil {
+			fmt.Fprintf(b, "  %s\n", indented.String())
+		}
+	case string:
+		fmt.Fprintf(b, "  %s\n", body)
+	}
+}
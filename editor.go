@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// editorCommand returns the user's editor from $VISUAL or $EDITOR, falling
+// back to a platform default.
+func editorCommand() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(env)); len(fields) > 0 {
+			return fields
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+	return []string{"vi"}
+}
+
+// editText opens initial in the user's editor and returns the saved text.
+func editText(initial string) (string, error) {
+	f, err := os.CreateTemp("", "mastodon-scout-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("creating draft file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(initial); err != nil {
+		f.Close()
+		return "", fmt.Errorf("writing draft file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("writing draft file: %w", err)
+	}
+
+	editor := editorCommand()
+	cmd := exec.Command(editor[0], append(editor[1:], f.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stderr, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("running editor %s: %w", editor[0], err)
+	}
+
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", fmt.Errorf("reading draft file: %w", err)
+	}
+	text := strings.TrimSpace(string(b))
+	if text == "" {
+		return "", errors.New("empty text, aborting")
+	}
+	return text, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// Error codes name the class of a failure in the JSON error envelope. Each
+// has its own exit status, so scripts can branch on what went wrong without
+// parsing the message.
+const (
+	codeError        = "error"
+	codeUsage        = "usage"
+	codeUnauthorized = "unauthorized"
+	codeNotFound     = "not_found"
+	codeRateLimited  = "rate_limited"
+	codeTimeout      = "network_timeout"
+	codeServerError  = "server_error"
+	codeInterrupted  = "interrupted"
+)
+
+// exitCodes maps error codes to process exit statuses.
+var exitCodes = map[string]int{
+	codeError:        1,
+	codeUsage:        2,
+	codeUnauthorized: 3,
+	codeNotFound:     4,
+	codeRateLimited:  5,
+	codeTimeout:      6,
+	codeServerError:  7,
+	// 128 + SIGINT, as shells report it.
+	codeInterrupted: 130,
+}
+
+var errNoToken = errors.New("no access token: set MASTODON_TOKEN, pass --token or --token-file, or run `mastodon-scout login`")
+
+// errorCode classifies err.
+func errorCode(err error) string {
+	var netErr net.Error
+	switch {
+	case interrupted() && errors.Is(err, context.Canceled):
+		return codeInterrupted
+	case errors.Is(err, errNoToken), errors.Is(err, mastodon.ErrUnauthorized):
+		return codeUnauthorized
+	case errors.Is(err, mastodon.ErrNotFound):
+		return codeNotFound
+	case errors.Is(err, mastodon.ErrRateLimited):
+		return codeRateLimited
+	case errors.Is(err, mastodon.ErrServer):
+		return codeServerError
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return codeTimeout
+	}
+	return codeError
+}
+
+// errorStatus is the HTTP status of the API response behind err, if any.
+func errorStatus(err error) int {
+	var apiErr *mastodon.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// errorResponse is the error envelope for err.
+func errorResponse(err error) MastodonResponse {
+	msg := err.Error()
+	return MastodonResponse{Success: false, Error: &msg, Code: errorCode(err), Status: errorStatus(err)}
+}
+
+// exitWithError reports err and exits with the status for its class.
+func exitWithError(err error) {
+	resp := errorResponse(err)
+	writeError(resp)
+	os.Exit(exitCodes[resp.Code])
+}
+
+// exitUsage reports a mistake on the command line and exits.
+func exitUsage(msg string) {
+	writeError(MastodonResponse{Success: false, Error: &msg, Code: codeUsage})
+	os.Exit(exitCodes[codeUsage])
+}
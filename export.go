@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// relationshipBatch is how many accounts one relationships request asks
+// about; instances reject much longer lists.
+const relationshipBatch = 40
+
+// ExportResult lists the files export wrote.
+type ExportResult struct {
+	Dir   string         `json:"dir"`
+	Files []ExportedFile `json:"files"`
+	// Interrupted is set when a signal stopped the export; Files lists
+	// what was written before it.
+	Interrupted bool `json:"interrupted,omitempty"`
+}
+
+// ExportedFile is one file of an export and how many items it holds.
+type ExportedFile struct {
+	Name  string `json:"name"`
+	Items int    `json:"items"`
+	// Incomplete marks a file cut short by an interrupt.
+	Incomplete bool `json:"incomplete,omitempty"`
+}
+
+// exporter writes the files of a backup into dir.
+type exporter struct {
+	client    *mastodon.Client
+	dir       string
+	accountID string
+	// domain completes the addresses of local accounts, which the API
+	// returns without one.
+	domain string
+	result ExportResult
+}
+
+// runExport backs up the account into dir: the profile and every post,
+// bookmark, and favourite as JSON, and the followed accounts and hashtags,
+// lists, mutes, and blocks as CSV. The account CSVs use the layout of
+// Mastodon's own export, so an instance's import page (or the import
+// command) accepts them. An interrupt keeps the files written so far, along
+// with the posts, bookmarks, or favourites fetched before it.
+func runExport(ctx context.Context, client *mastodon.Client, dir string) (interface{}, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating export directory: %w", err)
+	}
+	domain := instanceHost(*flagInstanceURL)
+	if host, _, err := net.SplitHostPort(domain); err == nil {
+		domain = host
+	}
+	e := &exporter{client: client, dir: dir, domain: domain, result: ExportResult{Dir: dir}}
+
+	account, err := client.VerifyCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	e.accountID = account.ID
+	if err := e.writeJSON("account.json", 1, account); err != nil {
+		return nil, err
+	}
+
+	// Posts, bookmarks, and favourites can run to thousands, so they are
+	// written to their files as they arrive rather than collected first.
+	e.progress("posts")
+	var withMedia []mastodon.Status
+	err = e.writeListing(ctx, "posts.json", func(each func(interface{}) error) error {
+		_, err := client.AccountStatuses(ctx, account.ID, mastodon.AccountStatusFilter{}, mastodon.PageOptions{All: true, Each: each})
+		return err
+	}, func(s mastodon.Status) {
+		if post, _ := resolvePost(s); *flagWithMedia && len(post.MediaAttachments) > 0 {
+			withMedia = append(withMedia, s)
+		}
+	})
+	if err != nil {
+		return e.stopped(ctx, fmt.Errorf("exporting posts: %w", err))
+	}
+	if *flagWithMedia {
+		if err := downloadMedia(ctx, withMedia, filepath.Join(dir, "media")); err != nil {
+			return e.stopped(ctx, err)
+		}
+	}
+
+	e.progress("bookmarks")
+	var rows [][]string
+	err = e.writeListing(ctx, "bookmarks.json", func(each func(interface{}) error) error {
+		_, err := client.Bookmarks(ctx, mastodon.PageOptions{All: true, Each: each})
+		return err
+	}, func(s mastodon.Status) {
+		if s.URI == "" {
+			s.URI = s.URL
+		}
+		rows = append(rows, []string{s.URI})
+	})
+	if err != nil {
+		return e.stopped(ctx, fmt.Errorf("exporting bookmarks: %w", err))
+	}
+	if err := e.writeCSV("bookmarks.csv", nil, rows); err != nil {
+		return nil, err
+	}
+
+	e.progress("favourites")
+	err = e.writeListing(ctx, "favourites.json", func(each func(interface{}) error) error {
+		_, err := client.Favourites(ctx, mastodon.PageOptions{All: true, Each: each})
+		return err
+	}, nil)
+	if err != nil {
+		return e.stopped(ctx, fmt.Errorf("exporting favourites: %w", err))
+	}
+
+	for _, step := range []func(context.Context) error{
+		e.following, e.followedTags, e.lists, e.mutes, e.blocks, e.domainBlocks,
+	} {
+		if err := step(ctx); err != nil {
+			return e.stopped(ctx, err)
+		}
+	}
+	return e.result, nil
+}
+
+// stopped ends the export with err. After an interrupt the result still
+// lists the files written, so the user knows what the directory holds.
+func (e *exporter) stopped(ctx context.Context, err error) (interface{}, error) {
+	if ctx.Err() == nil {
+		return nil, err
+	}
+	e.result.Interrupted = true
+	return e.result, err
+}
+
+// writeListing writes the posts that fetch hands to each into name as an
+// indented JSON array, one post at a time, and passes every post to seen
+// (if not nil) as well. After an interrupt the array is closed with what
+// arrived and the file is marked incomplete; after any other error it is
+// removed.
+func (e *exporter) writeListing(ctx context.Context, name string, fetch func(each func(interface{}) error) error, seen func(mastodon.Status)) error {
+	path := filepath.Join(e.dir, name)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	w := bufio.NewWriter(f)
+	items := 0
+	err = fetch(func(item interface{}) error {
+		if s, ok := item.(mastodon.Status); ok && seen != nil {
+			seen(s)
+		}
+		data, err := json.MarshalIndent(item, "  ", "  ")
+		if err != nil {
+			return fmt.Errorf("encoding %s: %w", name, err)
+		}
+		sep := ",\n  "
+		if items == 0 {
+			sep = "[\n  "
+		}
+		items++
+		w.WriteString(sep)
+		_, err = w.Write(data)
+		return err
+	})
+	end := "\n]\n"
+	if items == 0 {
+		end = "[]\n"
+	}
+	w.WriteString(end)
+	if ferr := w.Flush(); err == nil && ferr != nil {
+		err = fmt.Errorf("writing %s: %w", name, ferr)
+	}
+	if cerr := f.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("writing %s: %w", name, cerr)
+	}
+	switch {
+	case err == nil:
+		e.result.Files = append(e.result.Files, ExportedFile{Name: name, Items: items})
+	case ctx.Err() != nil && items > 0:
+		e.result.Files = append(e.result.Files, ExportedFile{Name: name, Items: items, Incomplete: true})
+	default:
+		os.Remove(path)
+	}
+	return err
+}
+
+// progress tells the user what is being fetched; big accounts take a while.
+func (e *exporter) progress(what string) {
+	notef("Exporting %s...\n", what)
+}
+
+func (e *exporter) following(ctx context.Context) error {
+	e.progress("followed accounts")
+	accounts, err := e.client.Following(ctx, e.accountID, mastodon.PageOptions{All: true})
+	if err != nil {
+		return fmt.Errorf("exporting followed accounts: %w", err)
+	}
+	rels, err := e.relationships(ctx, accounts)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, len(accounts))
+	for i, a := range accounts {
+		r := rels[a.ID]
+		rows[i] = []string{e.address(a), strconv.FormatBool(r.ShowingReblogs), strconv.FormatBool(r.Notifying), strings.Join(r.Languages, ", ")}
+	}
+	return e.writeCSV("following_accounts.csv", []string{"Account address", "Show boosts", "Notify on new posts", "Languages"}, rows)
+}
+
+func (e *exporter) followedTags(ctx context.Context) error {
+	e.progress("followed hashtags")
+	tags, err := e.client.FollowedTags(ctx, mastodon.PageOptions{All: true})
+	if err != nil {
+		return fmt.Errorf("exporting followed hashtags: %w", err)
+	}
+	rows := make([][]string, len(tags))
+	for i, t := range tags {
+		rows[i] = []string{t.Name}
+	}
+	return e.writeCSV("followed_tags.csv", nil, rows)
+}
+
+func (e *exporter) lists(ctx context.Context) error {
+	e.progress("lists")
+	lists, err := e.client.Lists(ctx)
+	if err != nil {
+		return fmt.Errorf("exporting lists: %w", err)
+	}
+	if len(lists) == 0 {
+		return e.writeCSV("lists.csv", nil, nil)
+	}
+	// Each list's members are a separate listing, so they are fetched
+	// side by side.
+	rows, err := mastodon.FetchPages(ctx, e.client, len(lists), func(ctx context.Context, i int) ([][]string, bool, error) {
+		l := lists[i]
+		members, err := e.client.ListAccounts(ctx, l.ID, mastodon.PageOptions{All: true})
+		if err != nil {
+			return nil, false, fmt.Errorf("exporting list %q: %w", l.Title, err)
+		}
+		rows := make([][]string, len(members))
+		for j, a := range members {
+			rows[j] = []string{l.Title, e.address(a)}
+		}
+		return rows, true, nil
+	})
+	if err != nil {
+		return err
+	}
+	return e.writeCSV("lists.csv", nil, rows)
+}
+
+func (e *exporter) mutes(ctx context.Context) error {
+	e.progress("mutes")
+	accounts, err := e.client.Mutes(ctx, mastodon.PageOptions{All: true})
+	if err != nil {
+		return fmt.Errorf("exporting mutes: %w", err)
+	}
+	rels, err := e.relationships(ctx, accounts)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, len(accounts))
+	for i, a := range accounts {
+		rows[i] = []string{e.address(a), strconv.FormatBool(rels[a.ID].MutingNotifications)}
+	}
+	return e.writeCSV("muted_accounts.csv", []string{"Account address", "Hide notifications"}, rows)
+}
+
+func (e *exporter) blocks(ctx context.Context) error {
+	e.progress("blocks")
+	accounts, err := e.client.Blocks(ctx, mastodon.PageOptions{All: true})
+	if err != nil {
+		return fmt.Errorf("exporting blocks: %w", err)
+	}
+	rows := make([][]string, len(accounts))
+	for i, a := range accounts {
+		rows[i] = []string{e.address(a)}
+	}
+	return e.writeCSV("blocked_accounts.csv", nil, rows)
+}
+
+func (e *exporter) domainBlocks(ctx context.Context) error {
+	e.progress("blocked domains")
+	domains, err := e.client.DomainBlocks(ctx, mastodon.PageOptions{All: true})
+	if err != nil {
+		return fmt.Errorf("exporting blocked domains: %w", err)
+	}
+	rows := make([][]string, len(domains))
+	for i, d := range domains {
+		rows[i] = []string{d}
+	}
+	return e.writeCSV("blocked_domains.csv", nil, rows)
+}
+
+// relationships fetches the user's relationship with each account, by
+// account ID, a batch at a time; the batches are independent, so several
+// are in flight at once.
+func (e *exporter) relationships(ctx context.Context, accounts []mastodon.Account) (map[string]mastodon.Relationship, error) {
+	batches := (len(accounts) + relationshipBatch - 1) / relationshipBatch
+	if batches == 0 {
+		return map[string]mastodon.Relationship{}, nil
+	}
+	rels, err := mastodon.FetchPages(ctx, e.client, batches, func(ctx context.Context, batch int) ([]mastodon.Relationship, bool, error) {
+		start := batch * relationshipBatch
+		end := min(start+relationshipBatch, len(accounts))
+		ids := make([]string, 0, end-start)
+		for _, a := range accounts[start:end] {
+			ids = append(ids, a.ID)
+		}
+		rels, err := e.client.Relationships(ctx, ids)
+		return rels, true, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching relationships: %w", err)
+	}
+	byID := make(map[string]mastodon.Relationship, len(rels))
+	for _, r := range rels {
+		byID[r.ID] = r
+	}
+	return byID, nil
+}
+
+// address is an account's full user@domain address.
+func (e *exporter) address(a mastodon.Account) string {
+	if strings.Contains(a.Acct, "@") {
+		return a.Acct
+	}
+	return a.Acct + "@" + e.domain
+}
+
+func (e *exporter) writeJSON(name string, items int, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", name, err)
+	}
+	return e.write(name, items, append(data, '\n'))
+}
+
+// writeCSV writes rows under header; Mastodon's single-column exports have
+// no header, so header may be nil.
+func (e *exporter) writeCSV(name string, header []string, rows [][]string) error {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	if header != nil {
+		w.Write(header)
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return fmt.Errorf("encoding %s: %w", name, err)
+	}
+	return e.write(name, len(rows), b.Bytes())
+}
+
+// write saves one file of the export. Mutes and blocks are private, so the
+// files are readable only by the user.
+func (e *exporter) write(name string, items int, data []byte) error {
+	if err := os.WriteFile(filepath.Join(e.dir, name), data, 0o600); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	e.result.Files = append(e.result.Files, ExportedFile{Name: name, Items: items})
+	return nil
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// fanOutTarget is one instance that --instances queries, with the token to
+// query it with, if the user has one there.
+type fanOutTarget struct {
+	instance string
+	token    string
+}
+
+// fanOutTargets resolves --instances: instance URLs or host names, and the
+// names of groups from the [groups] table of config.toml, each a
+// comma-separated list of instances. The instance of this run is queried
+// with token; the others with the token of a configured account or a login
+// there, or else anonymously.
+func fanOutTargets(cfg *Config, token string) ([]fanOutTarget, error) {
+	groups := cfg.Table("groups")
+	var names []string
+	for _, name := range strings.Split(*flagInstances, ",") {
+		name = strings.TrimSpace(name)
+		if group, ok := groups[name]; ok {
+			names = append(names, strings.Split(group, ",")...)
+		} else if name != "" {
+			names = append(names, name)
+		}
+	}
+	var targets []fanOutTarget
+	seen := map[string]bool{}
+	for _, name := range names {
+		instance, err := normalizeInstanceURL(name)
+		if err != nil {
+			return nil, fmt.Errorf("--instances: %w", err)
+		}
+		if seen[instance] {
+			continue
+		}
+		seen[instance] = true
+		t := fanOutTarget{instance: instance, token: token}
+		if instance != *flagInstanceURL {
+			if t.token, err = instanceToken(cfg, instance); err != nil {
+				return nil, err
+			}
+		}
+		targets = append(targets, t)
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("--instances names no instances")
+	}
+	return targets, nil
+}
+
+// instanceToken finds a token for instance: that of a configured account
+// on it, or the one login saved for it.
+func instanceToken(cfg *Config, instance string) (string, error) {
+	names := make([]string, 0, len(cfg.Accounts))
+	for name := range cfg.Accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		a := cfg.Accounts[name]
+		if u, err := normalizeInstanceURL(a.Instance); err != nil || u != instance {
+			continue
+		}
+		switch {
+		case a.Token != "":
+			return normalizeToken(a.Token, fmt.Sprintf("the token of account %q", a.Name))
+		case a.TokenFile != "":
+			return readTokenFile(a.TokenFile, fmt.Sprintf("the token_file of account %q", a.Name))
+		}
+		if token := storedToken(a.Name); token != "" {
+			return token, nil
+		}
+	}
+	return storedToken(instance), nil
+}
+
+// runFanOut runs cmd against every target at once and merges the results.
+// An instance that fails is reported and left out; only when all of them
+// fail does the command.
+func runFanOut(ctx context.Context, cmd *command, args []string, targets []fanOutTarget) (interface{}, error) {
+	results := make([]interface{}, len(targets))
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t fanOutTarget) {
+			defer wg.Done()
+			data, err := cmd.Run(ctx, newInstanceClient(t.instance, t.token), args)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", instanceHost(t.instance), err)
+				return
+			}
+			results[i] = qualifyAccounts(data, instanceHost(t.instance))
+		}(i, t)
+	}
+	wg.Wait()
+
+	var merged []interface{}
+	for i := range results {
+		if errs[i] == nil {
+			merged = append(merged, results[i])
+		}
+	}
+	if len(merged) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	for _, err := range errs {
+		if err != nil {
+			notef("Warning: %v\n", err)
+		}
+	}
+	return mergeResults(merged, cmd.Name == "trends"), nil
+}
+
+// qualifyAccounts adds the instance's domain to the addresses of its local
+// accounts, which the API gives without one, so that merged results say
+// where everyone is.
+func qualifyAccounts(data interface{}, host string) interface{} {
+	qualify := func(a *mastodon.Account) {
+		if a.Acct != "" && !strings.Contains(a.Acct, "@") {
+			a.Acct += "@" + host
+		}
+	}
+	qualifyStatuses := func(statuses []mastodon.Status) {
+		for i := range statuses {
+			qualify(&statuses[i].Account)
+			if r := statuses[i].Reblog; r != nil {
+				qualify(&r.Account)
+			}
+		}
+	}
+	switch v := data.(type) {
+	case []mastodon.Status:
+		qualifyStatuses(v)
+	case mastodon.SearchResult:
+		qualifyStatuses(v.Statuses)
+		for i := range v.Accounts {
+			qualify(&v.Accounts[i])
+		}
+	}
+	return data
+}
+
+// mergeResults combines what several instances returned for the same
+// command, dropping what more than one returned. IDs are local to an
+// instance, so posts are matched by URI, accounts by profile URL, and
+// hashtags and links by name and URL; their usage is added up. Posts come
+// newest first, except trending ones, which, like hashtags and links, come
+// busiest first. Each list is cut to --limit unless --all is set.
+func mergeResults(results []interface{}, trending bool) interface{} {
+	switch results[0].(type) {
+	case []mastodon.Status:
+		var statuses []mastodon.Status
+		for _, r := range results {
+			statuses = append(statuses, r.([]mastodon.Status)...)
+		}
+		return trimMerged(mergeStatuses(statuses, trending))
+	case []mastodon.Tag:
+		var tags []mastodon.Tag
+		for _, r := range results {
+			tags = append(tags, r.([]mastodon.Tag)...)
+		}
+		return trimMerged(mergeTags(tags))
+	case []mastodon.TrendingLink:
+		var links []mastodon.TrendingLink
+		for _, r := range results {
+			links = append(links, r.([]mastodon.TrendingLink)...)
+		}
+		return trimMerged(mergeLinks(links))
+	case mastodon.SearchResult:
+		var merged mastodon.SearchResult
+		for _, r := range results {
+			v := r.(mastodon.SearchResult)
+			merged.Statuses = append(merged.Statuses, v.Statuses...)
+			merged.Accounts = append(merged.Accounts, v.Accounts...)
+			merged.Hashtags = append(merged.Hashtags, v.Hashtags...)
+		}
+		merged.Statuses = trimMerged(mergeStatuses(merged.Statuses, false))
+		merged.Accounts = trimMerged(dedupe(merged.Accounts, func(a mastodon.Account) string { return a.URL }))
+		merged.Hashtags = trimMerged(mergeTags(merged.Hashtags))
+		return merged
+	}
+	// Nothing else fans out, but keep the first answer if it did.
+	return results[0]
+}
+
+func mergeStatuses(statuses []mastodon.Status, byEngagement bool) []mastodon.Status {
+	statuses = dedupe(statuses, func(s mastodon.Status) string {
+		if s.URI != "" {
+			return s.URI
+		}
+		return s.URL
+	})
+	key := func(s mastodon.Status) int64 {
+		if byEngagement {
+			return int64(s.ReblogsCount + s.FavouritesCount + s.RepliesCount)
+		}
+		t, _ := time.Parse(time.RFC3339, s.CreatedAt)
+		return t.UnixMilli()
+	}
+	sort.SliceStable(statuses, func(i, j int) bool { return key(statuses[i]) > key(statuses[j]) })
+	return statuses
+}
+
+func mergeTags(tags []mastodon.Tag) []mastodon.Tag {
+	merged := []mastodon.Tag{}
+	index := map[string]int{}
+	for _, t := range tags {
+		name := strings.ToLower(t.Name)
+		if i, ok := index[name]; ok {
+			merged[i].History = addHistory(merged[i].History, t.History)
+			continue
+		}
+		index[name] = len(merged)
+		merged = append(merged, t)
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return uses(merged[i].History) > uses(merged[j].History) })
+	return merged
+}
+
+func mergeLinks(links []mastodon.TrendingLink) []mastodon.TrendingLink {
+	merged := []mastodon.TrendingLink{}
+	index := map[string]int{}
+	for _, l := range links {
+		if i, ok := index[l.URL]; ok {
+			merged[i].History = addHistory(merged[i].History, l.History)
+			continue
+		}
+		index[l.URL] = len(merged)
+		merged = append(merged, l)
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return uses(merged[i].History) > uses(merged[j].History) })
+	return merged
+}
+
+// addHistory adds the daily counts of b to those of a, day by day.
+func addHistory(a, b []mastodon.TagHistory) []mastodon.TagHistory {
+	sum := append([]mastodon.TagHistory{}, a...)
+	for _, h := range b {
+		found := false
+		for i := range sum {
+			if sum[i].Day == h.Day {
+				sum[i].Uses = addCounts(sum[i].Uses, h.Uses)
+				sum[i].Accounts = addCounts(sum[i].Accounts, h.Accounts)
+				found = true
+				break
+			}
+		}
+		if !found {
+			sum = append(sum, h)
+		}
+	}
+	return sum
+}
+
+// addCounts adds two of the API's numbers-as-strings.
+func addCounts(a, b string) string {
+	x, _ := strconv.Atoi(a)
+	y, _ := strconv.Atoi(b)
+	return strconv.Itoa(x + y)
+}
+
+// uses totals a usage history.
+func uses(history []mastodon.TagHistory) int {
+	total := 0
+	for _, h := range history {
+		n, _ := strconv.Atoi(h.Uses)
+		total += n
+	}
+	return total
+}
+
+// dedupe keeps the first item with each key; items without one are kept.
+func dedupe[T any](items []T, key func(T) string) []T {
+	kept := []T{}
+	seen := map[string]bool{}
+	for _, item := range items {
+		k := key(item)
+		if k != "" && seen[k] {
+			continue
+		}
+		seen[k] = true
+		kept = append(kept, item)
+	}
+	return kept
+}
+
+// trimMerged cuts a merged list to --limit, unless --all is set.
+func trimMerged[T any](items []T) []T {
+	if !*flagAll && *flagLimit > 0 && len(items) > *flagLimit {
+		return items[:*flagLimit]
+	}
+	return items
+}
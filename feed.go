@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// feedTitleLength caps the plain-text excerpt used as an item title.
+const feedTitleLength = 80
+
+// rssFeed is an RSS 2.0 document.
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Generator     string    `xml:"generator"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate,omitempty"`
+	Creator     string  `xml:"dc:creator"`
+	Description string  `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// atomFeed is an Atom (RFC 4287) document.
+type atomFeed struct {
+	XMLName   xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Link      atomLink    `xml:"link"`
+	Generator string      `xml:"generator"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published,omitempty"`
+	Link      *atomLink   `xml:"link"`
+	Author    atomAuthor  `xml:"author"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// resultStatuses extracts the posts in a result: timelines, search
+// results, a single status, or the statuses notifications refer to.
+func resultStatuses(data interface{}) ([]mastodon.Status, bool) {
+	switch v := data.(type) {
+	case []mastodon.Status:
+		return v, true
+	case mastodon.Status:
+		return []mastodon.Status{v}, true
+	case mastodon.SearchResult:
+		return v.Statuses, true
+	case []mastodon.Notification:
+		var statuses []mastodon.Status
+		for _, n := range v {
+			if n.Status != nil {
+				statuses = append(statuses, *n.Status)
+			}
+		}
+		return statuses, true
+	}
+	return nil, false
+}
+
+// writeFeed renders the posts in data as an RSS 2.0 or Atom feed on stdout.
+// title names the feed, for example after the command that produced it.
+func writeFeed(format, title string, data interface{}) error {
+	statuses, ok := resultStatuses(data)
+	if !ok {
+		return fmt.Errorf("%s output needs a command that returns posts", format)
+	}
+	link := *flagInstanceURL
+	id := link + "#" + url.PathEscape(title)
+	title = fmt.Sprintf("%s · %s", title, instanceHost(link))
+	updated := time.Now().UTC()
+	if len(statuses) > 0 {
+		if t, err := time.Parse(time.RFC3339, statuses[0].CreatedAt); err == nil {
+			updated = t.UTC()
+		}
+	}
+
+	var doc interface{}
+	if format == "atom" {
+		feed := atomFeed{
+			ID:        id,
+			Title:     title,
+			Updated:   updated.Format(time.RFC3339),
+			Link:      atomLink{Href: link},
+			Generator: "mastodon-scout",
+		}
+		for _, s := range statuses {
+			feed.Entries = append(feed.Entries, atomStatusEntry(s))
+		}
+		doc = feed
+	} else {
+		feed := rssFeed{
+			Version: "2.0",
+			DC:      "http://purl.org/dc/elements/1.1/",
+			Channel: rssChannel{
+				Title:         title,
+				Link:          link,
+				Description:   "Posts fetched by mastodon-scout",
+				Generator:     "mastodon-scout",
+				LastBuildDate: updated.Format(time.RFC1123Z),
+			},
+		}
+		for _, s := range statuses {
+			feed.Channel.Items = append(feed.Channel.Items, rssStatusItem(s))
+		}
+		doc = feed
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(os.Stdout, xml.Header)
+	os.Stdout.Write(out)
+	fmt.Fprintln(os.Stdout)
+	return nil
+}
+
+func rssStatusItem(s mastodon.Status) rssItem {
+	item := rssItem{
+		Title:       feedItemTitle(s),
+		Link:        statusLink(s),
+		GUID:        rssGUID{Value: statusID(s)},
+		Creator:     "@" + s.Account.Acct,
+		Description: feedItemHTML(s),
+	}
+	if t, err := time.Parse(time.RFC3339, s.CreatedAt); err == nil {
+		item.PubDate = t.UTC().Format(time.RFC1123Z)
+	}
+	return item
+}
+
+func atomStatusEntry(s mastodon.Status) atomEntry {
+	entry := atomEntry{
+		ID:        statusID(s),
+		Title:     feedItemTitle(s),
+		Published: s.CreatedAt,
+		Updated:   s.CreatedAt,
+		Author:    atomAuthor{Name: "@" + s.Account.Acct, URI: s.Account.URL},
+		Content:   atomContent{Type: "html", Value: feedItemHTML(s)},
+	}
+	if s.EditedAt != nil {
+		entry.Updated = *s.EditedAt
+	}
+	if link := statusLink(s); link != "" {
+		entry.Link = &atomLink{Href: link, Rel: "alternate"}
+	}
+	return entry
+}
+
+// statusLink is the web page of a post; boosts link to the original.
+func statusLink(s mastodon.Status) string {
+	if s.Reblog != nil {
+		s = *s.Reblog
+	}
+	return s.URL
+}
+
+// statusID is a stable, globally unique identifier for a post.
+func statusID(s mastodon.Status) string {
+	if s.URI != "" {
+		return s.URI
+	}
+	if s.URL != "" {
+		return s.URL
+	}
+	return strings.TrimRight(*flagInstanceURL, "/") + "/api/v1/statuses/" + s.ID
+}
+
+// feedItemTitle is the author and the start of the post as plain text, or
+// its content warning.
+func feedItemTitle(s mastodon.Status) string {
+	prefix := "@" + s.Account.Acct
+	if s.Reblog != nil {
+		prefix += " 🔁 @" + s.Reblog.Account.Acct
+		s = *s.Reblog
+	}
+	text := s.SpoilerText
+	if text == "" {
+		text = strings.Join(strings.Fields(s.ContentText()), " ")
+	}
+	text = truncate(text, feedTitleLength)
+	if text == "" {
+		return prefix
+	}
+	return prefix + ": " + text
+}
+
+// feedItemHTML is the post's HTML content with its content warning and links
+// to its media appended.
+func feedItemHTML(s mastodon.Status) string {
+	if s.Reblog != nil {
+		s = *s.Reblog
+	}
+	var b strings.Builder
+	if s.SpoilerText != "" {
+		fmt.Fprintf(&b, "<p><strong>CW: %s</strong></p>", html.EscapeString(s.SpoilerText))
+	}
+	b.WriteString(s.Content)
+	for _, m := range s.MediaAttachments {
+		label := m.Type
+		if m.Description != nil && *m.Description != "" {
+			label = *m.Description
+		}
+		fmt.Fprintf(&b, `<p><a href="%s">%s</a></p>`, html.EscapeString(m.URL), html.EscapeString(label))
+	}
+	return b.String()
+}
+
+// instanceHost returns the host of an instance URL for display.
+func instanceHost(instance string) string {
+	if u, err := url.Parse(instance); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return instance
+}
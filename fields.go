@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// jsonValue converts a result item to its generic JSON form, keeping numbers
+// exact so large IDs survive the round trip.
+func jsonValue(item interface{}) (interface{}, error) {
+	b, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// fieldPruner cuts JSON results down to the --fields dot paths, keeping
+// their nesting: account.acct yields {"account": {"acct": ...}}. On arrays a
+// path applies to every element, or to one element when the segment is an
+// index.
+type fieldPruner struct {
+	paths [][]string
+	found []bool
+}
+
+func newFieldPruner(list string) *fieldPruner {
+	p := &fieldPruner{}
+	for _, c := range parseFields(list) {
+		p.paths = append(p.paths, strings.Split(c.path, "."))
+	}
+	p.found = make([]bool, len(p.paths))
+	return p
+}
+
+// prune returns the parts of item the paths select.
+func (p *fieldPruner) prune(item interface{}) (interface{}, error) {
+	v, err := jsonValue(item)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{} = map[string]interface{}{}
+	for i, path := range p.paths {
+		if sel, ok := selectPath(v, path); ok {
+			p.found[i] = true
+			out = mergeJSON(out, sel)
+		}
+	}
+	return out, nil
+}
+
+// pruneAll prunes every item of a result, keeping the shape of lists and
+// search results.
+func (p *fieldPruner) pruneAll(data interface{}) (interface{}, error) {
+	pruneList := func(items []interface{}) ([]interface{}, error) {
+		out := make([]interface{}, len(items))
+		for i, item := range items {
+			v, err := p.prune(item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+	}
+	if result, ok := data.(mastodon.SearchResult); ok {
+		pruned := map[string]interface{}{}
+		for key, items := range map[string][]interface{}{
+			"accounts": resultItems(result.Accounts),
+			"statuses": resultItems(result.Statuses),
+			"hashtags": resultItems(result.Hashtags),
+		} {
+			list, err := pruneList(items)
+			if err != nil {
+				return nil, err
+			}
+			pruned[key] = list
+		}
+		return pruned, nil
+	}
+	if reflect.ValueOf(data).Kind() == reflect.Slice {
+		return pruneList(resultItems(data))
+	}
+	return p.prune(data)
+}
+
+// check reports a path that matched nothing in any item pruned so far,
+// which is most likely a typo.
+func (p *fieldPruner) check(items int) error {
+	if items == 0 {
+		return nil
+	}
+	for i, ok := range p.found {
+		if !ok {
+			return fmt.Errorf("unknown field %q", strings.Join(p.paths[i], "."))
+		}
+	}
+	return nil
+}
+
+// selectPath returns v cut down to the value at path, wrapped in the objects
+// and arrays that lead to it.
+func selectPath(v interface{}, path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		return v, true
+	}
+	switch node := v.(type) {
+	case map[string]interface{}:
+		child, ok := node[path[0]]
+		if !ok {
+			return nil, false
+		}
+		sel, ok := selectPath(child, path[1:])
+		return map[string]interface{}{path[0]: sel}, ok
+	case []interface{}:
+		if i, err := strconv.Atoi(path[0]); err == nil {
+			if i < 0 || i >= len(node) {
+				return []interface{}{}, i >= 0
+			}
+			sel, ok := selectPath(node[i], path[1:])
+			return []interface{}{sel}, ok
+		}
+		out := make([]interface{}, len(node))
+		found := len(node) == 0
+		for i, el := range node {
+			sel, ok := selectPath(el, path)
+			out[i] = sel
+			found = found || ok
+		}
+		return out, found
+	case nil:
+		return nil, true
+	}
+	return nil, false
+}
+
+// mergeJSON combines two selections from the same value.
+func mergeJSON(a, b interface{}) interface{} {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		if b, ok := b.(map[string]interface{}); ok {
+			for k, v := range b {
+				if existing, ok := a[k]; ok {
+					a[k] = mergeJSON(existing, v)
+				} else {
+					a[k] = v
+				}
+			}
+			return a
+		}
+	case []interface{}:
+		if b, ok := b.([]interface{}); ok && len(a) == len(b) {
+			for i := range a {
+				a[i] = mergeJSON(a[i], b[i])
+			}
+			return a
+		}
+	case nil:
+		return b
+	}
+	return b
+}
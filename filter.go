@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// postFilter narrows fetched posts on the client, so any command that
+// returns posts can be filtered without setting up server-side filters.
+type postFilter struct {
+	include   *regexp.Regexp
+	exclude   *regexp.Regexp
+	muted     []*regexp.Regexp
+	languages []string
+	noBoosts  bool
+	noReplies bool
+	onlyMedia bool
+	cwKeyword string
+
+	minBoosts, minFavs, minReplies int
+}
+
+// activeFilter is the filter built from this run's flags; nil when none of
+// them is set.
+var activeFilter *postFilter
+
+// configureFilter builds activeFilter from --include, --exclude, --lang,
+// --no-boosts, --no-replies, --only-media, --cw-only, --min-boosts,
+// --min-favs, --min-replies, and the mute file, and validates --sort.
+func configureFilter() error {
+	f := &postFilter{
+		noBoosts:  *flagNoBoosts,
+		noReplies: *flagNoReplyPosts,
+		onlyMedia: *flagOnlyMedia,
+		cwKeyword: strings.ToLower(*flagCWOnly),
+
+		minBoosts:  *flagMinBoosts,
+		minFavs:    *flagMinFavs,
+		minReplies: *flagMinReplies,
+	}
+	switch *flagSort {
+	case "", "engagement", "newest", "oldest":
+	default:
+		return fmt.Errorf("invalid --sort %q (want engagement, newest, or oldest)", *flagSort)
+	}
+	var err error
+	if *flagInclude != "" {
+		if f.include, err = regexp.Compile(*flagInclude); err != nil {
+			return fmt.Errorf("invalid --include: %w", err)
+		}
+	}
+	if *flagExcludeText != "" {
+		if f.exclude, err = regexp.Compile(*flagExcludeText); err != nil {
+			return fmt.Errorf("invalid --exclude: %w", err)
+		}
+	}
+	if f.muted, err = loadMuteFile(); err != nil {
+		return err
+	}
+	for _, lang := range strings.Split(*flagLang, ",") {
+		if lang = strings.ToLower(strings.TrimSpace(lang)); lang != "" {
+			f.languages = append(f.languages, lang)
+		}
+	}
+	if f.include != nil || f.exclude != nil || len(f.muted) > 0 || len(f.languages) > 0 ||
+		f.noBoosts || f.noReplies || f.onlyMedia || f.cwKeyword != "" || f.requiresEngagement() {
+		activeFilter = f
+	}
+	return nil
+}
+
+// muteFileName is the mute file read from the config directory when
+// --mute-file isn't given.
+const muteFileName = "muted-words.txt"
+
+// loadMuteFile reads the mute list named by --mute-file, or muted-words.txt
+// in the config directory if it exists. Each line is a word or phrase,
+// matched case-insensitively as a whole word, or a regular expression after
+// "regex:". Blank lines and lines starting with "# " are ignored, so
+// "#hashtag" is still a pattern. --mute-file "" turns muting off.
+func loadMuteFile() ([]*regexp.Regexp, error) {
+	path := *flagMuteFile
+	if !flagWasSet("mute-file") {
+		dir, err := configDir()
+		if err != nil {
+			return nil, nil
+		}
+		path = filepath.Join(dir, muteFileName)
+		if _, err := os.Stat(path); err != nil {
+			return nil, nil
+		}
+	}
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading mute file: %w", err)
+	}
+	defer file.Close()
+
+	var patterns []*regexp.Regexp
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line == "#" || strings.HasPrefix(line, "# ") {
+			continue
+		}
+		re, err := mutePattern(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		patterns = append(patterns, re)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading mute file: %w", err)
+	}
+	return patterns, nil
+}
+
+// mutePattern compiles one line of a mute file.
+func mutePattern(line string) (*regexp.Regexp, error) {
+	if expr, ok := strings.CutPrefix(line, "regex:"); ok {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			return nil, errors.New("empty regex")
+		}
+		return regexp.Compile(expr)
+	}
+	const boundary = `[^\pL\pN_]`
+	return regexp.Compile(`(?i)(?:^|` + boundary + `)` + regexp.QuoteMeta(line) + `(?:$|` + boundary + `)`)
+}
+
+// keep reports whether a post passes the filter. Boosts are judged by the
+// boosted post's content.
+func (f *postFilter) keep(s mastodon.Status) bool {
+	if f == nil {
+		return true
+	}
+	if f.noBoosts && s.Reblog != nil {
+		return false
+	}
+	post, _ := resolvePost(s)
+	if f.noReplies && post.InReplyToID != nil {
+		return false
+	}
+	if f.onlyMedia && len(post.MediaAttachments) == 0 {
+		return false
+	}
+	if post.ReblogsCount < f.minBoosts || post.FavouritesCount < f.minFavs || post.RepliesCount < f.minReplies {
+		return false
+	}
+	if f.cwKeyword != "" && !strings.Contains(strings.ToLower(post.SpoilerText), f.cwKeyword) {
+		return false
+	}
+	if len(f.languages) > 0 && !f.matchesLanguage(post.Language) {
+		return false
+	}
+	if f.include != nil || f.exclude != nil || len(f.muted) > 0 {
+		text := post.ContentText()
+		if post.SpoilerText != "" {
+			text = post.SpoilerText + "\n" + text
+		}
+		if f.include != nil && !f.include.MatchString(text) {
+			return false
+		}
+		if f.exclude != nil && f.exclude.MatchString(text) {
+			return false
+		}
+		for _, re := range f.muted {
+			if re.MatchString(text) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchesLanguage reports whether lang is one of the wanted languages; a
+// code like "en" also matches regional variants such as "en-GB". Posts
+// without a language never match.
+func (f *postFilter) matchesLanguage(lang *string) bool {
+	if lang == nil {
+		return false
+	}
+	code := strings.ToLower(*lang)
+	for _, want := range f.languages {
+		if code == want || strings.HasPrefix(code, want+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// keepNotification reports whether a notification passes the filter.
+// Notifications without a post, such as follows, are only dropped by
+// filters that require some property of a post, not by ones that hide posts.
+func (f *postFilter) keepNotification(n mastodon.Notification) bool {
+	if n.Status != nil {
+		return f.keep(*n.Status)
+	}
+	return f == nil || f.include == nil && len(f.languages) == 0 && !f.onlyMedia && f.cwKeyword == "" && !f.requiresEngagement()
+}
+
+func (f *postFilter) requiresEngagement() bool {
+	return f.minBoosts > 0 || f.minFavs > 0 || f.minReplies > 0
+}
+
+// apply filters the posts in results made of posts; other results pass
+// through.
+func (f *postFilter) apply(data interface{}) interface{} {
+	if f == nil {
+		return data
+	}
+	switch v := data.(type) {
+	case []mastodon.Status:
+		filtered := []mastodon.Status{}
+		for _, s := range v {
+			if f.keep(s) {
+				filtered = append(filtered, s)
+			}
+		}
+		return filtered
+	case mastodon.SearchResult:
+		v.Statuses = f.apply(v.Statuses).([]mastodon.Status)
+		return v
+	case []mastodon.Notification:
+		filtered := []mastodon.Notification{}
+		for _, n := range v {
+			if f.keepNotification(n) {
+				filtered = append(filtered, n)
+			}
+		}
+		return filtered
+	}
+	return data
+}
+
+// keepItem reports whether a single post or notification passes the
+// filter; other items always do.
+func (f *postFilter) keepItem(item interface{}) bool {
+	switch v := item.(type) {
+	case mastodon.Status:
+		return f.keep(v)
+	case mastodon.Notification:
+		return f.keepNotification(v)
+	}
+	return true
+}
+
+// sortResults orders the posts in data by --sort: engagement (boosts,
+// favourites, and replies combined, highest first), newest, or oldest.
+// Without --sort results keep the server's order.
+func sortResults(data interface{}) interface{} {
+	if *flagSort == "" {
+		return data
+	}
+	switch v := data.(type) {
+	case []mastodon.Status:
+		sortByPost(v, func(s mastodon.Status) *mastodon.Status { return &s })
+	case mastodon.SearchResult:
+		sortByPost(v.Statuses, func(s mastodon.Status) *mastodon.Status { return &s })
+	case []mastodon.Notification:
+		sortByPost(v, func(n mastodon.Notification) *mastodon.Status { return n.Status })
+	}
+	return data
+}
+
+// sortByPost sorts items in place by the post each refers to, which may be
+// nil for notifications without one.
+func sortByPost[T any](items []T, post func(T) *mastodon.Status) {
+	key := func(item T) int64 {
+		s := post(item)
+		if s == nil {
+			return 0
+		}
+		if *flagSort == "engagement" {
+			p, _ := resolvePost(*s)
+			return int64(p.ReblogsCount + p.FavouritesCount + p.RepliesCount)
+		}
+		t, _ := time.Parse(time.RFC3339, s.CreatedAt)
+		return t.UnixMilli()
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if *flagSort == "oldest" {
+			return key(items[i]) < key(items[j])
+		}
+		return key(items[i]) > key(items[j])
+	})
+}
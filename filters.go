@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// DeletedFilter reports a deleted filter.
+type DeletedFilter struct {
+	Filter mastodon.Filter `json:"filter"`
+}
+
+// runFilters handles "filters [list|create|edit|delete]". Filters can be
+// named by ID or title.
+func runFilters(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+	if len(args) == 0 || args[0] == "list" {
+		return client.Filters(ctx)
+	}
+	sub, rest := args[0], args[1:]
+	switch sub {
+	case "create":
+		if len(rest) == 0 {
+			return nil, errors.New("filters create requires a title")
+		}
+		params, err := filterParamsFromFlags()
+		if err != nil {
+			return nil, err
+		}
+		params.Title = strings.Join(rest, " ")
+		if len(params.Context) == 0 {
+			params.Context = mastodon.FilterContexts
+		}
+		if len(params.Keywords) == 0 {
+			return nil, errors.New("filters create requires at least one --keyword")
+		}
+		filter, err := client.CreateFilter(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		return *filter, nil
+	case "edit":
+		if len(rest) == 0 {
+			return nil, errors.New("filters edit requires a filter ID or title")
+		}
+		filter, err := findFilter(ctx, client, rest[0])
+		if err != nil {
+			return nil, err
+		}
+		params, err := filterParamsFromFlags()
+		if err != nil {
+			return nil, err
+		}
+		params.Title = strings.Join(rest[1:], " ")
+		for _, word := range flagDropKeyword {
+			found := false
+			for _, k := range filter.Keywords {
+				if strings.EqualFold(k.Keyword, word) {
+					params.Keywords = append(params.Keywords, mastodon.FilterKeywordParams{ID: k.ID, Destroy: true})
+					found = true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("filter %q has no keyword %q", filter.Title, word)
+			}
+		}
+		updated, err := client.UpdateFilter(ctx, filter.ID, params)
+		if err != nil {
+			return nil, err
+		}
+		return *updated, nil
+	case "delete":
+		if len(rest) == 0 {
+			return nil, errors.New("filters delete requires a filter ID or title")
+		}
+		filter, err := findFilter(ctx, client, rest[0])
+		if err != nil {
+			return nil, err
+		}
+		if err := client.DeleteFilter(ctx, filter.ID); err != nil {
+			return nil, err
+		}
+		return DeletedFilter{Filter: *filter}, nil
+	default:
+		return nil, fmt.Errorf("unknown filters subcommand: %s", sub)
+	}
+}
+
+// filterParamsFromFlags collects --context, --keyword, --whole-word,
+// --filter-action, and --expires.
+func filterParamsFromFlags() (mastodon.FilterParams, error) {
+	var p mastodon.FilterParams
+	if *flagContext != "" {
+		for _, c := range strings.Split(*flagContext, ",") {
+			if c = strings.TrimSpace(c); c != "" {
+				p.Context = append(p.Context, c)
+			}
+		}
+		if err := mastodon.ValidateFilterContext(p.Context); err != nil {
+			return p, err
+		}
+	}
+	if flagWasSet("filter-action") {
+		switch *flagAction {
+		case "warn", "hide", "blur":
+			p.Action = *flagAction
+		default:
+			return p, fmt.Errorf("invalid --filter-action %q (want warn, hide, or blur)", *flagAction)
+		}
+	}
+	if flagWasSet("expires") {
+		if *flagExpires < 0 {
+			return p, errors.New("--expires must not be negative")
+		}
+		p.ExpiresIn = flagExpires
+	}
+	for _, k := range flagKeywords {
+		p.Keywords = append(p.Keywords, mastodon.FilterKeywordParams{Keyword: k, WholeWord: *flagWholeWord})
+	}
+	return p, nil
+}
+
+func findFilter(ctx context.Context, client *mastodon.Client, ref string) (*mastodon.Filter, error) {
+	filters, err := client.Filters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, f := range filters {
+		if f.ID == ref || strings.EqualFold(f.Title, ref) {
+			return &filters[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no filter found for %q", ref)
+}
@@ -0,0 +1,1497 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+func formatText(command string, data interface{}) {
+	switch command {
+	case "home", "posts", "public", "local", "federated", "tag", "bookmarks", "pinned", "lists timeline", "trends posts", "trends statuses", "archive posts", "archive search":
+		statuses, ok := data.([]mastodon.Status)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatStatuses(statuses)
+	case "mentions":
+		notifications, ok := data.([]mastodon.Notification)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatMentions(notifications)
+	case "notifications", "archive notifications":
+		notifications, ok := data.([]mastodon.Notification)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatNotifications(notifications)
+	case "search":
+		result, ok := data.(mastodon.SearchResult)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatSearch(result)
+	case "search-accounts":
+		accounts, ok := data.([]mastodon.Account)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatAccountResults(accounts)
+	case "conversations":
+		convs, ok := data.([]mastodon.Conversation)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatConversations(convs)
+	case "status":
+		status, ok := data.(mastodon.Status)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatStatusDetail(status)
+	case "count":
+		count, ok := data.(CountResult)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatCount(count)
+	case "audit alt-text":
+		audit, ok := data.(AltTextAudit)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatAltTextAudit(audit)
+	case "api":
+		formatAPIResponse(data)
+	case "translate":
+		t, ok := data.(mastodon.Translation)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatTranslation(t)
+	case "edit":
+		status, ok := data.(mastodon.Status)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		fmt.Printf("Edited %s\n", status.ID)
+		fmt.Printf("🔗 %s\n", status.URL)
+	case "history":
+		edits, ok := data.([]mastodon.StatusEdit)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatHistory(edits)
+	case "delete":
+		status, ok := data.(mastodon.Status)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		fmt.Printf("Deleted %s\n", status.ID)
+	case "post", "reply", "dm", "redraft":
+		if scheduled, ok := data.(mastodon.ScheduledStatus); ok {
+			fmt.Printf("Scheduled %s for %s\n", scheduled.ID, formatTime(scheduled.ScheduledAt))
+			return
+		}
+		if thread, ok := data.([]mastodon.Status); ok {
+			fmt.Printf("Posted a thread of %d\n", len(thread))
+			for i, status := range thread {
+				fmt.Printf("%d. %s  🔗 %s\n", i+1, status.ID, status.URL)
+			}
+			return
+		}
+		status, ok := data.(mastodon.Status)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		fmt.Printf("Posted %s\n", status.ID)
+		fmt.Printf("🔗 %s\n", status.URL)
+	case "boost", "unboost":
+		status, ok := data.(mastodon.Status)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		post, _ := resolvePost(status)
+		if command == "boost" {
+			fmt.Printf("Boosted %s\n", post.ID)
+		} else {
+			fmt.Printf("Removed boost from %s\n", post.ID)
+		}
+		fmt.Printf("🔁 %d\n", post.ReblogsCount)
+		fmt.Printf("🔗 %s\n", post.URL)
+	case "fav", "unfav":
+		status, ok := data.(mastodon.Status)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		if command == "fav" {
+			fmt.Printf("Favourited %s\n", status.ID)
+		} else {
+			fmt.Printf("Removed favourite from %s\n", status.ID)
+		}
+		fmt.Printf("⭐ %d\n", status.FavouritesCount)
+		fmt.Printf("🔗 %s\n", status.URL)
+	case "vote":
+		poll, ok := data.(mastodon.Poll)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		fmt.Println("Vote recorded.")
+		formatPoll(poll)
+	case "instance":
+		info, ok := data.(InstanceInfo)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatInstance(info)
+	case "compare-instances":
+		compared, ok := data.([]ComparedInstance)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatComparison(compared)
+	case "instance peers":
+		peers, ok := data.([]string)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		for _, p := range peers {
+			fmt.Println(p)
+		}
+		fmt.Printf("\n%d peers\n", len(peers))
+	case "instance activity":
+		activity, ok := data.([]mastodon.InstanceActivity)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatActivity(activity)
+	case "export":
+		result, ok := data.(ExportResult)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatExport(result)
+	case "archive stats":
+		stats, ok := data.(ArchiveStats)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatArchiveStats(stats)
+	case "import":
+		result, ok := data.(ImportResult)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatImport(result)
+	case "markers get":
+		markers, ok := data.([]TimelineMarker)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		if len(markers) == 0 {
+			fmt.Println("No read markers saved.")
+		}
+		for _, m := range markers {
+			formatMarker(m)
+		}
+	case "markers set":
+		marker, ok := data.(TimelineMarker)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatMarker(marker)
+	case "preferences":
+		prefs, ok := data.(mastodon.Preferences)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatPreferences(prefs)
+	case "rate-limit":
+		rl, ok := data.(mastodon.RateLimit)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatRateLimit(rl)
+	case "instance rules":
+		rules, ok := data.([]mastodon.Rule)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatRules(rules)
+	case "trends tags":
+		tags, ok := data.([]mastodon.Tag)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatTrendingTags(tags)
+	case "trends links":
+		links, ok := data.([]mastodon.TrendingLink)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatTrendingLinks(links)
+	case "filters", "filters list":
+		filters, ok := data.([]mastodon.Filter)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		if len(filters) == 0 {
+			fmt.Println("No filters.")
+			return
+		}
+		for _, f := range filters {
+			formatFilter(f)
+		}
+	case "filters create", "filters edit":
+		filter, ok := data.(mastodon.Filter)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		if command == "filters create" {
+			fmt.Println("Created filter:")
+		} else {
+			fmt.Println("Updated filter:")
+		}
+		formatFilter(filter)
+	case "filters delete":
+		result, ok := data.(DeletedFilter)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		fmt.Printf("Deleted filter %s: %s\n", result.Filter.ID, result.Filter.Title)
+	case "lists":
+		lists, ok := data.([]mastodon.List)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		if len(lists) == 0 {
+			fmt.Println("No lists.")
+			return
+		}
+		for _, l := range lists {
+			fmt.Printf("📋 %s  %s\n", l.ID, l.Title)
+		}
+	case "lists create", "lists rename":
+		list, ok := data.(mastodon.List)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		verb := "Created"
+		if command == "lists rename" {
+			verb = "Renamed"
+		}
+		fmt.Printf("%s list %s: %s\n", verb, list.ID, list.Title)
+	case "lists delete":
+		result, ok := data.(DeletedList)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		fmt.Printf("Deleted list %s: %s\n", result.List.ID, result.List.Title)
+	case "lists add", "lists remove":
+		result, ok := data.(ListMembership)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		verb := "Removed from"
+		if result.Added {
+			verb = "Added to"
+		}
+		for _, a := range result.Accounts {
+			fmt.Printf("%s %s: @%s\n", verb, result.List.Title, a.Acct)
+		}
+	case "scheduled list":
+		scheduled, ok := data.([]mastodon.ScheduledStatus)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		if len(scheduled) == 0 {
+			fmt.Println("No scheduled posts.")
+			return
+		}
+		for _, s := range scheduled {
+			formatScheduledStatus(s)
+		}
+	case "scheduled reschedule":
+		scheduled, ok := data.(mastodon.ScheduledStatus)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		fmt.Printf("Rescheduled %s for %s\n", scheduled.ID, formatTime(scheduled.ScheduledAt))
+	case "scheduled cancel":
+		result, ok := data.(CancelledScheduledStatus)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		fmt.Printf("Cancelled scheduled post %s\n", result.ID)
+	case "upload":
+		media, ok := data.([]mastodon.MediaAttachment)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		for _, m := range media {
+			fmt.Printf("📎 %s (%s) %s\n", m.ID, m.Type, m.URL)
+			if m.Description != nil && *m.Description != "" {
+				fmt.Printf("   Alt: %s\n", *m.Description)
+			}
+		}
+	case "account", "profile set", "profile avatar", "profile header":
+		profile, ok := data.(AccountProfile)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatProfile(profile)
+	case "followers", "following", "mutes", "blocks", "boosters", "favouriters", "endorsements", "lists members", "archive accounts":
+		accounts, ok := data.([]mastodon.Account)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatAccounts(accounts)
+	case "follow", "unfollow":
+		result, ok := data.(AccountRelationship)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		rel := result.Relationship
+		switch {
+		case rel.Following:
+			fmt.Printf("Following @%s\n", result.Account.Acct)
+		case rel.Requested:
+			fmt.Printf("Follow requested for @%s (awaiting approval)\n", result.Account.Acct)
+		default:
+			fmt.Printf("Not following @%s\n", result.Account.Acct)
+		}
+		if rel.FollowedBy {
+			fmt.Printf("@%s follows you\n", result.Account.Acct)
+		}
+	case "endorse", "unendorse":
+		result, ok := data.(AccountRelationship)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		if result.Relationship.Endorsed {
+			fmt.Printf("Featuring @%s on your profile\n", result.Account.Acct)
+		} else {
+			fmt.Printf("No longer featuring @%s on your profile\n", result.Account.Acct)
+		}
+	case "relationship":
+		rels, ok := data.([]AccountRelationship)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		formatRelationships(rels)
+	case "report":
+		report, ok := data.(mastodon.Report)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		fmt.Printf("Reported @%s (report %s, category %s)\n", report.TargetAccount.Acct, report.ID, report.Category)
+		if len(report.StatusIDs) > 0 {
+			fmt.Printf("Posts: %s\n", strings.Join(report.StatusIDs, ", "))
+		}
+		if report.Forwarded {
+			fmt.Println("Forwarded to the remote server")
+		}
+	case "mute", "unmute", "block", "unblock":
+		result, ok := data.(AccountRelationship)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		rel := result.Relationship
+		switch {
+		case command == "mute" || command == "unmute":
+			if rel.Muting {
+				fmt.Printf("Muted @%s\n", result.Account.Acct)
+			} else {
+				fmt.Printf("Unmuted @%s\n", result.Account.Acct)
+			}
+		case rel.Blocking:
+			fmt.Printf("Blocked @%s\n", result.Account.Acct)
+		default:
+			fmt.Printf("Unblocked @%s\n", result.Account.Acct)
+		}
+	case "domain-block", "domain-unblock":
+		result, ok := data.(DomainBlockResult)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		if result.Blocked {
+			fmt.Printf("Blocked domain %s\n", result.Domain)
+		} else {
+			fmt.Printf("Unblocked domain %s\n", result.Domain)
+		}
+	case "domain-blocks":
+		domains, ok := data.([]string)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		if len(domains) == 0 {
+			fmt.Println("No blocked domains.")
+			return
+		}
+		for _, d := range domains {
+			fmt.Println(d)
+		}
+	case "login":
+		result, ok := data.(LoginResult)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		fmt.Printf("Logged in as @%s on %s\n", result.Account.Acct, result.Instance)
+		if result.Profile != "" {
+			fmt.Printf("Account profile: %s\n", result.Profile)
+		}
+		fmt.Printf("Scopes: %s\n", result.Scope)
+		fmt.Printf("Token saved to %s\n", result.SavedTo)
+	case "whoami":
+		me, ok := data.(WhoAmI)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		fmt.Printf("@%s", me.Acct)
+		if me.DisplayName != "" {
+			fmt.Printf(" (%s)", me.DisplayName)
+		}
+		fmt.Println()
+		fmt.Printf("ID: %s\n", me.ID)
+		fmt.Printf("Instance: %s\n", me.Instance)
+		if me.Profile != "" {
+			fmt.Printf("Account profile: %s\n", me.Profile)
+		}
+		if me.App != "" {
+			fmt.Printf("App: %s\n", me.App)
+		}
+		scope := me.Scope
+		if scope == "" {
+			scope = "unknown (the instance doesn't report them)"
+		}
+		fmt.Printf("Scopes: %s\n", scope)
+	case "dry-run":
+		run, ok := data.(DryRun)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		fmt.Printf("Dry run: %d request(s) not sent\n", len(run.Requests))
+		for _, req := range run.Requests {
+			var b strings.Builder
+			writeDryRunRequest(&b, req)
+			fmt.Printf("\n%s", b.String())
+		}
+	case "config list":
+		values, ok := data.([]ConfigValue)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, v := range values {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", v.Key, v.Value, v.Source)
+		}
+		tw.Flush()
+	case "config get":
+		v, ok := data.(ConfigValue)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		fmt.Println(v.Value)
+	case "config set":
+		result, ok := data.(ConfigSetResult)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		fmt.Printf("Set %s = %s in %s\n", result.Key, result.Value, result.Path)
+	case "version":
+		v, ok := data.(VersionInfo)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		fmt.Printf("mastodon-scout %s\n", v.Version)
+		if v.Commit != "" {
+			fmt.Printf("Commit: %s\n", v.Commit)
+		}
+		if v.Date != "" {
+			fmt.Printf("Built: %s\n", v.Date)
+		}
+		fmt.Printf("Go: %s (%s)\n", v.GoVersion, v.Platform)
+		switch {
+		case v.Latest == nil:
+		case v.UpdateAvailable:
+			fmt.Printf("Update available: %s (%s)\n", v.Latest.Version, v.Latest.URL)
+		default:
+			fmt.Printf("Latest release: %s\n", v.Latest.Version)
+		}
+	case "docs man":
+		pages, ok := data.(ManPages)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		fmt.Printf("Wrote %d man pages to %s\n", len(pages.Files), pages.Dir)
+	case "auth list":
+		creds, ok := data.([]StoredCredential)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		if len(creds) == 0 {
+			fmt.Println("No stored tokens.")
+			return
+		}
+		for _, c := range creds {
+			fmt.Printf("%s\t@%s\t%s\t%s\n", c.Name, c.Account, c.Storage, c.Scope)
+		}
+	case "auth remove":
+		cred, ok := data.(StoredCredential)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		fmt.Printf("Removed stored token %s (%s)\n", cred.Name, cred.Storage)
+	case "bookmark", "unbookmark":
+		status, ok := data.(mastodon.Status)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		if command == "bookmark" {
+			fmt.Printf("Bookmarked %s\n", status.ID)
+		} else {
+			fmt.Printf("Removed bookmark from %s\n", status.ID)
+		}
+		fmt.Printf("🔗 %s\n", status.URL)
+	case "pin", "unpin":
+		status, ok := data.(mastodon.Status)
+		if !ok {
+			fmt.Println("Error: unexpected data format")
+			return
+		}
+		if command == "pin" {
+			fmt.Printf("📌 Pinned %s\n", status.ID)
+		} else {
+			fmt.Printf("Unpinned %s\n", status.ID)
+		}
+		fmt.Printf("🔗 %s\n", status.URL)
+	}
+}
+
+// resolvePost returns the displayable post and the booster's username (if it's a boost).
+func resolvePost(s mastodon.Status) (post mastodon.Status, boostedBy string) {
+	if s.Reblog != nil {
+		return *s.Reblog, s.Account.Username
+	}
+	return s, ""
+}
+
+func formatStatuses(statuses []mastodon.Status) {
+	if len(statuses) == 0 {
+		fmt.Println("No posts found.")
+		return
+	}
+	for i, s := range statuses {
+		formatStatus(i+1, s)
+	}
+}
+
+func formatStatus(n int, s mastodon.Status) {
+	post, boostedBy := resolvePost(s)
+	fmt.Println(paint("header", fmt.Sprintf("--- Post %d ---", n)))
+	if boostedBy != "" {
+		fmt.Printf("🔁 %s boosted\n", paint("username", "@"+boostedBy))
+	}
+	fmt.Printf("%s (%s)\n", paint("username", "@"+post.Account.Username), post.Account.DisplayName)
+	fmt.Println(paint("timestamp", formatTime(post.CreatedAt)))
+	fmt.Printf("\n%s\n\n", statusText(post))
+	if cwHidden(post) {
+		if len(post.MediaAttachments) > 0 {
+			fmt.Printf("📎 %d attachment(s)\n", len(post.MediaAttachments))
+		}
+	} else {
+		if post.Poll != nil {
+			formatPoll(*post.Poll)
+		}
+		for _, m := range post.MediaAttachments {
+			alt := "no alt text"
+			if m.Description != nil && *m.Description != "" {
+				alt = *m.Description
+			}
+			fmt.Printf("📎 %s: %s\n   %s\n", m.Type, alt, paint("link", m.URL))
+			printPreview(m)
+		}
+	}
+	fmt.Println(formatCounts(post))
+	fmt.Printf("🔗 %s\n\n", paint("link", post.URL))
+}
+
+// formatStatusDetail prints everything about a single status.
+func formatStatusDetail(s mastodon.Status) {
+	post, boostedBy := resolvePost(s)
+	if boostedBy != "" {
+		fmt.Printf("🔁 Boosted by %s\n", paint("username", "@"+boostedBy))
+	}
+	fmt.Printf("%s (%s)\n", paint("username", "@"+post.Account.Acct), post.Account.DisplayName)
+	fmt.Printf("🕒 %s", paint("timestamp", formatTime(post.CreatedAt)))
+	if post.EditedAt != nil {
+		fmt.Printf("  (edited %s)", paint("timestamp", formatTime(*post.EditedAt)))
+	}
+	fmt.Println()
+	details := []string{"👁 " + post.Visibility}
+	if post.Language != nil && *post.Language != "" {
+		details = append(details, "🌐 "+*post.Language)
+	}
+	if post.Application != nil && post.Application.Name != "" {
+		details = append(details, "📱 "+post.Application.Name)
+	}
+	fmt.Println(strings.Join(details, "  "))
+	if post.InReplyToID != nil {
+		fmt.Printf("↩️  In reply to %s\n", *post.InReplyToID)
+	}
+	fmt.Printf("\n%s\n\n", statusText(post))
+	if post.Poll != nil && !cwHidden(post) {
+		formatPoll(*post.Poll)
+	}
+	for i, m := range post.MediaAttachments {
+		fmt.Printf("📎 %d. %s %s\n", i+1, m.Type, paint("link", m.URL))
+		if m.Description != nil && *m.Description != "" {
+			fmt.Printf("   Alt: %s\n", *m.Description)
+		} else {
+			fmt.Println("   Alt: (none)")
+		}
+		if !cwHidden(post) {
+			printPreview(m)
+		}
+	}
+	if post.Card != nil {
+		fmt.Printf("🃏 %s\n   %s\n", post.Card.Title, paint("link", post.Card.URL))
+	}
+	if len(post.Tags) > 0 {
+		tags := make([]string, len(post.Tags))
+		for i, t := range post.Tags {
+			tags[i] = paint("hashtag", "#"+t.Name)
+		}
+		fmt.Printf("🏷  %s\n", strings.Join(tags, " "))
+	}
+	fmt.Println(formatCounts(post))
+	fmt.Printf("🔗 %s\n", paint("link", post.URL))
+}
+
+// statusText is a post's content for text output. Behind a content warning
+// only the warning is shown, as clients do, unless --show-cw is set.
+func statusText(post mastodon.Status) string {
+	text := highlight(mastodon.PlainText(post.Content))
+	if post.SpoilerText == "" {
+		return text
+	}
+	cw := "⚠️  " + paint("cw", "CW: "+post.SpoilerText)
+	if cwHidden(post) {
+		return cw + "\n(hidden; use --show-cw to expand)"
+	}
+	return cw + "\n\n" + text
+}
+
+// formatCount prints a post's length against the limit, and what it would
+// take to post it when it's over.
+func formatCount(c CountResult) {
+	if c.Remaining >= 0 {
+		fmt.Printf("%d/%d characters (%d left)\n", c.Characters, c.Limit, c.Remaining)
+		return
+	}
+	fmt.Printf("%d/%d characters (%d over)\n", c.Characters, c.Limit, -c.Remaining)
+	fmt.Printf("post --thread would split it into %d posts\n", c.Parts)
+}
+
+func formatAltTextAudit(a AltTextAudit) {
+	if a.Missing == 0 {
+		fmt.Printf("Every attachment in your last %d posts with media has alt text.\n", a.Posts)
+		return
+	}
+	fmt.Printf("%d of %d attachments in %d posts have no alt text:\n", a.Missing, a.Attachments, a.Posts)
+	for _, issue := range a.Issues {
+		fmt.Println()
+		fmt.Printf("%s  %d of %d missing\n", paint("timestamp", formatTime(issue.CreatedAt)), len(issue.Media), issue.Total)
+		if text := strings.TrimSpace(issue.Text); text != "" {
+			fmt.Println(truncate(strings.Join(strings.Fields(text), " "), 100))
+		}
+		for _, m := range issue.Media {
+			fmt.Printf("📎 %s %s\n", m.Type, paint("link", m.URL))
+		}
+		fmt.Printf("🔗 %s\n", paint("link", issue.URL))
+	}
+}
+
+// formatAPIResponse prints a raw API response: JSON indented, anything else
+// as it came.
+func formatAPIResponse(data interface{}) {
+	switch body := data.(type) {
+	case nil:
+		fmt.Println("(empty response)")
+	case json.RawMessage:
+		var b bytes.Buffer
+		if err := json.Indent(&b, body, "", "  "); err != nil {
+			fmt.Println(string(body))
+			return
+		}
+		fmt.Println(b.String())
+	case string:
+		fmt.Println(body)
+	default:
+		fmt.Println("Error: unexpected data format")
+	}
+}
+
+// formatTranslation prints a translated post. The reader asked for the
+// text, so a content warning doesn't hide it.
+func formatTranslation(t mastodon.Translation) {
+	from := t.DetectedSourceLanguage
+	if from == "" {
+		from = "an unknown language"
+	}
+	fmt.Printf("🌐 Translated from %s", from)
+	if t.Provider != "" {
+		fmt.Printf(" by %s", t.Provider)
+	}
+	fmt.Print("\n\n")
+	if t.SpoilerText != "" {
+		fmt.Printf("⚠️  %s\n\n", paint("cw", "CW: "+t.SpoilerText))
+	}
+	fmt.Println(highlight(t.ContentText()))
+	for i, m := range t.MediaAttachments {
+		if m.Description != "" {
+			fmt.Printf("📎 %d. Alt: %s\n", i+1, m.Description)
+		}
+	}
+}
+
+// cwHidden reports whether a post's body is hidden behind its content
+// warning.
+func cwHidden(post mastodon.Status) bool {
+	return post.SpoilerText != "" && !*flagShowCW
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
+
+// formatCounts is the replies, boosts, and favourites line under a post.
+func formatCounts(post mastodon.Status) string {
+	return fmt.Sprintf("💬 %s  🔁 %s  ⭐ %s",
+		paint("count", strconv.Itoa(post.RepliesCount)),
+		paint("count", strconv.Itoa(post.ReblogsCount)),
+		paint("count", strconv.Itoa(post.FavouritesCount)))
+}
+
+func formatHistory(edits []mastodon.StatusEdit) {
+	if len(edits) == 0 {
+		fmt.Println("No edit history.")
+		return
+	}
+	for i, e := range edits {
+		label := fmt.Sprintf("Version %d", i+1)
+		switch {
+		case i == len(edits)-1:
+			label += " (current)"
+		case i == 0:
+			label += " (original)"
+		}
+		fmt.Println(paint("header", "--- "+label+" ---"))
+		fmt.Println(paint("timestamp", formatTime(e.CreatedAt)))
+		if e.SpoilerText != "" {
+			fmt.Println(paint("cw", "CW: "+e.SpoilerText))
+		}
+		fmt.Printf("\n%s\n\n", highlight(mastodon.PlainText(e.Content)))
+		if e.Poll != nil {
+			formatPoll(*e.Poll)
+		}
+		if len(e.MediaAttachments) > 0 {
+			fmt.Printf("📎 %d attachment(s)\n\n", len(e.MediaAttachments))
+		}
+	}
+}
+
+func formatFilter(f mastodon.Filter) {
+	fmt.Printf("--- Filter %s: %s ---\n", f.ID, f.Title)
+	fmt.Printf("Action: %s  Contexts: %s\n", f.FilterAction, strings.Join(f.Context, ", "))
+	if f.ExpiresAt != nil {
+		fmt.Printf("Expires: %s\n", formatTime(*f.ExpiresAt))
+	}
+	for _, k := range f.Keywords {
+		if k.WholeWord {
+			fmt.Printf("  🔤 %s (whole word)\n", k.Keyword)
+		} else {
+			fmt.Printf("  🔤 %s\n", k.Keyword)
+		}
+	}
+	if len(f.Statuses) > 0 {
+		fmt.Printf("  + %d specific post(s)\n", len(f.Statuses))
+	}
+	fmt.Println()
+}
+
+func formatPoll(p mastodon.Poll) {
+	voted := make(map[int]bool, len(p.OwnVotes))
+	for _, i := range p.OwnVotes {
+		voted[i] = true
+	}
+	kind := "single choice"
+	if p.Multiple {
+		kind = "multiple choice"
+	}
+	fmt.Printf("📊 Poll (%s, %d votes)\n", kind, p.VotesCount)
+	// Percentages for multiple-choice polls are of voters, not votes.
+	total := p.VotesCount
+	if p.Multiple && p.VotersCount != nil {
+		total = *p.VotersCount
+	}
+	for i, o := range p.Options {
+		mark := ""
+		if voted[i] {
+			mark = " ✓"
+		}
+		if o.VotesCount == nil {
+			fmt.Printf("  %d. %s%s\n", i+1, o.Title, mark)
+			continue
+		}
+		pct := 0.0
+		if total > 0 {
+			pct = float64(*o.VotesCount) * 100 / float64(total)
+		}
+		fmt.Printf("  %d. %s: %d (%.0f%%)%s\n", i+1, o.Title, *o.VotesCount, pct, mark)
+	}
+	switch {
+	case p.Expired:
+		fmt.Println("  Closed")
+	case p.ExpiresAt != nil:
+		fmt.Printf("  Closes %s\n", formatTime(*p.ExpiresAt))
+	}
+	fmt.Println()
+}
+
+func formatScheduledStatus(s mastodon.ScheduledStatus) {
+	fmt.Printf("--- Scheduled %s ---\n", s.ID)
+	fmt.Printf("⏰ %s  (%s)\n", formatTime(s.ScheduledAt), s.Params.Visibility)
+	if s.Params.SpoilerText != nil && *s.Params.SpoilerText != "" {
+		fmt.Printf("CW: %s\n", *s.Params.SpoilerText)
+	}
+	fmt.Printf("\n%s\n\n", s.Params.Text)
+	if len(s.MediaAttachments) > 0 {
+		fmt.Printf("📎 %d attachment(s)\n\n", len(s.MediaAttachments))
+	}
+}
+
+func formatMentions(notifications []mastodon.Notification) {
+	if len(notifications) == 0 {
+		fmt.Println("No mentions found.")
+		return
+	}
+	for i, n := range notifications {
+		fmt.Println(paint("header", fmt.Sprintf("--- Mention %d ---", i+1)))
+		fmt.Printf("%s (%s) mentioned you\n", paint("username", "@"+n.Account.Username), n.Account.DisplayName)
+		fmt.Println(paint("timestamp", formatTime(n.CreatedAt)))
+		if n.Status != nil {
+			fmt.Printf("\n%s\n\n", statusText(*n.Status))
+		}
+	}
+}
+
+func formatNotifications(notifications []mastodon.Notification) {
+	if len(notifications) == 0 {
+		fmt.Println("No notifications found.")
+		return
+	}
+	for i, n := range notifications {
+		formatNotification(i+1, n)
+	}
+}
+
+func formatConversations(convs []mastodon.Conversation) {
+	if len(convs) == 0 {
+		fmt.Println("No conversations found.")
+		return
+	}
+	for i, c := range convs {
+		marker := ""
+		if c.Unread {
+			marker = " 🔵 unread"
+		}
+		fmt.Println(paint("header", fmt.Sprintf("--- Conversation %d%s ---", i+1, marker)))
+		var with []string
+		for _, a := range c.Accounts {
+			with = append(with, paint("username", "@"+a.Acct))
+		}
+		fmt.Printf("With: %s\n", strings.Join(with, ", "))
+		if c.LastStatus != nil {
+			fmt.Println(paint("timestamp", formatTime(c.LastStatus.CreatedAt)))
+			fmt.Printf("\n%s: %s\n", paint("username", "@"+c.LastStatus.Account.Acct), highlight(mastodon.PlainText(c.LastStatus.Content)))
+			fmt.Printf("🔗 %s\n", paint("link", c.LastStatus.URL))
+		}
+		fmt.Println()
+	}
+}
+
+func formatProfile(p AccountProfile) {
+	a := p.Account
+	fmt.Printf("%s (%s)\n", a.DisplayName, paint("username", "@"+a.Acct))
+	var badges []string
+	if a.Locked {
+		badges = append(badges, "🔒 locked")
+	}
+	if a.Bot {
+		badges = append(badges, "🤖 bot")
+	}
+	if a.Group {
+		badges = append(badges, "👥 group")
+	}
+	if len(badges) > 0 {
+		fmt.Println(strings.Join(badges, "  "))
+	}
+	fmt.Printf("🔗 %s\n", paint("link", a.URL))
+	if a.Moved != nil {
+		fmt.Printf("➡️  Moved to @%s\n", a.Moved.Acct)
+	}
+	if note := mastodon.PlainText(a.Note); note != "" {
+		fmt.Printf("\n%s\n", highlight(note))
+	}
+	if len(a.Fields) > 0 {
+		fmt.Println()
+		for _, f := range a.Fields {
+			verified := ""
+			if f.VerifiedAt != nil {
+				verified = " ✓"
+			}
+			fmt.Printf("%s: %s%s\n", f.Name, mastodon.PlainText(f.Value), verified)
+		}
+	}
+	fmt.Printf("\n📝 %d posts  👥 %d followers  ➡️  %d following\n", a.StatusesCount, a.FollowersCount, a.FollowingCount)
+	joined := a.CreatedAt
+	if t, err := time.Parse(time.RFC3339, a.CreatedAt); err == nil {
+		joined = t.Format("January 2, 2006")
+	}
+	fmt.Printf("📅 Joined %s\n", joined)
+	if a.LastStatusAt != nil {
+		fmt.Printf("🕒 Last posted %s\n", *a.LastStatusAt)
+	}
+	if len(p.Pinned) > 0 {
+		fmt.Printf("\n📌 Pinned posts\n\n")
+		formatStatuses(p.Pinned)
+	}
+}
+
+func formatInstance(info InstanceInfo) {
+	in := info.Instance
+	cfg := in.Configuration
+	fmt.Printf("🦣 %s (%s)\n", in.Title, in.Domain)
+	fmt.Printf("Version: %s\n", in.Version)
+	if in.Description != "" {
+		fmt.Printf("\n%s\n\n", mastodon.PlainText(in.Description))
+	}
+	fmt.Printf("👥 %d active users this month\n", in.Usage.Users.ActiveMonth)
+	fmt.Printf("📝 Registrations: %s\n", registrationStatus(in.Registrations))
+	fmt.Printf("✏️  Posts: %d characters, %d attachments (URLs count as %d)\n",
+		cfg.Statuses.MaxCharacters, cfg.Statuses.MaxMediaAttachments, cfg.Statuses.CharactersReservedPerURL)
+	fmt.Printf("📎 Uploads: images up to %s, videos up to %s\n",
+		formatBytes(cfg.MediaAttachments.ImageSizeLimit), formatBytes(cfg.MediaAttachments.VideoSizeLimit))
+	fmt.Printf("📊 Polls: up to %d options of %d characters\n", cfg.Polls.MaxOptions, cfg.Polls.MaxCharactersPerOption)
+	if len(in.Languages) > 0 {
+		fmt.Printf("🌐 Languages: %s\n", strings.Join(in.Languages, ", "))
+	}
+	if in.Contact.Email != "" {
+		fmt.Printf("✉️  Contact: %s\n", in.Contact.Email)
+	}
+	if in.Contact.Account != nil {
+		fmt.Printf("👤 Admin: @%s\n", in.Contact.Account.Acct)
+	}
+	if len(info.Activity) > 0 {
+		fmt.Println()
+		formatActivity(info.Activity[:min(4, len(info.Activity))])
+	}
+}
+
+func registrationStatus(r mastodon.InstanceRegistrations) string {
+	switch {
+	case !r.Enabled:
+		return "closed"
+	case r.ApprovalRequired:
+		return "open, approval required"
+	default:
+		return "open"
+	}
+}
+
+// comparisonRows are the rows of the compare-instances table, for
+// instances that answered.
+var comparisonRows = []struct {
+	name string
+	get  func(ComparedInstance) string
+}{
+	{"Title", func(c ComparedInstance) string { return truncate(c.Instance.Title, 30) }},
+	{"Version", func(c ComparedInstance) string { return c.Instance.Version }},
+	{"Users", func(c ComparedInstance) string {
+		if c.Stats == nil {
+			return "?"
+		}
+		return strconv.Itoa(c.Stats.UserCount)
+	}},
+	{"Active this month", func(c ComparedInstance) string { return strconv.Itoa(c.Instance.Usage.Users.ActiveMonth) }},
+	{"Registrations", func(c ComparedInstance) string { return registrationStatus(c.Instance.Registrations) }},
+	{"Post length", func(c ComparedInstance) string {
+		return strconv.Itoa(max(c.Instance.Configuration.Statuses.MaxCharacters, c.Instance.MaxTootChars))
+	}},
+	{"Attachments", func(c ComparedInstance) string {
+		return strconv.Itoa(c.Instance.Configuration.Statuses.MaxMediaAttachments)
+	}},
+	{"Images", func(c ComparedInstance) string {
+		return formatBytes(c.Instance.Configuration.MediaAttachments.ImageSizeLimit)
+	}},
+	{"Videos", func(c ComparedInstance) string {
+		return formatBytes(c.Instance.Configuration.MediaAttachments.VideoSizeLimit)
+	}},
+	{"Poll options", func(c ComparedInstance) string { return strconv.Itoa(c.Instance.Configuration.Polls.MaxOptions) }},
+	{"Translation", func(c ComparedInstance) string {
+		if c.Instance.Configuration.Translation.Enabled {
+			return "yes"
+		}
+		return "no"
+	}},
+	{"Languages", func(c ComparedInstance) string { return truncate(strings.Join(c.Instance.Languages, ", "), 30) }},
+	{"Rules", func(c ComparedInstance) string { return strconv.Itoa(len(c.Instance.Rules)) }},
+	{"Moderated servers", func(c ComparedInstance) string {
+		if !c.DomainBlocksPublished {
+			return "not published"
+		}
+		suspended := 0
+		for _, b := range c.DomainBlocks {
+			if b.Severity == "suspend" {
+				suspended++
+			}
+		}
+		return fmt.Sprintf("%d (%d suspended)", len(c.DomainBlocks), suspended)
+	}},
+	{"Contact", func(c ComparedInstance) string { return c.Instance.Contact.Email }},
+}
+
+// formatComparison prints the instances side by side, then each one's
+// rules, which are too long for the table.
+func formatComparison(compared []ComparedInstance) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	for _, c := range compared {
+		fmt.Fprintf(w, "\t%s", c.Domain)
+	}
+	fmt.Fprintln(w)
+	for _, row := range comparisonRows {
+		fmt.Fprint(w, row.name)
+		for _, c := range compared {
+			value := "–"
+			if c.Instance != nil {
+				value = row.get(c)
+			}
+			fmt.Fprintf(w, "\t%s", value)
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+	for _, c := range compared {
+		if c.Instance == nil {
+			fmt.Printf("\n%s couldn't be reached: %s\n", c.Domain, c.Error)
+			continue
+		}
+		fmt.Printf("\nRules of %s:\n", c.Domain)
+		formatRules(c.Instance.Rules)
+	}
+}
+
+func formatActivity(activity []mastodon.InstanceActivity) {
+	if len(activity) == 0 {
+		fmt.Println("No activity reported.")
+		return
+	}
+	fmt.Printf("%-12s %10s %8s %14s\n", "Week", "Posts", "Logins", "Registrations")
+	for _, a := range activity {
+		fmt.Printf("%-12s %10s %8s %14s\n", activityWeek(a.Week), a.Statuses, a.Logins, a.Registrations)
+	}
+}
+
+func formatRateLimit(rl mastodon.RateLimit) {
+	fmt.Printf("%d of %d requests left\n", rl.Remaining, rl.Limit)
+	fmt.Printf("Resets at %s (in %s)\n", rl.Reset.Local().Format("2006-01-02 15:04:05"), time.Until(rl.Reset).Round(time.Second))
+}
+
+func formatExport(r ExportResult) {
+	fmt.Printf("Exported to %s:\n", r.Dir)
+	for _, f := range r.Files {
+		incomplete := ""
+		if f.Incomplete {
+			incomplete = " (incomplete)"
+		}
+		fmt.Printf("  %-24s %d%s\n", f.Name, f.Items, incomplete)
+	}
+	if r.Interrupted {
+		fmt.Println("Interrupted; the export is incomplete")
+	}
+}
+
+func formatArchiveStats(s ArchiveStats) {
+	fmt.Printf("Archive: %s (%s)\n", s.Path, formatBytes(s.Size))
+	fmt.Printf("  Posts:         %d\n", s.Posts)
+	fmt.Printf("  Accounts:      %d\n", s.Accounts)
+	fmt.Printf("  Notifications: %d\n", s.Notifications)
+	if s.Oldest != "" {
+		fmt.Printf("Posts from %s to %s\n", formatTime(s.Oldest), formatTime(s.Newest))
+	}
+}
+
+func formatImport(r ImportResult) {
+	if r.DryRun {
+		fmt.Printf("Dry run: %d of %d entries resolved; nothing was changed\n", r.Applied, r.Total)
+	} else {
+		fmt.Printf("Imported %d of %d entries (%s)\n", r.Applied, r.Total, r.Type)
+	}
+	if r.Interrupted {
+		fmt.Println("Interrupted; the remaining entries were not tried")
+	}
+	for _, f := range r.Failed {
+		fmt.Printf("  line %d: %s: %s\n", f.Line, f.Item, f.Error)
+	}
+}
+
+func formatMarker(m TimelineMarker) {
+	fmt.Printf("%s: read up to %s (%s)\n", m.Timeline, m.LastReadID, formatTime(m.UpdatedAt))
+}
+
+func formatPreferences(p mastodon.Preferences) {
+	language := "detected from the text"
+	if p.PostingLanguage != nil && *p.PostingLanguage != "" {
+		language = *p.PostingLanguage
+	}
+	fmt.Printf("Default visibility: %s\n", p.PostingVisibility)
+	fmt.Printf("Default language: %s\n", language)
+	fmt.Printf("Mark media as sensitive: %s\n", yesNo(p.PostingSensitive))
+	fmt.Printf("Show media: %s\n", mediaDisplay[p.ReadingMedia])
+	fmt.Printf("Expand content warnings: %s\n", yesNo(p.ReadingSpoilers))
+}
+
+// mediaDisplay describes the reading:expand:media preference.
+var mediaDisplay = map[string]string{
+	"default":  "unless marked sensitive",
+	"show_all": "always",
+	"hide_all": "never",
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func formatRules(rules []mastodon.Rule) {
+	if len(rules) == 0 {
+		fmt.Println("No rules published.")
+		return
+	}
+	for i, r := range rules {
+		fmt.Printf("%d. %s\n", i+1, r.Text)
+		if r.Hint != "" {
+			fmt.Printf("   %s\n", r.Hint)
+		}
+	}
+}
+
+// formatSearch prints the result types that were searched for, each with its
+// own renderer.
+func formatSearch(r mastodon.SearchResult) {
+	switch *flagSearchType {
+	case mastodon.SearchStatuses:
+		formatStatuses(r.Statuses)
+	case mastodon.SearchAccounts:
+		formatAccountResults(r.Accounts)
+	case mastodon.SearchHashtags:
+		formatHashtags(r.Hashtags)
+	default:
+		if len(r.Accounts)+len(r.Hashtags)+len(r.Statuses) == 0 {
+			fmt.Println("No results found.")
+			return
+		}
+		if len(r.Accounts) > 0 {
+			fmt.Println(paint("header", "=== Accounts ==="))
+			formatAccountResults(r.Accounts)
+			fmt.Println()
+		}
+		if len(r.Hashtags) > 0 {
+			fmt.Println(paint("header", "=== Hashtags ==="))
+			formatHashtags(r.Hashtags)
+			fmt.Println()
+		}
+		if len(r.Statuses) > 0 {
+			fmt.Println(paint("header", "=== Posts ==="))
+			formatStatuses(r.Statuses)
+		}
+	}
+}
+
+// formatHashtags lists hashtags with their recent use, when the server
+// reports it.
+func formatHashtags(tags []mastodon.Tag) {
+	if len(tags) == 0 {
+		fmt.Println("No hashtags found.")
+		return
+	}
+	for _, t := range tags {
+		if len(t.History) == 0 {
+			fmt.Printf("%s  %s\n", paint("hashtag", "#"+t.Name), paint("link", t.URL))
+			continue
+		}
+		line, uses, people := sparkline(t.History)
+		fmt.Printf("%s %s  %d uses by %d people over %d days\n", paint("hashtag", fmt.Sprintf("#%-24s", t.Name)), line, uses, people, len(t.History))
+	}
+}
+
+func formatTrendingTags(tags []mastodon.Tag) {
+	if len(tags) == 0 {
+		fmt.Println("No trending tags.")
+		return
+	}
+	for _, t := range tags {
+		line, uses, people := sparkline(t.History)
+		fmt.Printf("#%-24s %s  %d uses by %d people over %d days\n", t.Name, line, uses, people, len(t.History))
+	}
+}
+
+func formatTrendingLinks(links []mastodon.TrendingLink) {
+	if len(links) == 0 {
+		fmt.Println("No trending links.")
+		return
+	}
+	for i, l := range links {
+		line, shares, people := sparkline(l.History)
+		fmt.Printf("--- Link %d ---\n", i+1)
+		fmt.Printf("%s\n", l.Title)
+		if l.ProviderName != "" {
+			fmt.Printf("📰 %s\n", l.ProviderName)
+		}
+		fmt.Printf("%s  %d shares by %d people\n", line, shares, people)
+		fmt.Printf("🔗 %s\n\n", l.URL)
+	}
+}
+
+// relationshipColumns are the flags shown by the relationship command.
+var relationshipColumns = []struct {
+	name string
+	get  func(mastodon.Relationship) bool
+}{
+	{"following", func(r mastodon.Relationship) bool { return r.Following }},
+	{"followed_by", func(r mastodon.Relationship) bool { return r.FollowedBy }},
+	{"requested", func(r mastodon.Relationship) bool { return r.Requested }},
+	{"blocking", func(r mastodon.Relationship) bool { return r.Blocking }},
+	{"blocked_by", func(r mastodon.Relationship) bool { return r.BlockedBy }},
+	{"muting", func(r mastodon.Relationship) bool { return r.Muting }},
+	{"notifying", func(r mastodon.Relationship) bool { return r.Notifying }},
+	{"showing_reblogs", func(r mastodon.Relationship) bool { return r.ShowingReblogs }},
+	{"endorsed", func(r mastodon.Relationship) bool { return r.Endorsed }},
+}
+
+func formatRelationships(rels []AccountRelationship) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprint(w, "account")
+	for _, c := range relationshipColumns {
+		fmt.Fprintf(w, "\t%s", c.name)
+	}
+	fmt.Fprintln(w)
+	for _, r := range rels {
+		fmt.Fprintf(w, "@%s", r.Account.Acct)
+		for _, c := range relationshipColumns {
+			mark := "·"
+			if c.get(r.Relationship) {
+				mark = "✓"
+			}
+			fmt.Fprintf(w, "\t%s", mark)
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+}
+
+func formatAccounts(accounts []mastodon.Account) {
+	if len(accounts) == 0 {
+		fmt.Println("No accounts found.")
+		return
+	}
+	for _, a := range accounts {
+		fmt.Printf("%s (%s) · %s followers\n", paint("username", "@"+a.Acct), a.DisplayName, paint("count", strconv.Itoa(a.FollowersCount)))
+	}
+}
+
+// bioSnippetLength caps the bio shown under account search results.
+const bioSnippetLength = 100
+
+// formatAccountResults lists accounts found by a search with a line of their
+// bio, to help pick the right one.
+func formatAccountResults(accounts []mastodon.Account) {
+	if len(accounts) == 0 {
+		fmt.Println("No accounts found.")
+		return
+	}
+	for _, a := range accounts {
+		fmt.Printf("%s (%s) · %s followers\n", paint("username", "@"+a.Acct), a.DisplayName, paint("count", strconv.Itoa(a.FollowersCount)))
+		bio := truncate(strings.Join(strings.Fields(a.NoteText()), " "), bioSnippetLength)
+		if bio != "" {
+			fmt.Printf("   %s\n", highlight(bio))
+		}
+	}
+}
+
+// notificationHeadline describes what happened in a notification, phrased
+// for its type.
+func notificationHeadline(n mastodon.Notification) string {
+	who := fmt.Sprintf("%s (%s)", paint("username", "@"+n.Account.Acct), n.Account.DisplayName)
+	switch n.Type {
+	case "mention":
+		return "💬 " + who + " mentioned you"
+	case "status":
+		return "📝 " + who + " posted"
+	case "reblog":
+		return "🔁 " + who + " boosted your post"
+	case "favourite":
+		return "⭐ " + who + " favourited your post"
+	case "follow":
+		return "👤 " + who + " followed you"
+	case "follow_request":
+		return "🙋 " + who + " requested to follow you"
+	case "poll":
+		return "📊 A poll you voted in or created has ended"
+	case "update":
+		return "✏️ " + who + " edited a post you interacted with"
+	case "admin.sign_up":
+		return "🆕 " + who + " signed up"
+	case "admin.report":
+		return "🚩 " + who + " filed a report"
+	}
+	return fmt.Sprintf("🔔 %s: %s", who, n.Type)
+}
+
+func formatNotification(i int, n mastodon.Notification) {
+	fmt.Println(paint("header", fmt.Sprintf("--- Notification %d ---", i)))
+	fmt.Println(notificationHeadline(n))
+	fmt.Println(paint("timestamp", formatTime(n.CreatedAt)))
+	if n.Status != nil {
+		fmt.Printf("\n%s\n", statusText(*n.Status))
+		fmt.Printf("🔗 %s\n", paint("link", n.Status.URL))
+	}
+	fmt.Println()
+}
+
+// formatTime renders an API timestamp as --timestamps asks: relative to now
+// ("3h ago"), in the local time zone, in UTC, or unchanged (iso). Values that
+// aren't RFC 3339 timestamps, such as bare dates, are returned as they are.
+func formatTime(value string) string {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return value
+	}
+	switch *flagTimestamps {
+	case "iso":
+		return value
+	case "utc":
+		return t.UTC().Format("2006-01-02 15:04 UTC")
+	case "local":
+		return t.Local().Format("2006-01-02 15:04 MST")
+	}
+	return relativeTime(t, time.Now())
+}
+
+// relativeTime describes t as a short distance from now, such as "5m ago" or
+// "in 2d". Anything more than a month away is shown as a date instead.
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+	var amount string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		amount = fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < 24*time.Hour:
+		amount = fmt.Sprintf("%dh", int(d/time.Hour))
+	case d < 30*24*time.Hour:
+		amount = fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	default:
+		if t.Year() == now.Year() {
+			return t.Local().Format("Jan 2")
+		}
+		return t.Local().Format("Jan 2, 2006")
+	}
+	if future {
+		return "in " + amount
+	}
+	return amount + " ago"
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// frontMatterFlags maps front matter keys to the flags they stand for.
+var frontMatterFlags = map[string]string{
+	"visibility": "visibility",
+	"cw":         "spoiler",
+	"spoiler":    "spoiler",
+	"language":   "language",
+	"schedule":   "schedule",
+	"thread":     "thread",
+}
+
+// frontMatterMedia is one entry of the media list.
+type frontMatterMedia struct {
+	file, alt, focus string
+}
+
+// frontMatter is the metadata block at the top of a --file post.
+type frontMatter struct {
+	values map[string]string
+	media  []frontMatterMedia
+}
+
+// readPostFile reads a --file post and applies its front matter to the
+// flags that weren't given on the command line, returning the post text.
+// Media paths are relative to the file.
+func readPostFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	meta, body, err := splitFrontMatter(string(data))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	for key, value := range meta.values {
+		name := frontMatterFlags[key]
+		if flagWasSet(name) {
+			continue
+		}
+		if err := flag.Lookup(name).Value.Set(value); err != nil {
+			return "", fmt.Errorf("%s: invalid %s %q: %w", path, key, value, err)
+		}
+	}
+	if len(meta.media) > 0 && !flagWasSet("media") {
+		files := make([]string, len(meta.media))
+		alts := make(stringList, len(meta.media))
+		focus := make(stringList, len(meta.media))
+		hasFocus := false
+		for i, m := range meta.media {
+			files[i] = m.file
+			if !filepath.IsAbs(m.file) {
+				files[i] = filepath.Join(filepath.Dir(path), m.file)
+			}
+			alts[i] = m.alt
+			// --focus goes by position, so entries without one get the center.
+			focus[i] = "0,0"
+			if m.focus != "" {
+				focus[i], hasFocus = m.focus, true
+			}
+		}
+		*flagMedia = strings.Join(files, ",")
+		if len(flagAlt) == 0 {
+			flagAlt = alts
+		}
+		if len(flagFocus) == 0 && hasFocus {
+			flagFocus = focus
+		}
+	}
+	body = strings.TrimSpace(body)
+	if body == "" && *flagMedia == "" {
+		return "", fmt.Errorf("%s has no text to post", path)
+	}
+	return body, nil
+}
+
+// splitFrontMatter separates a "---" delimited front matter block from the
+// text after it. Text without one is all body.
+func splitFrontMatter(data string) (frontMatter, string, error) {
+	meta := frontMatter{values: map[string]string{}}
+	data = strings.TrimPrefix(data, "\ufeff")
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	if strings.TrimSpace(lines[0]) != "---" {
+		return meta, data, nil
+	}
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "---" {
+			err := meta.parse(lines[1:i])
+			return meta, strings.Join(lines[i+1:], "\n"), err
+		}
+	}
+	return meta, "", errors.New("front matter has no closing ---")
+}
+
+// parse reads the subset of YAML front matter uses: "key: value" pairs
+// with plain or quoted scalars, # comments, and a media list whose items
+// are file names or mappings of file, alt, and focus.
+func (m *frontMatter) parse(lines []string) error {
+	inMedia := false
+	for n, raw := range lines {
+		line := strings.TrimRight(stripTOMLComment(raw), " \t")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lineErr := func(err error) error { return fmt.Errorf("front matter line %d: %w", n+2, err) }
+		indented := line[0] == ' ' || line[0] == '\t'
+		line = strings.TrimSpace(line)
+
+		if indented {
+			if !inMedia {
+				return lineErr(errors.New("unexpected indentation"))
+			}
+			if item, ok := strings.CutPrefix(line, "-"); ok {
+				m.media = append(m.media, frontMatterMedia{})
+				line = strings.TrimSpace(item)
+				if !strings.Contains(line, ": ") && !strings.HasSuffix(line, ":") {
+					file, err := yamlScalar(line)
+					if err != nil {
+						return lineErr(err)
+					}
+					m.media[len(m.media)-1].file = file
+					continue
+				}
+			}
+			if len(m.media) == 0 {
+				return lineErr(errors.New("media entries start with -"))
+			}
+			if err := m.media[len(m.media)-1].set(line); err != nil {
+				return lineErr(err)
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return lineErr(fmt.Errorf("expected key: value, got %q", line))
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		inMedia = key == "media"
+		if inMedia {
+			if value == "" {
+				continue
+			}
+			files, err := yamlFlowList(value)
+			if err != nil {
+				return lineErr(err)
+			}
+			for _, f := range files {
+				m.media = append(m.media, frontMatterMedia{file: f})
+			}
+			continue
+		}
+		if _, known := frontMatterFlags[key]; !known {
+			return lineErr(fmt.Errorf("unknown key %q (want visibility, cw, language, schedule, thread, or media)", key))
+		}
+		v, err := yamlScalar(value)
+		if err != nil {
+			return lineErr(err)
+		}
+		m.values[key] = v
+	}
+	for _, media := range m.media {
+		if media.file == "" {
+			return errors.New("front matter media entry without a file")
+		}
+	}
+	return nil
+}
+
+// set applies one "key: value" line of a media mapping.
+func (media *frontMatterMedia) set(line string) error {
+	key, raw, ok := strings.Cut(line, ":")
+	if !ok {
+		return fmt.Errorf("expected key: value, got %q", line)
+	}
+	value, err := yamlScalar(strings.TrimSpace(raw))
+	if err != nil {
+		return err
+	}
+	switch strings.TrimSpace(key) {
+	case "file":
+		media.file = value
+	case "alt":
+		media.alt = value
+	case "focus":
+		media.focus = value
+	default:
+		return fmt.Errorf("unknown media key %q (want file, alt, or focus)", key)
+	}
+	return nil
+}
+
+// yamlScalar unquotes a double- or single-quoted YAML string; plain
+// scalars are returned as they are.
+func yamlScalar(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		value, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return value, nil
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return strings.ReplaceAll(raw[1:len(raw)-1], "''", "'"), nil
+	}
+	return raw, nil
+}
+
+// yamlFlowList parses a one-line [a, b] list of scalars.
+func yamlFlowList(raw string) ([]string, error) {
+	if !strings.HasPrefix(raw, "[") || !strings.HasSuffix(raw, "]") {
+		return nil, fmt.Errorf("expected a list, got %q", raw)
+	}
+	var items []string
+	for _, item := range strings.Split(raw[1:len(raw)-1], ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		value, err := yamlScalar(item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, value)
+	}
+	return items, nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"time"
+)
+
+// HAR is an HTTP Archive (version 1.2), the format browser developer tools
+// export and import.
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog is the top level of an archive.
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator names the program that wrote an archive.
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry is one request and its response. Requests that failed without a
+// response have a zero status and the error in _error.
+type HAREntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+// HARRequest is the request half of an entry.
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARResponse is the response half of an entry.
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARNameValue is a header, cookie, or query parameter.
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData is a request body.
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// HARContent is a response body. Text is left out for binary bodies and
+// streams.
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+}
+
+// HARTimings splits an entry's time into phases. Only the wait for the
+// response is measured.
+type HARTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// harRecorder collects the requests of a run for --har. The archive is
+// rewritten after every request, so it is complete however the run ends.
+// Calls are serialized by the tracingTransport.
+type harRecorder struct {
+	path string
+	har  HAR
+}
+
+func newHARRecorder(path string) *harRecorder {
+	return &harRecorder{path: path, har: HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "mastodon-scout", Version: buildVersion()},
+		Entries: []HAREntry{},
+	}}}
+}
+
+// record adds a request to the archive, with credentials redacted, and
+// writes it out. A failure to write is a warning rather than failing the
+// command.
+func (r *harRecorder) record(start time.Time, req *http.Request, reqBody []byte, resp *http.Response, respBody []byte, err error, elapsed time.Duration) {
+	ms := float64(elapsed) / float64(time.Millisecond)
+	entry := HAREntry{
+		StartedDateTime: start.Format(time.RFC3339Nano),
+		Time:            ms,
+		Request: HARRequest{
+			Method:      req.Method,
+			URL:         redactURL(req.URL),
+			HTTPVersion: req.Proto,
+			Cookies:     []HARNameValue{},
+			Headers:     harHeaders(req.Header),
+			QueryString: harQuery(req.URL),
+			HeadersSize: -1,
+			BodySize:    int64(len(reqBody)),
+		},
+		Timings: HARTimings{Wait: ms},
+	}
+	if len(reqBody) > 0 {
+		contentType := req.Header.Get("Content-Type")
+		text := fmt.Sprintf("[%d bytes of %s]", len(reqBody), contentType)
+		if textBody(contentType) {
+			text = redactBody(string(reqBody))
+		}
+		entry.Request.PostData = &HARPostData{MimeType: contentType, Text: text}
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		entry.Response = HARResponse{Cookies: []HARNameValue{}, Headers: []HARNameValue{}, HeadersSize: -1, BodySize: -1}
+	} else {
+		size := max(resp.ContentLength, 0)
+		if respBody != nil {
+			size = int64(len(respBody))
+		}
+		entry.Response = HARResponse{
+			Status:      resp.StatusCode,
+			StatusText:  http.StatusText(resp.StatusCode),
+			HTTPVersion: resp.Proto,
+			Cookies:     []HARNameValue{},
+			Headers:     harHeaders(resp.Header),
+			Content:     HARContent{Size: size, MimeType: resp.Header.Get("Content-Type"), Text: redactBody(string(respBody))},
+			RedirectURL: resp.Header.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    size,
+		}
+	}
+	r.har.Log.Entries = append(r.har.Log.Entries, entry)
+
+	data, jerr := json.MarshalIndent(r.har, "", "  ")
+	if jerr == nil {
+		jerr = os.WriteFile(r.path, data, 0o600)
+	}
+	if jerr != nil {
+		notef("Warning: --har: %v\n", jerr)
+	}
+}
+
+// harHeaders lists h in name order, with credentials redacted.
+func harHeaders(h http.Header) []HARNameValue {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	headers := []HARNameValue{}
+	for _, name := range names {
+		for _, value := range h[name] {
+			if secretHeaders[name] {
+				value = redactHeader(name, value)
+			}
+			headers = append(headers, HARNameValue{Name: name, Value: value})
+		}
+	}
+	return headers
+}
+
+// harQuery lists the query parameters of u, with tokens redacted.
+func harQuery(u *url.URL) []HARNameValue {
+	query := []HARNameValue{}
+	redactedURL, err := url.Parse(redactURL(u))
+	if err != nil {
+		return query
+	}
+	q := redactedURL.Query()
+	names := make([]string, 0, len(q))
+	for name := range q {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range q[name] {
+			query = append(query, HARNameValue{Name: name, Value: value})
+		}
+	}
+	return query
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// forwarding reports whether new items are handed to --exec or
+// --webhook-url.
+func forwarding() bool {
+	return *flagExec != "" || *flagWebhookURL != ""
+}
+
+// forwardItems hands each post or notification in data to --exec and
+// --webhook-url.
+func forwardItems(data interface{}) {
+	if !forwarding() {
+		return
+	}
+	for _, item := range resultItems(data) {
+		forwardItem(item)
+	}
+}
+
+func forwardItem(item interface{}) {
+	switch item.(type) {
+	case mastodon.Status, mastodon.Notification:
+	default:
+		return
+	}
+	if *flagExec != "" {
+		runExecHook(item)
+	}
+	if *flagWebhookURL != "" {
+		if err := postWebhook(item); err != nil {
+			notef("Warning: --webhook-url: %v\n", err)
+		}
+	}
+}
+
+// runExecHook runs the --exec command through the shell for one post or
+// notification. The item's JSON arrives on stdin and its main fields in
+// MASTODON_* environment variables. The hook's output goes to stderr so it
+// can't corrupt machine-readable output, and a failing hook only warns.
+func runExecHook(item interface{}) {
+	env := []string{}
+	var post *mastodon.Status
+	switch v := item.(type) {
+	case mastodon.Status:
+		p, _ := resolvePost(v)
+		env = append(env, "MASTODON_TYPE=status", "MASTODON_ID="+v.ID, "MASTODON_ACCT="+p.Account.Acct)
+		post = &v
+	case mastodon.Notification:
+		env = append(env, "MASTODON_TYPE="+v.Type, "MASTODON_ID="+v.ID, "MASTODON_ACCT="+v.Account.Acct)
+		post = v.Status
+	}
+	if post != nil {
+		p, _ := resolvePost(*post)
+		env = append(env,
+			"MASTODON_STATUS_ID="+p.ID,
+			"MASTODON_URL="+p.URL,
+			"MASTODON_CW="+p.SpoilerText,
+			"MASTODON_TEXT="+p.ContentText(),
+		)
+	}
+	payload, err := json.Marshal(item)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := requestContext()
+	defer cancel()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", *flagExec)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", *flagExec)
+	}
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = bytes.NewReader(append(payload, '\n'))
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		notef("Warning: --exec failed: %v\n", err)
+	}
+}
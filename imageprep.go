@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"mime"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// jpegQuality is the quality resized JPEGs are saved at.
+const jpegQuality = 90
+
+// prepareImage applies --max-pixels and --strip-exif to a file about to be
+// uploaded. It returns the data to upload and the name to upload it under,
+// or nil data when the file should go as it is. Only JPEG, PNG, and GIF can
+// be processed; other media passes through, except that --strip-exif
+// refuses image formats it can't clean rather than leak their metadata.
+func prepareImage(name string) ([]byte, string, error) {
+	maxPixels, err := parseMaxPixels(*flagMaxPixels)
+	if err != nil {
+		return nil, "", err
+	}
+	if maxPixels == 0 && !*flagStripEXIF {
+		return nil, "", nil
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, "", err
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		if *flagStripEXIF && strings.HasPrefix(mime.TypeByExtension(strings.ToLower(filepath.Ext(name))), "image/") {
+			return nil, "", fmt.Errorf("can't remove metadata from %s: only JPEG, PNG, and GIF images are supported", name)
+		}
+		return nil, "", nil
+	}
+
+	if maxPixels == 0 || config.Width*config.Height <= maxPixels {
+		if !*flagStripEXIF {
+			return nil, "", nil
+		}
+		switch format {
+		case "jpeg":
+			data, err = stripJPEGMetadata(data)
+		case "png":
+			data, err = stripPNGMetadata(data)
+		default:
+			// GIFs don't carry EXIF.
+			return nil, "", nil
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("removing metadata from %s: %w", name, err)
+		}
+		return data, name, nil
+	}
+
+	data, name, err = resizeImage(data, name, format, maxPixels)
+	if err != nil {
+		return nil, "", fmt.Errorf("resizing %s: %w", name, err)
+	}
+	return data, name, nil
+}
+
+// parseMaxPixels reads --max-pixels, given as a pixel count or as
+// dimensions like 3840x2160. Zero means no limit.
+func parseMaxPixels(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if w, h, ok := strings.Cut(strings.ToLower(s), "x"); ok {
+		width, werr := strconv.Atoi(w)
+		height, herr := strconv.Atoi(h)
+		n, err = width*height, errors.Join(werr, herr)
+	}
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid --max-pixels %q (want a pixel count or dimensions like 3840x2160)", s)
+	}
+	return n, nil
+}
+
+// resizeImage scales an image down to at most maxPixels pixels, keeping its
+// aspect ratio. The result has no metadata, so a JPEG's EXIF orientation is
+// applied to the pixels first and only its color profile is carried over.
+// Animated GIFs can't be resized; still ones become PNGs.
+func resizeImage(data []byte, name, format string, maxPixels int) ([]byte, string, error) {
+	if format == "gif" {
+		g, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, "", err
+		}
+		if len(g.Image) > 1 {
+			return nil, "", errors.New("animated GIFs can't be resized")
+		}
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	rgba := toRGBA(img)
+	if format == "jpeg" {
+		rgba = orientImage(rgba, jpegOrientation(data))
+	}
+	rgba = downscale(rgba, maxPixels)
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		if err := jpeg.Encode(&buf, rgba, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, "", err
+		}
+		return withICCProfile(buf.Bytes(), data), name, nil
+	}
+	if err := png.Encode(&buf, rgba); err != nil {
+		return nil, "", err
+	}
+	if format == "gif" {
+		name = strings.TrimSuffix(name, filepath.Ext(name)) + ".png"
+	}
+	return buf.Bytes(), name, nil
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
+
+// orientImage turns img upright according to an EXIF orientation (1-8).
+func orientImage(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	out := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // flip horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotate 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // flip vertically
+				dx, dy = x, h-1-y
+			case 5: // transpose
+				dx, dy = y, x
+			case 6: // rotate 90° clockwise
+				dx, dy = h-1-y, x
+			case 7: // transverse
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotate 90° counterclockwise
+				dx, dy = y, w-1-x
+			}
+			copy(out.Pix[out.PixOffset(dx, dy):][:4], img.Pix[img.PixOffset(x, y):][:4])
+		}
+	}
+	return out
+}
+
+// downscale shrinks img to fit in maxPixels, averaging the source pixels
+// that make up each pixel of the result.
+func downscale(img *image.RGBA, maxPixels int) *image.RGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	if w*h <= maxPixels {
+		return img
+	}
+	scale := math.Sqrt(float64(maxPixels) / float64(w*h))
+	dw, dh := max(int(float64(w)*scale), 1), max(int(float64(h)*scale), 1)
+	out := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for dy := 0; dy < dh; dy++ {
+		y0, y1 := dy*h/dh, (dy+1)*h/dh
+		for dx := 0; dx < dw; dx++ {
+			x0, x1 := dx*w/dw, (dx+1)*w/dw
+			var sum [4]int
+			for y := y0; y < y1; y++ {
+				p := img.Pix[img.PixOffset(x0, y):img.PixOffset(x1, y)]
+				for i := 0; i < len(p); i += 4 {
+					sum[0] += int(p[i])
+					sum[1] += int(p[i+1])
+					sum[2] += int(p[i+2])
+					sum[3] += int(p[i+3])
+				}
+			}
+			n := (x1 - x0) * (y1 - y0)
+			d := out.Pix[out.PixOffset(dx, dy):][:4]
+			for i := range d {
+				d[i] = uint8((sum[i] + n/2) / n)
+			}
+		}
+	}
+	return out
+}
+
+// jpegSegment is one marker segment of a JPEG file, before the image data.
+type jpegSegment struct {
+	marker byte
+	// data is the whole segment, marker and length included.
+	data []byte
+}
+
+// jpegSegments splits a JPEG into its header segments and the image data
+// that follows them, up to and including the end-of-image marker. Anything
+// after that, such as the extra images phones append, is dropped.
+func jpegSegments(data []byte) ([]jpegSegment, []byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, nil, errors.New("not a JPEG file")
+	}
+	var segments []jpegSegment
+	i := 2
+	for {
+		if i+4 > len(data) || data[i] != 0xFF {
+			return nil, nil, errors.New("malformed JPEG file")
+		}
+		marker := data[i+1]
+		if marker == 0xFF {
+			// Fill byte before a marker.
+			i++
+			continue
+		}
+		if marker == 0xDA {
+			// Start of scan: from here on keep everything up to EOI.
+			end := jpegImageEnd(data, i)
+			return segments, data[i:end], nil
+		}
+		n := int(binary.BigEndian.Uint16(data[i+2:]))
+		if n < 2 || i+2+n > len(data) {
+			return nil, nil, errors.New("malformed JPEG file")
+		}
+		segments = append(segments, jpegSegment{marker, data[i : i+2+n]})
+		i += 2 + n
+	}
+}
+
+// jpegImageEnd finds the end of the end-of-image marker, scanning the
+// entropy-coded data from the first start of scan at i. Progressive JPEGs
+// have several scans, with tables between them, which are skipped whole.
+func jpegImageEnd(data []byte, i int) int {
+	for i+1 < len(data) {
+		if data[i] != 0xFF {
+			i++
+			continue
+		}
+		switch m := data[i+1]; {
+		case m == 0xD9:
+			return i + 2
+		case m == 0x00, m == 0xFF, m >= 0xD0 && m <= 0xD7:
+			// Stuffed byte, fill, or restart marker: still image data.
+			i++
+		default:
+			if i+4 > len(data) {
+				return len(data)
+			}
+			// Skip the marker segment and carry on with the scan after it.
+			i += 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+		}
+	}
+	return len(data)
+}
+
+// isICCProfile reports whether a JPEG segment holds a color profile.
+func isICCProfile(s jpegSegment) bool {
+	return s.marker == 0xE2 && bytes.HasPrefix(s.data[4:], []byte("ICC_PROFILE\x00"))
+}
+
+// stripJPEGMetadata removes EXIF, XMP, IPTC, comments, and appended images
+// from a JPEG without re-encoding it. The orientation is the one EXIF field
+// kept, in a minimal EXIF block, so the photo still displays upright.
+func stripJPEGMetadata(data []byte) ([]byte, error) {
+	segments, scan, err := jpegSegments(data)
+	if err != nil {
+		return nil, err
+	}
+	orientation := jpegOrientation(data)
+	out := []byte{0xFF, 0xD8}
+	for _, s := range segments {
+		switch {
+		case s.marker == 0xE1:
+			if orientation > 1 && isEXIF(s) {
+				out = append(out, minimalEXIF(orientation)...)
+				orientation = 0
+			}
+			continue
+		case s.marker == 0xE2 && !isICCProfile(s), s.marker >= 0xE3 && s.marker <= 0xED, s.marker == 0xFE:
+			// APP14 (Adobe) and APP15 stay: decoders need APP14's color
+			// transform.
+			continue
+		}
+		out = append(out, s.data...)
+	}
+	return append(out, scan...), nil
+}
+
+// withICCProfile copies the color profile of the JPEG orig, if it has one,
+// into the freshly encoded JPEG encoded.
+func withICCProfile(encoded, orig []byte) []byte {
+	segments, _, err := jpegSegments(orig)
+	if err != nil {
+		return encoded
+	}
+	out := []byte{0xFF, 0xD8}
+	for _, s := range segments {
+		if isICCProfile(s) {
+			out = append(out, s.data...)
+		}
+	}
+	return append(out, encoded[2:]...)
+}
+
+func isEXIF(s jpegSegment) bool {
+	return bytes.HasPrefix(s.data[4:], []byte("Exif\x00\x00"))
+}
+
+// jpegOrientation reads the EXIF orientation of a JPEG: 1 (upright) when
+// there is none.
+func jpegOrientation(data []byte) int {
+	segments, _, err := jpegSegments(data)
+	if err != nil {
+		return 1
+	}
+	for _, s := range segments {
+		if s.marker != 0xE1 || !isEXIF(s) {
+			continue
+		}
+		tiff := s.data[10:]
+		if len(tiff) < 8 {
+			return 1
+		}
+		var order binary.ByteOrder = binary.BigEndian
+		if string(tiff[:2]) == "II" {
+			order = binary.LittleEndian
+		}
+		ifd := int(order.Uint32(tiff[4:]))
+		if ifd+2 > len(tiff) {
+			return 1
+		}
+		count := int(order.Uint16(tiff[ifd:]))
+		for e := ifd + 2; e+12 <= len(tiff) && count > 0; e, count = e+12, count-1 {
+			if order.Uint16(tiff[e:]) == 0x0112 {
+				return int(order.Uint16(tiff[e+8:]))
+			}
+		}
+		return 1
+	}
+	return 1
+}
+
+// minimalEXIF builds an APP1 segment whose only field is the orientation.
+func minimalEXIF(orientation int) []byte {
+	seg := []byte{0xFF, 0xE1, 0, 0}
+	seg = append(seg, "Exif\x00\x00"...)
+	seg = append(seg, "MM\x00\x2a\x00\x00\x00\x08"...) // big-endian TIFF, IFD at 8
+	seg = append(seg, 0, 1)                            // one entry
+	seg = append(seg, 0x01, 0x12, 0, 3, 0, 0, 0, 1)    // orientation, SHORT, count 1
+	seg = append(seg, 0, byte(orientation), 0, 0)
+	seg = append(seg, 0, 0, 0, 0) // no next IFD
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(seg)-2))
+	return seg
+}
+
+// pngMetadataChunks are the PNG chunks that hold text, EXIF, and timestamps.
+var pngMetadataChunks = map[string]bool{
+	"tEXt": true, "zTXt": true, "iTXt": true, "eXIf": true, "tIME": true,
+}
+
+// stripPNGMetadata removes text, EXIF, and timestamp chunks from a PNG
+// without re-encoding it.
+func stripPNGMetadata(data []byte) ([]byte, error) {
+	const signature = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(data, []byte(signature)) {
+		return nil, errors.New("not a PNG file")
+	}
+	out := []byte(signature)
+	for i := len(signature); i < len(data); {
+		if i+8 > len(data) {
+			return nil, errors.New("malformed PNG file")
+		}
+		n := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + n // length, type, data, CRC
+		if n < 0 || end > len(data) {
+			return nil, errors.New("malformed PNG file")
+		}
+		if !pngMetadataChunks[string(data[i+4:i+8])] {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out, nil
+}
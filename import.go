@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// importFiles maps each kind of import to the file Mastodon's export (and
+// the export command) writes it to, so --type can be left out.
+var importFiles = map[string]string{
+	"following":     "following_accounts.csv",
+	"mutes":         "muted_accounts.csv",
+	"blocks":        "blocked_accounts.csv",
+	"domain-blocks": "blocked_domains.csv",
+	"bookmarks":     "bookmarks.csv",
+	"lists":         "lists.csv",
+}
+
+// importVerbs describe what an import does to each entry.
+var importVerbs = map[string]string{
+	"following":     "follow",
+	"mutes":         "mute",
+	"blocks":        "block",
+	"domain-blocks": "block domain",
+	"bookmarks":     "bookmark",
+	"lists":         "add",
+}
+
+// ImportResult summarizes an import.
+type ImportResult struct {
+	Type    string          `json:"type"`
+	DryRun  bool            `json:"dry_run"`
+	Total   int             `json:"total"`
+	Applied int             `json:"applied"`
+	Failed  []ImportFailure `json:"failed"`
+	// Interrupted is set when a signal stopped the import before the end
+	// of the file; the entries after the last one applied were not tried.
+	Interrupted bool `json:"interrupted,omitempty"`
+}
+
+// ImportFailure is an entry that couldn't be imported.
+type ImportFailure struct {
+	Line  int    `json:"line"`
+	Item  string `json:"item"`
+	Error string `json:"error"`
+}
+
+// importRow is one entry of an import file.
+type importRow struct {
+	line int
+	// item is an account address, a domain, or a post's URI.
+	item   string
+	list   string
+	follow mastodon.FollowOptions
+	mute   mastodon.MuteOptions
+}
+
+// importer applies the rows of an import file.
+type importer struct {
+	client *mastodon.Client
+	kind   string
+	dryRun bool
+	// lists and members cache the user's lists by title and their members,
+	// so a list is created once and existing members are skipped.
+	lists   map[string]*mastodon.List
+	members map[string]map[string]bool
+}
+
+// runImport applies a CSV file in the format of Mastodon's export: follows,
+// mutes, blocks, domain blocks, bookmarks, or list members. Each entry is
+// resolved (remote accounts through WebFinger) and applied in turn, with
+// progress on stderr; entries that fail are reported and skipped. With
+// --dry-run entries are resolved but nothing changes. An interrupt stops it
+// between entries, with the summary so far.
+func runImport(ctx context.Context, client *mastodon.Client, path string) (interface{}, error) {
+	kind, err := importKind(path)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := readImportFile(path, kind)
+	if err != nil {
+		return nil, err
+	}
+	im := &importer{client: client, kind: kind, dryRun: *flagDryRun}
+	result := ImportResult{Type: kind, DryRun: im.dryRun, Total: len(rows), Failed: []ImportFailure{}}
+	verb := importVerbs[kind]
+	if im.dryRun {
+		verb = "would " + verb
+	}
+	for i, row := range rows {
+		what := row.item
+		if kind == "lists" {
+			what = fmt.Sprintf("%s to %q", row.item, row.list)
+		}
+		if err := im.apply(ctx, row); err != nil {
+			if ctx.Err() != nil {
+				result.Interrupted = true
+				return result, err
+			}
+			notef("[%d/%d] %s: %v\n", i+1, len(rows), row.item, err)
+			result.Failed = append(result.Failed, ImportFailure{Line: row.line, Item: row.item, Error: err.Error()})
+			continue
+		}
+		notef("[%d/%d] %s %s\n", i+1, len(rows), verb, what)
+		result.Applied++
+	}
+	return result, nil
+}
+
+// importKind is --type, or else the kind whose export file name path has.
+func importKind(path string) (string, error) {
+	if flagWasSet("type") {
+		if _, ok := importFiles[*flagSearchType]; !ok {
+			return "", fmt.Errorf("invalid --type %q for import (want following, mutes, blocks, domain-blocks, bookmarks, or lists)", *flagSearchType)
+		}
+		return *flagSearchType, nil
+	}
+	base := filepath.Base(path)
+	for kind, name := range importFiles {
+		if base == name {
+			return kind, nil
+		}
+	}
+	return "", fmt.Errorf("can't tell what %s holds; pass --type following, mutes, blocks, domain-blocks, bookmarks, or lists", base)
+}
+
+// readImportFile parses an import file. Account files may start with
+// Mastodon's "Account address" header; the other columns are optional, so
+// plain lists of addresses work too.
+func readImportFile(path, kind string) ([]importRow, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	r := csv.NewReader(file)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	var rows []importRow
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		line, _ := r.FieldPos(0)
+		if len(rows) == 0 && strings.EqualFold(strings.TrimSpace(rec[0]), "Account address") {
+			continue
+		}
+		row, err := parseImportRow(kind, rec)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if row.item == "" {
+			continue
+		}
+		row.line = line
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func parseImportRow(kind string, rec []string) (importRow, error) {
+	for i := range rec {
+		rec[i] = strings.TrimSpace(rec[i])
+	}
+	col := func(i int) string {
+		if i < len(rec) {
+			return rec[i]
+		}
+		return ""
+	}
+	boolCol := func(i int, def bool) (bool, error) {
+		if col(i) == "" {
+			return def, nil
+		}
+		return strconv.ParseBool(col(i))
+	}
+
+	row := importRow{item: strings.TrimPrefix(col(0), "@")}
+	var err error
+	switch kind {
+	case "following":
+		var showBoosts bool
+		if showBoosts, err = boolCol(1, true); err != nil {
+			return row, fmt.Errorf("invalid Show boosts %q", col(1))
+		}
+		row.follow.HideReblogs = !showBoosts
+		if row.follow.Notify, err = boolCol(2, false); err != nil {
+			return row, fmt.Errorf("invalid Notify on new posts %q", col(2))
+		}
+		for _, lang := range strings.Split(col(3), ",") {
+			if lang = strings.TrimSpace(lang); lang != "" {
+				row.follow.Languages = append(row.follow.Languages, lang)
+			}
+		}
+	case "mutes":
+		var hide bool
+		if hide, err = boolCol(1, true); err != nil {
+			return row, fmt.Errorf("invalid Hide notifications %q", col(1))
+		}
+		row.mute.KeepNotifications = !hide
+	case "lists":
+		row.list, row.item = col(0), strings.TrimPrefix(col(1), "@")
+		if row.list != "" && row.item == "" {
+			return row, errors.New("missing account address")
+		}
+	}
+	return row, nil
+}
+
+// apply imports one row.
+func (im *importer) apply(ctx context.Context, row importRow) error {
+	switch im.kind {
+	case "domain-blocks":
+		domain, err := mastodon.NormalizeDomain(row.item)
+		if err != nil || im.dryRun {
+			return err
+		}
+		return im.client.BlockDomain(ctx, domain)
+	case "bookmarks":
+		id, err := im.client.ResolveStatusID(ctx, row.item)
+		if err != nil || im.dryRun {
+			return err
+		}
+		_, err = im.client.Bookmark(ctx, id)
+		return err
+	}
+
+	account, err := im.client.ResolveAccount(ctx, row.item)
+	if err != nil || im.dryRun {
+		return err
+	}
+	switch im.kind {
+	case "following":
+		_, err = im.client.FollowWithOptions(ctx, account.ID, row.follow)
+	case "mutes":
+		_, err = im.client.Mute(ctx, account.ID, row.mute)
+	case "blocks":
+		_, err = im.client.Block(ctx, account.ID)
+	case "lists":
+		err = im.addToList(ctx, row.list, account.ID)
+	}
+	return err
+}
+
+// addToList adds an account to the list titled title, creating the list if
+// the user has none by that title. Accounts already on the list are left
+// alone, so an import can be repeated.
+func (im *importer) addToList(ctx context.Context, title, accountID string) error {
+	if im.lists == nil {
+		lists, err := im.client.Lists(ctx)
+		if err != nil {
+			return err
+		}
+		im.lists = map[string]*mastodon.List{}
+		im.members = map[string]map[string]bool{}
+		for i := range lists {
+			im.lists[lists[i].Title] = &lists[i]
+		}
+	}
+	list, ok := im.lists[title]
+	if !ok {
+		created, err := im.client.CreateList(ctx, title)
+		if err != nil {
+			return fmt.Errorf("creating list %q: %w", title, err)
+		}
+		list = created
+		im.lists[title] = list
+		im.members[list.ID] = map[string]bool{}
+	}
+	members, ok := im.members[list.ID]
+	if !ok {
+		accounts, err := im.client.ListAccounts(ctx, list.ID, mastodon.PageOptions{All: true})
+		if err != nil {
+			return err
+		}
+		members = map[string]bool{}
+		for _, a := range accounts {
+			members[a.ID] = true
+		}
+		im.members[list.ID] = members
+	}
+	if members[accountID] {
+		return nil
+	}
+	if err := im.client.AddToList(ctx, list.ID, []string{accountID}); err != nil {
+		return err
+	}
+	members[accountID] = true
+	return nil
+}
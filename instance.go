@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// InstanceInfo is the instance command's result: server details plus recent
+// weekly activity when the server publishes it.
+type InstanceInfo struct {
+	Instance mastodon.Instance           `json:"instance"`
+	Activity []mastodon.InstanceActivity `json:"activity,omitempty"`
+}
+
+// runInstance handles "instance" and its peers, activity, and rules
+// subcommands.
+func runInstance(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+	if len(args) == 0 {
+		instance, err := client.Instance(ctx)
+		if err != nil {
+			return nil, err
+		}
+		info := InstanceInfo{Instance: *instance}
+		// Activity is optional; some servers turn it off.
+		if activity, err := client.InstanceActivity(ctx); err == nil {
+			info.Activity = activity
+		}
+		return info, nil
+	}
+	switch args[0] {
+	case "peers":
+		return client.InstancePeers(ctx)
+	case "activity":
+		return client.InstanceActivity(ctx)
+	case "rules":
+		return client.InstanceRules(ctx)
+	default:
+		return nil, fmt.Errorf("unknown instance subcommand: %s", args[0])
+	}
+}
+
+// formatBytes renders a byte count in MB, which is how Mastodon documents
+// its upload limits.
+func formatBytes(n int64) string {
+	return strconv.FormatFloat(float64(n)/(1<<20), 'f', -1, 64) + " MB"
+}
+
+// activityWeek renders an activity week (a Unix timestamp string) as a date.
+func activityWeek(week string) string {
+	sec, err := strconv.ParseInt(week, 10, 64)
+	if err != nil {
+		return week
+	}
+	return time.Unix(sec, 0).UTC().Format("2006-01-02")
+}
+
+// normalizeInstanceURL accepts an instance as a bare host name or a URL and
+// returns it as scheme://host. Plain http:// is refused unless
+// --allow-insecure is given, since the token would cross the network in
+// the clear.
+func normalizeInstanceURL(raw string) (string, error) {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return "", errors.New("the instance URL is empty")
+	}
+	if !strings.Contains(s, "://") {
+		s = "https://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid instance URL %q", raw)
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return "", fmt.Errorf("invalid instance URL %q: use https://", raw)
+	}
+	if strings.Trim(u.Path, "/") != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return "", fmt.Errorf("instance URL %q should be just the server, like https://%s", raw, u.Host)
+	}
+	if u.Scheme == "http" && !*flagAllowInsecure {
+		return "", fmt.Errorf("refusing to talk to %s over plain http://, which would expose your token; use https:// or pass --allow-insecure", u.Host)
+	}
+	return u.Scheme + "://" + strings.ToLower(u.Host), nil
+}
+
+// instanceError is a probe's finding that the instance URL doesn't lead
+// to a Mastodon server.
+type instanceError struct {
+	msg string
+}
+
+func (e *instanceError) Error() string { return e.msg }
+
+// probeInstance checks that the instance URL leads to the Mastodon API,
+// turning the ways a mistyped address fails into advice. It makes one
+// attempt, without the response cache.
+func probeInstance(ctx context.Context) error {
+	client := mastodon.NewClient(*flagInstanceURL, "", mastodon.WithHTTPClient(httpClient))
+	host := instanceHost(*flagInstanceURL)
+	resp, err := client.Do(ctx, http.MethodGet, "/api/v2/instance", nil)
+	var apiErr *mastodon.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		// Servers older than Mastodon 4.0 only describe themselves in v1.
+		resp, err = client.Do(ctx, http.MethodGet, "/api/v1/instance", nil)
+	}
+	var dnsErr *net.DNSError
+	switch {
+	case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+		return &instanceError{fmt.Sprintf("there is no server named %s; check the spelling of --instance", host)}
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+		return &instanceError{fmt.Sprintf("%s doesn't serve the Mastodon API (no /api/v2/instance); check --instance", host)}
+	case err != nil:
+		return fmt.Errorf("probing %s: %w", *flagInstanceURL, err)
+	}
+	var info struct {
+		Version string `json:"version"`
+	}
+	if json.Unmarshal(resp.Body, &info) != nil || info.Version == "" {
+		return &instanceError{fmt.Sprintf("%s answered, but not as a Mastodon server (its /api/v2/instance isn't an instance description); check --instance", host)}
+	}
+	return nil
+}
+
+// explainInstanceError probes the instance after a failure that a wrong
+// --instance would cause: a host that doesn't resolve or refuses
+// connections, a 404, or a response that isn't JSON. When the probe finds
+// no Mastodon server there, its advice replaces err.
+func explainInstanceError(err error) error {
+	var apiErr *mastodon.APIError
+	var opErr *net.OpError
+	var syntaxErr *json.SyntaxError
+	suspect := errors.As(err, &opErr) || errors.As(err, &syntaxErr) ||
+		(errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound)
+	if !suspect {
+		return err
+	}
+	ctx, cancel := requestContext()
+	defer cancel()
+	var instErr *instanceError
+	if perr := probeInstance(ctx); errors.As(perr, &instErr) {
+		return perr
+	}
+	return err
+}
@@ -0,0 +1,257 @@
+// Package mastotest is a fake Mastodon server that answers from recorded
+// fixtures, so commands and formatters can be exercised offline, without an
+// instance or a token.
+//
+// Fixtures are HTTP Archives as written by mastodon-scout --har. Record them
+// with --no-cache, so that the archive holds full responses rather than
+// 304s:
+//
+//	mastodon-scout --no-cache --har testdata/home.har home
+//
+// A Replayer serves them either as an http.Handler, for httptest.NewServer,
+// or as an http.RoundTripper that stands in for the network in an
+// http.Client, which is how mastodon-scout --replay uses it.
+//
+// Requests are matched on method, path, and query, then on method and path
+// alone. A request for a later page (max_id, min_id, since_id, or offset)
+// is only matched exactly; past the end of a recorded listing it gets an
+// empty page, so --all stops where the recording did.
+package mastotest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Response is a recorded response.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Replayer answers requests with the responses recorded for them. Requests
+// are matched on method, path, and query string, ignoring the host and any
+// access_token parameter; failing that, on method and path alone. When a
+// request was recorded several times, as a polling loop does, the
+// responses are replayed in order and the last one repeats.
+type Replayer struct {
+	mu        sync.Mutex
+	responses map[string][]Response
+	served    map[string]int
+}
+
+// New returns a Replayer with no fixtures.
+func New() *Replayer {
+	return &Replayer{responses: map[string][]Response{}, served: map[string]int{}}
+}
+
+// Load returns a Replayer serving every .har file in dir.
+func Load(dir string) (*Replayer, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.har"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .har fixtures in %s", dir)
+	}
+	sort.Strings(files)
+	r := New()
+	for _, file := range files {
+		if err := r.LoadHAR(file); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// harFile is the part of an HTTP Archive a Replayer reads.
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				Method string `json:"method"`
+				URL    string `json:"url"`
+			} `json:"request"`
+			Response struct {
+				Status  int `json:"status"`
+				Headers []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"headers"`
+				Content struct {
+					Text     string `json:"text"`
+					Encoding string `json:"encoding"`
+				} `json:"content"`
+			} `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// LoadHAR adds the responses recorded in an HTTP Archive. Entries without
+// a response, and 304s that only make sense next to a cache, are skipped.
+func (r *Replayer) LoadHAR(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, entry := range har.Log.Entries {
+		resp := entry.Response
+		if resp.Status == 0 || resp.Status == http.StatusNotModified {
+			continue
+		}
+		if resp.Content.Encoding != "" {
+			return fmt.Errorf("%s: %s %s: %s encoded bodies are not supported", path, entry.Request.Method, entry.Request.URL, resp.Content.Encoding)
+		}
+		header := http.Header{}
+		for _, h := range resp.Headers {
+			header.Add(h.Name, h.Value)
+		}
+		if err := r.Add(entry.Request.Method, entry.Request.URL, Response{Status: resp.Status, Header: header, Body: []byte(resp.Content.Text)}); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// Add records resp as the answer to a method request for rawURL, which may
+// be a full URL or just a path and query string.
+func (r *Replayer) Add(method, rawURL string, resp Response) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if resp.Header == nil {
+		resp.Header = http.Header{}
+	}
+	// The body may have changed size since it was recorded.
+	for _, name := range []string{"Content-Length", "Content-Encoding", "Transfer-Encoding"} {
+		resp.Header.Del(name)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range requestKeys(method, u) {
+		r.responses[key] = append(r.responses[key], resp)
+	}
+	return nil
+}
+
+// cursorParams are the query parameters that ask for a page further along a
+// listing than the first.
+var cursorParams = []string{"max_id", "min_id", "since_id", "offset"}
+
+// requestKeys returns the exact key for a request and the looser one
+// without its query string.
+func requestKeys(method string, u *url.URL) []string {
+	query := u.Query()
+	query.Del("access_token")
+	path := strings.ToUpper(method) + " " + u.EscapedPath()
+	return []string{path + "?" + query.Encode(), path}
+}
+
+// hasCursor reports whether u asks for a page past the first.
+func hasCursor(u *url.URL) bool {
+	query := u.Query()
+	for _, name := range cursorParams {
+		if query.Has(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup finds the response for a request. A request for a later page that
+// wasn't recorded gets an empty page rather than the first one again, so
+// that following a listing ends where the recording did.
+func (r *Replayer) lookup(method string, u *url.URL) (Response, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys := requestKeys(method, u)
+	if hasCursor(u) {
+		// Only a listing that was recorded has pages past the end.
+		if len(r.responses[keys[0]]) == 0 && len(r.responses[keys[1]]) > 0 {
+			return emptyPage(), true
+		}
+		keys = keys[:1]
+	}
+	for _, key := range keys {
+		responses := r.responses[key]
+		if len(responses) == 0 {
+			continue
+		}
+		n := r.served[key]
+		r.served[key]++
+		return responses[min(n, len(responses)-1)], true
+	}
+	return Response{}, false
+}
+
+// emptyPage is the answer to a request past the end of a recorded listing.
+func emptyPage() Response {
+	return Response{
+		Status: http.StatusOK,
+		Header: http.Header{"Content-Type": {"application/json; charset=utf-8"}},
+		Body:   []byte("[]"),
+	}
+}
+
+// notFound is the answer to a request nothing was recorded for, in the
+// shape of a Mastodon error.
+func notFound(method string, u *url.URL) Response {
+	body, _ := json.Marshal(map[string]string{"error": fmt.Sprintf("no recorded response for %s %s", method, u.RequestURI())})
+	return Response{
+		Status: http.StatusNotFound,
+		Header: http.Header{"Content-Type": {"application/json; charset=utf-8"}},
+		Body:   body,
+	}
+}
+
+// ServeHTTP answers req with its recorded response, or a 404.
+func (r *Replayer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	resp, ok := r.lookup(req.Method, req.URL)
+	if !ok {
+		resp = notFound(req.Method, req.URL)
+	}
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(resp.Status)
+	w.Write(resp.Body)
+}
+
+// RoundTrip answers req with its recorded response, or a 404, without
+// touching the network.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		io.Copy(io.Discard, req.Body)
+		req.Body.Close()
+	}
+	resp, ok := r.lookup(req.Method, req.URL)
+	if !ok {
+		resp = notFound(req.Method, req.URL)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", resp.Status, http.StatusText(resp.Status)),
+		StatusCode:    resp.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        resp.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(resp.Body)),
+		ContentLength: int64(len(resp.Body)),
+		Request:       req,
+	}, nil
+}
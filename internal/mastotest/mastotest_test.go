@@ -0,0 +1,91 @@
+package mastotest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReplayerMatching(t *testing.T) {
+	r, err := Load("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Add("GET", "/api/v1/polls/1", Response{Status: 200, Body: []byte(`{"id":"1","votes_count":1}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Add("GET", "/api/v1/polls/1", Response{Status: 200, Body: []byte(`{"id":"1","votes_count":2}`)}); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"exact query", "GET", "/api/v1/timelines/home?limit=20", 200, `"id"`},
+		{"other query falls back to the path", "GET", "/api/v1/timelines/home?limit=5", 200, `"id"`},
+		{"recorded later page", "GET", "/api/v1/timelines/home?limit=40&max_id=960", 200, `"959"`},
+		{"unrecorded later page is empty", "GET", "/api/v1/timelines/home?limit=20&max_id=980", 200, `[]`},
+		{"offset past the recording is empty", "GET", "/api/v1/trends/tags?limit=20&offset=20", 200, `[]`},
+		{"later page of an unrecorded listing", "GET", "/api/v1/bookmarks?max_id=1", 404, "no recorded response"},
+		{"access token is ignored", "GET", "/api/v1/statuses/996?access_token=secret", 200, `"996"`},
+		{"unrecorded path", "GET", "/api/v1/statuses/1", 404, "no recorded response for GET /api/v1/statuses/1"},
+		{"unrecorded method", "POST", "/api/v1/statuses/996", 404, "no recorded response"},
+		{"first of repeated responses", "GET", "/api/v1/polls/1", 200, `"votes_count":1`},
+		{"second of repeated responses", "GET", "/api/v1/polls/1", 200, `"votes_count":2`},
+		{"the last response repeats", "GET", "/api/v1/polls/1", 200, `"votes_count":2`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("body = %.200s, want it to contain %s", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestReplayerRoundTrip(t *testing.T) {
+	r, err := Load("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: r}
+	resp, err := client.Get("https://anywhere.example/api/v2/instance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || !strings.Contains(string(body), `"domain"`) {
+		t.Errorf("got %d %.200s, want the recorded instance", resp.StatusCode, body)
+	}
+	if got := resp.ContentLength; got != int64(len(body)) {
+		t.Errorf("ContentLength = %d, want %d", got, len(body))
+	}
+}
+
+func TestLoadWithoutFixtures(t *testing.T) {
+	if _, err := Load(t.TempDir()); err == nil {
+		t.Error("Load of an empty directory succeeded")
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import "errors"
+
+// keyringService is the service name tokens are filed under in the OS
+// credential store.
+const keyringService = appName
+
+// errKeyringUnavailable means no OS credential store could be used; callers
+// fall back to the credentials file.
+var errKeyringUnavailable = errors.New("OS keyring unavailable")
+
+// errKeyringNotFound means the credential store has no entry for the key.
+var errKeyringNotFound = errors.New("token not found in OS keyring")
+
+// keyring is a minimal interface over the platform credential stores:
+// macOS Keychain, libsecret (Secret Service) on Linux/BSD, and the Windows
+// Credential Manager.
+type keyring interface {
+	Get(key string) (string, error)
+	Set(key, secret string) error
+	Delete(key string) error
+}
+
+// osKeyring returns the platform credential store, or nil when the user
+// opted out with --no-keyring.
+func osKeyring() keyring {
+	if *flagNoKeyring {
+		return nil
+	}
+	return systemKeyring()
+}
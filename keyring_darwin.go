@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// macKeychain stores tokens as generic passwords via the security(1) tool.
+// Writes go through `security -i` on stdin so the token never appears in the
+// process list.
+type macKeychain struct{}
+
+func systemKeyring() keyring {
+	if _, err := exec.LookPath("security"); err != nil {
+		return nil
+	}
+	return macKeychain{}
+}
+
+func (macKeychain) Get(key string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", keyringService, "-a", key, "-w").Output()
+	if err != nil {
+		return "", errKeyringNotFound
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (macKeychain) Set(key, secret string) error {
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+		strconv.Quote(keyringService), strconv.Quote(key), strconv.Quote(secret)))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", errKeyringUnavailable, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (macKeychain) Delete(key string) error {
+	if err := exec.Command("security", "delete-generic-password", "-s", keyringService, "-a", key).Run(); err != nil {
+		return errKeyringNotFound
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build !darwin && !windows && !linux && !freebsd && !openbsd && !netbsd
+
+package main
+
+// systemKeyring reports no credential store on platforms without one; tokens
+// are kept in the credentials file instead.
+func systemKeyring() keyring {
+	return nil
+}
@@ -0,0 +1,50 @@
+//go:build linux || freebsd || openbsd || netbsd
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// secretService stores tokens through libsecret's secret-tool, which reads
+// the secret from stdin.
+type secretService struct{}
+
+func systemKeyring() keyring {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return nil
+	}
+	// Without a session bus there is no Secret Service to talk to.
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+		return nil
+	}
+	return secretService{}
+}
+
+func (secretService) Get(key string) (string, error) {
+	out, err := exec.Command("secret-tool", "lookup", "service", keyringService, "account", key).Output()
+	if err != nil || len(out) == 0 {
+		return "", errKeyringNotFound
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (secretService) Set(key, secret string) error {
+	cmd := exec.Command("secret-tool", "store", "--label", keyringService+" ("+key+")",
+		"service", keyringService, "account", key)
+	cmd.Stdin = strings.NewReader(secret)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", errKeyringUnavailable, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (secretService) Delete(key string) error {
+	if err := exec.Command("secret-tool", "clear", "service", keyringService, "account", key).Run(); err != nil {
+		return errKeyringNotFound
+	}
+	return nil
+}
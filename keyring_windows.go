@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// winCredentials stores tokens as generic credentials in the Windows
+// Credential Manager via advapi32.
+type winCredentials struct{}
+
+var (
+	advapi32       = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW  = advapi32.NewProc("CredReadW")
+	procCredWriteW = advapi32.NewProc("CredWriteW")
+	procCredDelete = advapi32.NewProc("CredDeleteW")
+	procCredFree   = advapi32.NewProc("CredFree")
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+)
+
+// winCredential mirrors the CREDENTIALW structure.
+type winCredential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+func systemKeyring() keyring {
+	if err := advapi32.Load(); err != nil {
+		return nil
+	}
+	return winCredentials{}
+}
+
+func credTarget(key string) string {
+	return keyringService + ":" + key
+}
+
+func (winCredentials) Get(key string) (string, error) {
+	target, err := syscall.UTF16PtrFromString(credTarget(key))
+	if err != nil {
+		return "", err
+	}
+	var cred *winCredential
+	ret, _, _ := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if ret == 0 {
+		return "", errKeyringNotFound
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+	blob := unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)
+	return string(blob), nil
+}
+
+func (winCredentials) Set(key, secret string) error {
+	target, err := syscall.UTF16PtrFromString(credTarget(key))
+	if err != nil {
+		return err
+	}
+	user, err := syscall.UTF16PtrFromString(key)
+	if err != nil {
+		return err
+	}
+	blob := []byte(secret)
+	cred := winCredential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+	ret, _, callErr := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if ret == 0 {
+		return fmt.Errorf("%w: %v", errKeyringUnavailable, callErr)
+	}
+	return nil
+}
+
+func (winCredentials) Delete(key string) error {
+	target, err := syscall.UTF16PtrFromString(credTarget(key))
+	if err != nil {
+		return err
+	}
+	ret, _, _ := procCredDelete.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0)
+	if ret == 0 {
+		return errKeyringNotFound
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// ListMembership reports accounts added to or removed from a list.
+type ListMembership struct {
+	List     mastodon.List      `json:"list"`
+	Accounts []mastodon.Account `json:"accounts"`
+	Added    bool               `json:"added"`
+}
+
+// DeletedList reports a deleted list.
+type DeletedList struct {
+	List mastodon.List `json:"list"`
+}
+
+// runLists handles the lists subcommands. Lists can be named by ID or title.
+func runLists(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+	if len(args) == 0 {
+		return client.Lists(ctx)
+	}
+	sub, rest := args[0], args[1:]
+	need := func(n int, what string) error {
+		if len(rest) < n {
+			return fmt.Errorf("lists %s requires %s", sub, what)
+		}
+		return nil
+	}
+	switch sub {
+	case "create":
+		if err := need(1, "a title"); err != nil {
+			return nil, err
+		}
+		list, err := client.CreateList(ctx, strings.Join(rest, " "))
+		if err != nil {
+			return nil, err
+		}
+		return *list, nil
+	case "rename":
+		if err := need(2, "a list and a new title"); err != nil {
+			return nil, err
+		}
+		list, err := client.FindList(ctx, rest[0])
+		if err != nil {
+			return nil, err
+		}
+		renamed, err := client.RenameList(ctx, list.ID, strings.Join(rest[1:], " "))
+		if err != nil {
+			return nil, err
+		}
+		return *renamed, nil
+	case "delete":
+		if err := need(1, "a list ID or title"); err != nil {
+			return nil, err
+		}
+		list, err := client.FindList(ctx, rest[0])
+		if err != nil {
+			return nil, err
+		}
+		if err := client.DeleteList(ctx, list.ID); err != nil {
+			return nil, err
+		}
+		return DeletedList{List: *list}, nil
+	case "add", "remove":
+		if err := need(2, "a list and at least one account"); err != nil {
+			return nil, err
+		}
+		return changeListMembers(ctx, client, rest[0], rest[1:], sub == "add")
+	case "members":
+		if err := need(1, "a list ID or title"); err != nil {
+			return nil, err
+		}
+		list, err := client.FindList(ctx, rest[0])
+		if err != nil {
+			return nil, err
+		}
+		return client.ListAccounts(ctx, list.ID, pageOptions())
+	case "timeline":
+		if err := need(1, "a list ID or title"); err != nil {
+			return nil, err
+		}
+		list, err := client.FindList(ctx, rest[0])
+		if err != nil {
+			return nil, err
+		}
+		return client.ListTimeline(ctx, list.ID, pageOptions())
+	default:
+		return nil, fmt.Errorf("unknown lists subcommand: %s", sub)
+	}
+}
+
+func changeListMembers(ctx context.Context, client *mastodon.Client, listRef string, refs []string, add bool) (interface{}, error) {
+	list, err := client.FindList(ctx, listRef)
+	if err != nil {
+		return nil, err
+	}
+	accounts := make([]mastodon.Account, 0, len(refs))
+	ids := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		account, err := client.ResolveAccount(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, *account)
+		ids = append(ids, account.ID)
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("no accounts given")
+	}
+	if add {
+		err = client.AddToList(ctx, list.ID, ids)
+	} else {
+		err = client.RemoveFromList(ctx, list.ID, ids)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ListMembership{List: *list, Accounts: accounts, Added: add}, nil
+}
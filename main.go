@@ -3,15 +3,17 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"html"
 	"io"
 	"net/http"
-	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
 )
 
 const (
@@ -20,12 +22,135 @@ const (
 )
 
 var (
-	flagInstanceURL = flag.String("instance", defaultInstanceURL, "Mastodon instance URL")
-	flagTimeout     = flag.Int("timeout", defaultTimeout, "Timeout in seconds")
-	flagLimit       = flag.Int("limit", 20, "Number of items to return")
-	flagJSON        = flag.Bool("json", false, "Output in JSON format")
+	flagInstanceURL   = flag.String("instance", defaultInstanceURL, "Mastodon instance URL")
+	flagAccount       = flag.String("account", "", "Named account from the config file to use")
+	flagTimeout       = flag.Int("timeout", defaultTimeout, "Timeout in seconds for the whole command, or for each request of export and import (0 = none)")
+	flagConnTimeout   = flag.Duration("connect-timeout", mastodon.DefaultConnectTimeout, "Give up connecting to the instance or proxy, and on the TLS handshake, after this long")
+	flagRespTimeout   = flag.Duration("response-timeout", 0, "Give up waiting for the response to a request after this long (0 = only --timeout applies)")
+	flagRetries       = flag.Int("retries", 3, "Retry rate-limited requests, server errors, and network failures this many times")
+	flagNoRetry       = flag.Bool("no-retry", false, "Never retry failed requests (same as --retries 0)")
+	flagNoCache       = flag.Bool("no-cache", false, "Don't cache responses or send conditional requests")
+	flagProxy         = flag.String("proxy", "", "Proxy URL (http://, https://, socks5://, or socks5h://); default from HTTPS_PROXY/HTTP_PROXY")
+	flagCACert        = flag.String("ca-cert", "", "PEM file of extra CA certificates to trust (for instances behind a private CA)")
+	flagClientCert    = flag.String("client-cert", "", "PEM client certificate for mTLS (with --client-key)")
+	flagClientKey     = flag.String("client-key", "", "PEM private key for --client-cert")
+	flagAllowInsecure = flag.Bool("allow-insecure", false, "Allow a plain http:// instance URL (sends your token unencrypted)")
+	flagInsecure      = flag.Bool("insecure-skip-verify", false, "Don't verify TLS certificates (DANGEROUS: exposes your token to interception)")
+	flagLimit         = flag.Int("limit", 20, "Number of items to return")
+	flagAll           = flag.Bool("all", false, "Follow pagination until every item has been fetched (ignores --limit)")
+	flagMaxPages      = flag.Int("max-pages", 0, "Maximum number of pages to fetch (0 = no limit)")
+	flagInstances     = flag.String("instances", "", "Query these instances at once and merge the results: comma-separated URLs, host names, or [groups] from the config file")
+	flagConcurrency   = flag.Int("concurrency", mastodon.DefaultConcurrency, "Requests in flight at once for the pages of search, search-accounts, and trends and for export's lookups; other listings fetch one page at a time")
+	flagSinceID       = flag.String("since-id", "", "Only return items newer than this ID")
+	flagMaxID         = flag.String("max-id", "", "Only return items older than this ID")
+	flagMinID         = flag.String("min-id", "", "Return items immediately newer than this ID, paging forward in time")
+	flagOffset        = flag.Int("offset", 0, "Skip this many results (search and trends)")
+	flagJSON          = flag.Bool("json", false, "Output in JSON format (same as --output json)")
+	flagOutput        = flag.String("output", "text", "Output format: text, json, ndjson, csv, tsv, rss, atom, or markdown")
+	flagVerbose       = flag.Bool("verbose", false, "Log each API request's method, URL, status, timing, and rate limit to stderr")
+	flagDebug         = flag.Bool("debug", false, "Like --verbose, and log request and response headers too (tokens redacted)")
+	flagTraceFile     = flag.String("trace-file", "", "Append every request and response, headers and bodies, to this file (tokens redacted)")
+	flagReplay        = flag.String("replay", "", "Answer requests from the .har fixtures in this directory instead of the network")
+	flagHAR           = flag.String("har", "", "Record every request and response of the run to this HTTP Archive (.har) file (tokens redacted)")
+	flagQuiet         = flag.Bool("quiet", false, "Don't print progress messages or warnings on stderr")
+	flagColor         = flag.String("color", "auto", "Color text output: auto (when writing to a terminal), always, or never")
+	flagTimezone      = flag.String("timezone", "", "IANA time zone for local timestamps, e.g. Europe/Berlin (default: $MASTODON_TIMEZONE, or $TZ or the system zone)")
+	flagTimestamps    = flag.String("timestamps", "relative", "How text output shows times: relative, local, utc, or iso")
+	flagShowCW        = flag.Bool("show-cw", false, "Show the text of posts behind content warnings")
+	flagCWOnly        = flag.String("cw-only", "", "Only show posts whose content warning contains this keyword")
+	flagInclude       = flag.String("include", "", "Only show posts whose text matches this regular expression")
+	flagExcludeText   = flag.String("exclude", "", "Hide posts whose text matches this regular expression")
+	flagLang          = flag.String("lang", "", "Only show posts in these comma-separated languages, e.g. en,de")
+	flagMuteFile      = flag.String("mute-file", "", "File of words, phrases, and regex: patterns to hide (default: muted-words.txt in the config directory)")
+	flagMinBoosts     = flag.Int("min-boosts", 0, "Only show posts boosted at least this many times")
+	flagMinFavs       = flag.Int("min-favs", 0, "Only show posts favourited at least this many times")
+	flagMinReplies    = flag.Int("min-replies", 0, "Only show posts with at least this many replies")
+	flagSort          = flag.String("sort", "", "Reorder fetched posts: engagement (most boosts, favourites, and replies first), newest, or oldest")
+	flagNoBoosts      = flag.Bool("no-boosts", false, "Hide boosts")
+	flagNoReplyPosts  = flag.Bool("no-replies", false, "Hide replies")
+	flagDownloadMedia = flag.String("download-media", "", "Save the attachments of fetched posts to this directory")
+	flagPreview       = flag.Bool("preview", false, "Show image previews inline on kitty, iTerm2, and WezTerm")
+	flagFormat        = flag.String("format", "", "Go template applied to each item, e.g. '{{.Account.Acct}}: {{.ContentText}}'")
+	flagOutFields     = flag.String("fields", "", "Comma-separated fields to output, as dot paths like account.acct (json, ndjson, csv, tsv)")
+	flagExec          = flag.String("exec", "", "Shell command to run for each new item in --watch or stream mode; gets the item as JSON on stdin and MASTODON_* variables")
+	flagWebhookURL    = flag.String("webhook-url", "", "POST each new item as JSON to this URL in --watch or stream mode")
+	flagWebhookSecret = flag.String("webhook-secret", "", "Sign --webhook-url requests with HMAC-SHA256 using this secret (default: $MASTODON_WEBHOOK_SECRET)")
+	flagWebhookFormat = flag.String("webhook-format", "json", "Body of --webhook-url requests: json (the API object), slack, or discord")
+	flagListen        = flag.String("listen", "127.0.0.1:9877", "Address the metrics exporter and the serve API listen on")
+	flagInterval      = flag.Duration("interval", time.Minute, "How often the metrics exporter polls the instance")
+	flagMarkRead      = flag.Bool("mark-read", false, "Mark listed conversations as read")
+	flagUnread        = flag.Bool("unread", false, "Only show home posts after your read marker (see markers)")
+	flagTypes         = flag.String("types", "", "Comma-separated notification types to include")
+	flagExclude       = flag.String("exclude-types", "", "Comma-separated notification types to exclude")
+	flagVisibility    = flag.String("visibility", "", "Visibility for new posts: public, unlisted, private, or direct (default: your preference on the instance)")
+	flagSpoiler       = flag.String("spoiler", "", "Content warning text for new posts")
+	flagLanguage      = flag.String("language", "", "ISO 639 language code for new posts (default: your preference on the instance)")
+	flagScopes        = flag.String("scopes", "read", "OAuth scopes to request during login")
+	flagNoBrowser     = flag.Bool("no-browser", false, "During login, paste the authorization code instead of using a browser redirect")
+	flagToken         = flag.String("token", "", "Access token to use (- reads it from the first line of stdin); visible to other local users, so prefer --token-file")
+	flagTokenFile     = flag.String("token-file", "", "Read the access token from this file")
+	flagNoKeyring     = flag.Bool("no-keyring", false, "Store tokens in the credentials file instead of the OS keyring")
+	flagDisplayName   = flag.String("display-name", "", "New display name (profile set)")
+	flagBio           = flag.String("bio", "", "New profile bio (profile set)")
+	flagLocked        = flag.Bool("locked", false, "Require approval for new followers (profile set)")
+	flagBot           = flag.Bool("bot", false, "Mark the account as a bot (profile set)")
+	flagDiscover      = flag.Bool("discoverable", false, "List the account in the profile directory (profile set)")
+	flagSchedule      = flag.String("schedule", "", "Publish the post at this RFC 3339 time instead of now")
+	flagPollExpires   = flag.Duration("poll-expires", 24*time.Hour, "How long a new poll stays open")
+	flagPollMulti     = flag.Bool("poll-multiple", false, "Allow choosing several options in a new poll")
+	flagMedia         = flag.String("media", "", "Comma-separated files to attach to a new post")
+	flagMaxPixels     = flag.String("max-pixels", "", "Shrink images to at most this many pixels (e.g. 8294400 or 3840x2160) before uploading")
+	flagStripEXIF     = flag.Bool("strip-exif", false, "Remove EXIF, XMP, and other metadata (such as GPS location) from images before uploading")
+	flagRequireAlt    = flag.Bool("require-alt-text", false, "Refuse to upload media without --alt (default: require_alt_text in the config file)")
+	flagThread        = flag.Bool("thread", false, "Split long text at the instance's character limit and post it as a numbered thread")
+	flagPostFile      = flag.String("file", "", "Post the text of a file, with options from its YAML front matter")
+	flagIdempotency   = flag.String("idempotency-key", "", "Key that makes the instance post only once however often the command runs (default: random per run)")
+	flagContext       = flag.String("context", "", "Comma-separated filter contexts: home, notifications, public, thread, account")
+	flagAction        = flag.String("filter-action", "warn", "What a filter does with matches: warn, hide, or blur")
+	flagExpires       = flag.Duration("expires", 0, "Filter lifetime, e.g. 24h (0 = never expires)")
+	flagWholeWord     = flag.Bool("whole-word", false, "Match --keyword values as whole words only")
+	flagCategory      = flag.String("category", "other", "Report category: spam, legal, violation, or other")
+	flagComment       = flag.String("comment", "", "Additional information for the moderators (report)")
+	flagForward       = flag.Bool("forward", false, "Forward a report to the remote account's server")
+	flagNoReplies     = flag.Bool("exclude-replies", false, "Skip replies when listing an account's posts")
+	flagNoReblogs     = flag.Bool("exclude-reblogs", false, "Skip boosts when listing an account's posts")
+	flagOnlyMedia     = flag.Bool("only-media", false, "Only show posts that have attachments")
+	flagPinned        = flag.Bool("pinned", false, "Only list an account's pinned posts")
+	flagTagged        = flag.String("tagged", "", "Only list an account's posts with this hashtag")
+	flagSearchType    = flag.String("type", "statuses", "What to search for: statuses, accounts, hashtags, or all; or what to import: following, mutes, blocks, domain-blocks, bookmarks, or lists")
+	flagResolve       = flag.Bool("resolve", false, "Look up remote accounts and post URLs the instance hasn't seen (search)")
+	flagAccountID     = flag.String("account-id", "", "Only search posts by this account (ID or @user@instance)")
+	flagFollowing     = flag.Bool("following", false, "Only search accounts you follow")
+	flagTo            = flag.String("to", "", "ISO 639 language to translate into (default: your interface language)")
+	flagWithMedia     = flag.Bool("with-media", false, "Also save the attachments of your posts to <dir>/media (export)")
+	flagCheck         = flag.Bool("check", false, "Also ask GitHub whether a newer release is out (version)")
+	flagYes           = flag.Bool("yes", false, "Don't ask before deleting posts, blocking, or reporting")
+	flagArchive       = flag.Bool("archive", false, "Store every fetched post, account, and notification in the local archive (see the archive command)")
+	flagDryRun        = flag.Bool("dry-run", false, "Show the requests that would change something instead of sending them; import resolves every entry without changing anything")
+	flagData          = flag.String("data", "", "JSON request body for api, or @file to read it from a file (@- for stdin)")
+	flagFields        stringList
+	flagRuleIDs       stringList
+	flagKeywords      stringList
+	flagDropKeyword   stringList
+	flagAlt           stringList
+	flagFocus         stringList
+	flagPollOptions   stringList
+	flagParams        stringList
+	flagWatch         watchInterval
+
+	// httpClient is shared by every API client this run creates, so
+	// requests reuse connections.
+	httpClient = mastodon.NewHTTPClient()
+
+	// responseCache holds GET responses for conditional requests; nil when
+	// caching is off or the cache directory is unusable.
+	responseCache mastodon.Cache
 
-	httpClient = &http.Client{}
+	// activeAccount is the config file account selected for this run, if any.
+	activeAccount *AccountConfig
+
+	// activeToken is the access token this run uses, once resolved.
+	activeToken string
 )
 
 // MastodonResponse wraps the API response
@@ -33,288 +158,1120 @@ type MastodonResponse struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   *string     `json:"error,omitempty"`
+	// Code classifies a failure (see errorCode), and Status is the HTTP
+	// status of the API response that caused it.
+	Code   string `json:"code,omitempty"`
+	Status int    `json:"status,omitempty"`
 }
 
-// Account represents a Mastodon user account
-type Account struct {
-	ID          string `json:"id"`
-	Username    string `json:"username"`
-	DisplayName string `json:"display_name"`
-}
-
-// Status represents a Mastodon post
-type Status struct {
-	ID              string  `json:"id"`
-	Content         string  `json:"content"`
-	CreatedAt       string  `json:"created_at"`
-	URL             string  `json:"url"`
-	RepliesCount    int     `json:"replies_count"`
-	ReblogsCount    int     `json:"reblogs_count"`
-	FavouritesCount int     `json:"favourites_count"`
-	Account         Account `json:"account"`
-	Reblog          *Status `json:"reblog"`
-}
-
-// Notification represents a Mastodon notification
-type Notification struct {
-	ID        string  `json:"id"`
-	Type      string  `json:"type"`
-	CreatedAt string  `json:"created_at"`
-	Account   Account `json:"account"`
-	Status    *Status `json:"status"`
-}
-
-// SearchResult represents the response from /api/v2/search
-type SearchResult struct {
-	Statuses []Status `json:"statuses"`
+func init() {
+	flag.Var(&flagFields, "field", "Profile field as name=value; repeat for each field, replacing all fields (profile set)")
+	flag.Var(&flagAlt, "alt", "Alt text for uploaded media; repeat once per file, in order")
+	flag.Var(&flagRuleIDs, "rule-id", "ID of an instance rule the reported content breaks (with --category violation); repeat for several")
+	flag.Var(&flagKeywords, "keyword", "Add a filter keyword; repeat for several")
+	flag.Var(&flagDropKeyword, "remove-keyword", "Remove a keyword when editing a filter; repeat for several")
+	flag.Var(&flagPollOptions, "poll-option", "Add a poll option to a new post; repeat for each option")
+	flag.Var(&flagWatch, "watch", "Poll for new items every minute, or as often as --watch=5m says, printing only what is new")
+	flag.Var(&flagParams, "param", "Request parameter as key=value for api; repeat for several (key[]=value for arrays)")
+	flag.Var(&flagFocus, "focus", "Focal point x,y (-1 to 1) for uploaded media; repeat once per file, in order")
 }
 
 func main() {
-	flag.Parse()
-
-	args := flag.Args()
-	if len(args) == 0 {
-		fmt.Fprintln(os.Stderr, "Usage: mastodon-scout <command> [args]")
-		fmt.Fprintln(os.Stderr, "Commands:")
-		fmt.Fprintln(os.Stderr, "  home              Get home timeline")
-		fmt.Fprintln(os.Stderr, "  user-tweets       Get user's tweets")
-		fmt.Fprintln(os.Stderr, "  mentions          Get mentions")
-		fmt.Fprintln(os.Stderr, "  search <query>    Search for posts")
+	args := os.Args[1:]
+	i := commandIndex(args)
+	if i < 0 {
+		for _, arg := range args {
+			if isHelpFlag(arg) {
+				printUsage(os.Stdout)
+				return
+			}
+		}
+		printUsage(os.Stderr)
 		os.Exit(1)
 	}
 
-	token := os.Getenv("MASTODON_TOKEN")
-	if token == "" {
-		outputError("MASTODON_TOKEN environment variable not set")
+	cfg, err := loadConfig()
+	if err != nil {
+		outputError(err.Error())
+		os.Exit(1)
+	}
+	if args, err = expandAliases(cfg, args); err != nil {
+		outputError(err.Error())
 		os.Exit(1)
 	}
+	i = commandIndex(args)
+	name := args[i]
+	if name == "help" {
+		if err := runHelp(args[i+1:]); err != nil {
+			outputError(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+	cmd := lookupCommand(name)
+	if cmd == nil {
+		exitUsage(fmt.Sprintf("unknown command: %s", name))
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(*flagTimeout)*time.Second)
-	defer cancel()
+	args, err = parseCommandLine(cmd, append(args[:i:i], args[i+1:]...))
+	if errors.Is(err, flag.ErrHelp) {
+		printCommandHelp(os.Stdout, cmd)
+		return
+	}
+	if err != nil {
+		exitUsage(usageError(cmd, err).Error())
+	}
+	if err := cmd.checkArgs(args); err != nil {
+		exitUsage(err.Error())
+	}
+	command := cmd.formatKey(args)
+	if *flagInstances != "" && flagWatch > 0 {
+		outputError("--instances can't be combined with --watch")
+		os.Exit(1)
+	}
+	if forwarding() && flagWatch == 0 && !cmd.Streaming {
+		outputError("--exec and --webhook-url run for new items, so they need --watch")
+		os.Exit(1)
+	}
+	if err := validateWebhookFormat(); err != nil {
+		outputError(err.Error())
+		os.Exit(1)
+	}
+
+	// config runs even when a setting is broken, so that it can fix it.
+	if err := applyDefaults(cfg); err != nil && cmd.Name != "config" {
+		outputError(err.Error())
+		os.Exit(1)
+	}
+	if err := configureTimezone(); err != nil {
+		outputError(err.Error())
+		os.Exit(1)
+	}
+	if err := validateOutputFormat(); err != nil {
+		outputError(err.Error())
+		os.Exit(1)
+	}
+	if err := configureFilter(); err != nil {
+		outputError(err.Error())
+		os.Exit(1)
+	}
+	if err := configureColor(cfg); err != nil {
+		outputError(err.Error())
+		os.Exit(1)
+	}
+	if cfg.RequireAltText && !flagWasSet("require-alt-text") {
+		*flagRequireAlt = true
+	}
+	activeAccount, err = cfg.selectAccount(*flagAccount)
+	if err != nil && !(cmd.Name == "login" && flagWasSet("account")) && cmd.Name != "config" {
+		outputError(err.Error())
+		os.Exit(1)
+	}
+	// MASTODON_TOKEN and MASTODON_INSTANCE together beat the default
+	// account, as MASTODON_TOKEN alone beats its token.
+	if os.Getenv("MASTODON_TOKEN") != "" && os.Getenv("MASTODON_INSTANCE") != "" && !flagWasSet("account") {
+		activeAccount = nil
+	}
+	if activeAccount != nil && !flagWasSet("instance") {
+		*flagInstanceURL = activeAccount.Instance
+		settingSources["instance"] = sourceAccount
+	}
+	if *flagInstanceURL, err = normalizeInstanceURL(*flagInstanceURL); err != nil && cmd.Name != "config" {
+		outputError(err.Error())
+		os.Exit(1)
+	}
+	if err := configureTransport(); err != nil {
+		outputError(err.Error())
+		os.Exit(1)
+	}
+	if err := configureReplay(); err != nil {
+		outputError(err.Error())
+		os.Exit(1)
+	}
+	if err := configureTracing(); err != nil {
+		outputError(err.Error())
+		os.Exit(1)
+	}
+	if *flagDryRun && cmd.Name == "login" {
+		outputError("login can't be a dry run: it needs the instance to register the app")
+		os.Exit(1)
+	}
+	configureDryRun(flagWatch > 0 || cmd.Streaming)
+	handleSignals()
 
-	command := args[0]
 	var data interface{}
-	var err error
+	partial := false
+	if cmd.NoAuth {
+		// Commands that manage credentials run before a token is required.
+		data, err = cmd.Run(runContext, nil, args)
+	} else {
+		// Public timelines are readable anonymously on most instances, so
+		// the token is only mandatory for everything else.
+		var token string
+		if token, err = resolveToken(); err != nil {
+			exitWithError(err)
+		}
+		if token == "" && *flagReplay != "" {
+			// Fixtures are recorded with the token redacted.
+			token = "replay"
+		}
+		if token == "" && !cmd.Anonymous {
+			exitWithError(errNoToken)
+		}
+		activeToken = token
+		if !*flagNoCache && *flagReplay == "" {
+			responseCache = openResponseCache()
+		}
+		perRequestTimeout = cmd.LongRunning
+		client := newClient(token)
+		var targets []fanOutTarget
+		if *flagInstances != "" {
+			if targets, err = fanOutTargets(cfg, token); err != nil {
+				outputError(err.Error())
+				os.Exit(1)
+			}
+		}
 
-	switch command {
-	case "home":
-		data, err = getHomeTimeline(ctx, token)
-	case "user-tweets":
-		data, err = getUserTweets(ctx, token)
-	case "mentions":
-		data, err = getMentions(ctx, token)
-	case "search":
-		if len(args) < 2 {
-			outputError("search command requires a query argument")
-			os.Exit(1)
+		if *flagArchive {
+			if activeArchive, err = openArchive(*flagInstanceURL); err != nil {
+				outputError(err.Error())
+				os.Exit(1)
+			}
+		}
+
+		if flagWatch > 0 {
+			if err := runWatch(client, cmd, args, command, strings.Join(append([]string{cmd.Name}, args...), " ")); err != nil {
+				exitWithError(err)
+			}
+			exitIfInterrupted()
+			return
+		}
+
+		// Streaming runs until interrupted, so it is exempt from --timeout.
+		if cmd.Streaming {
+			if _, err := cmd.Run(runContext, client, args); err != nil && !interrupted() {
+				exitWithError(err)
+			}
+			exitIfInterrupted()
+			return
+		}
+
+		var ctx context.Context
+		var cancel context.CancelFunc
+		if cmd.LongRunning {
+			ctx, cancel = context.WithCancel(runContext)
+		} else {
+			ctx, cancel = requestContext()
+		}
+		defer cancel()
+		if err := checkScopes(ctx, client, command); err != nil {
+			exitWithError(err)
+		}
+		configureStream(cmd)
+		if targets != nil {
+			data, err = runFanOut(ctx, cmd, args, targets)
+		} else {
+			data, err = cmd.Run(ctx, client, args)
+		}
+		err = explainScopeError(command, err)
+		if err != nil {
+			err = explainInstanceError(err)
+		}
+		archiveItems(data)
+		streamed := activeStream != nil && activeStream.written > 0
+		if err != nil && interrupted() && (partialResult(data) || streamed) {
+			// Print what was fetched before the interrupt rather than
+			// dropping it.
+			partial, err = true, nil
+		}
+		if err == nil {
+			data = sortResults(activeFilter.apply(data))
+		}
+		if err == nil && !partial && *flagDownloadMedia != "" {
+			err = downloadMedia(ctx, data, *flagDownloadMedia)
+		}
+		if cmd.Name != "rate-limit" {
+			warnRateLimit(client)
 		}
-		data, err = searchPosts(ctx, token, args[1])
-	default:
-		outputError(fmt.Sprintf("unknown command: %s", command))
-		os.Exit(1)
 	}
 
+	if dryRun != nil {
+		if held := dryRun.heldRequests(); len(held) > 0 {
+			if err != nil {
+				notef("Warning: the dry run stopped early: %v\n", err)
+			}
+			printResult("dry-run", "dry run", DryRun{Requests: held})
+			return
+		}
+	}
 	if err != nil {
-		outputError(err.Error())
-		os.Exit(1)
+		exitWithError(err)
+	}
+
+	printResult(command, strings.Join(append([]string{cmd.Name}, args...), " "), data)
+	if activeStream != nil {
+		if err := activeStream.finish(); err != nil {
+			outputError(err.Error())
+			os.Exit(1)
+		}
+	}
+	if partial {
+		notef("Interrupted: the results are incomplete\n")
+	}
+	exitIfInterrupted()
+}
+
+// requestContext returns a context bounded by --timeout that a signal also
+// cancels.
+func requestContext() (context.Context, context.CancelFunc) {
+	return withTimeout(runContext)
+}
+
+// withTimeout bounds parent by --timeout, unless it is 0.
+func withTimeout(parent context.Context) (context.Context, context.CancelFunc) {
+	if *flagTimeout <= 0 {
+		return context.WithCancel(parent)
 	}
+	return context.WithTimeout(parent, time.Duration(*flagTimeout)*time.Second)
+}
+
+// perRequestTimeout makes --timeout bound each request rather than the
+// whole command, for commands that make as many requests as they take.
+var perRequestTimeout bool
 
-	if *flagJSON {
+// printResult writes a command's result in the selected output format.
+// title describes the invocation, for formats such as feeds that name their
+// contents.
+func printResult(command, title string, data interface{}) {
+	if outputTemplate != nil {
+		if err := writeTemplate(data); err != nil {
+			outputError(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+	switch outputFormat() {
+	case "json":
+		if *flagOutFields != "" {
+			pruner := newFieldPruner(*flagOutFields)
+			pruned, err := pruner.pruneAll(data)
+			if err == nil {
+				err = pruner.check(len(resultItems(data)))
+			}
+			if err != nil {
+				outputError(err.Error())
+				os.Exit(1)
+			}
+			data = pruned
+		}
 		output, err := json.Marshal(MastodonResponse{Success: true, Data: data})
 		if err != nil {
 			outputError(fmt.Sprintf("marshaling response: %v", err))
 			os.Exit(1)
 		}
 		fmt.Println(string(output))
-	} else {
+	case "ndjson":
+		if err := writeNDJSON(data); err != nil {
+			outputError(err.Error())
+			os.Exit(1)
+		}
+	case "rss", "atom":
+		if err := writeFeed(outputFormat(), title, data); err != nil {
+			outputError(err.Error())
+			os.Exit(1)
+		}
+	case "markdown":
+		if err := writeMarkdown(data); err != nil {
+			outputError(err.Error())
+			os.Exit(1)
+		}
+	case "csv", "tsv":
+		sep := ','
+		if outputFormat() == "tsv" {
+			sep = '\t'
+		}
+		if err := writeCSV(data, sep); err != nil {
+			outputError(err.Error())
+			os.Exit(1)
+		}
+	default:
 		formatText(command, data)
 	}
 }
 
 func outputError(msg string) {
-	response := MastodonResponse{Success: false, Error: &msg}
+	writeError(MastodonResponse{Success: false, Error: &msg, Code: codeError})
+}
+
+// writeError reports an error once, in the form the output format calls
+// for: a line of text on stderr for text output, and the JSON envelope
+// otherwise. With --output json the envelope takes the result's place on
+// stdout; with the other formats it goes to stderr so it can't be mistaken
+// for a row or a feed.
+func writeError(response MastodonResponse) {
+	format := outputFormat()
+	if format == "text" && outputTemplate == nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", *response.Error)
+		return
+	}
 	output, _ := json.Marshal(response)
-	fmt.Println(string(output))
-	fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+	if format == "json" {
+		fmt.Println(string(output))
+		return
+	}
+	fmt.Fprintln(os.Stderr, string(output))
+}
+
+// notef prints a progress message or warning on stderr unless --quiet is
+// set.
+func notef(format string, args ...interface{}) {
+	if !*flagQuiet {
+		fmt.Fprintf(os.Stderr, format, args...)
+	}
+}
+
+// newClient builds the API client for this run from the global flags.
+func newClient(token string) *mastodon.Client {
+	return newInstanceClient(*flagInstanceURL, token)
 }
 
-func makeRequest(ctx context.Context, token, endpoint string) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, *flagInstanceURL+endpoint, nil)
+// newInstanceClient builds an API client for instance, configured like
+// newClient's.
+func newInstanceClient(instance, token string) *mastodon.Client {
+	retries := *flagRetries
+	if *flagNoRetry {
+		retries = 0
+	}
+	opts := []mastodon.Option{
+		mastodon.WithHTTPClient(httpClient), mastodon.WithRetries(retries), mastodon.WithConcurrency(*flagConcurrency),
+		mastodon.WithStreamTimeouts(mastodon.Timeouts{Connect: *flagConnTimeout, Response: *flagRespTimeout}),
+	}
+	if perRequestTimeout && *flagTimeout > 0 {
+		opts = append(opts, mastodon.WithRequestTimeout(time.Duration(*flagTimeout)*time.Second))
+	}
+	if responseCache != nil {
+		opts = append(opts, mastodon.WithCache(responseCache))
+	}
+	return mastodon.NewClient(instance, token, opts...)
+}
+
+// configureTransport applies --proxy, the TLS flags, and the connect and
+// response timeouts to the shared HTTP client. Without --proxy the
+// transport follows the HTTPS_PROXY, HTTP_PROXY, and NO_PROXY environment
+// variables.
+func configureTransport() error {
+	if *flagProxy == "" && *flagCACert == "" && *flagClientCert == "" && *flagClientKey == "" && !*flagInsecure &&
+		*flagConnTimeout == mastodon.DefaultConnectTimeout && *flagRespTimeout == 0 {
+		return nil
+	}
+	if *flagClientCert != "" && *flagClientKey == "" {
+		return errors.New("--client-cert requires --client-key")
+	}
+	if *flagClientKey != "" && *flagClientCert == "" {
+		return errors.New("--client-key requires --client-cert")
+	}
+	transport := mastodon.NewTransport()
+	mastodon.Timeouts{Connect: *flagConnTimeout, Response: *flagRespTimeout}.Apply(transport)
+	if *flagProxy != "" {
+		proxy, err := mastodon.ParseProxyURL(*flagProxy)
+		if err != nil {
+			return err
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	tlsConfig, err := mastodon.TLSOptions{
+		CACertFile:         *flagCACert,
+		ClientCertFile:     *flagClientCert,
+		ClientKeyFile:      *flagClientKey,
+		InsecureSkipVerify: *flagInsecure,
+	}.Config()
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+		return err
 	}
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Accept", "application/json")
+	if *flagInsecure {
+		fmt.Fprintln(os.Stderr, "WARNING: --insecure-skip-verify is set. TLS certificates are NOT verified, so anyone between you and the server can read and change this session, including your access token.")
+	}
+	transport.TLSClientConfig = tlsConfig
+	httpClient.Transport = transport
+	return nil
+}
+
+// cacheMaxAge is how long an unused cached response is kept.
+const cacheMaxAge = 7 * 24 * time.Hour
 
-	resp, err := httpClient.Do(req)
+// openResponseCache returns the on-disk response cache under the user cache
+// directory, or nil if it can't be used; caching is only an optimization.
+func openResponseCache() mastodon.Cache {
+	dir, err := os.UserCacheDir()
 	if err != nil {
-		return nil, fmt.Errorf("making request: %w", err)
+		return nil
 	}
-	defer resp.Body.Close()
+	cache, err := mastodon.NewDirCache(filepath.Join(dir, "mastodon-scout", "http"))
+	if err != nil {
+		return nil
+	}
+	cache.Prune(cacheMaxAge)
+	return cache
+}
+
+// pageOptions translates the pagination flags into per-call options.
+func pageOptions() mastodon.PageOptions {
+	return mastodon.PageOptions{
+		Limit:    *flagLimit,
+		All:      *flagAll,
+		MaxPages: *flagMaxPages,
+		MaxID:    *flagMaxID,
+		SinceID:  *flagSinceID,
+		MinID:    *flagMinID,
+		Offset:   *flagOffset,
+		Each:     streamItem(),
+	}
+}
 
-	body, err := io.ReadAll(resp.Body)
+// streamItem is where a listing's items go as they are decoded: the
+// active stream, or nowhere (nil) when the listing is collected.
+func streamItem() func(interface{}) error {
+	if activeStream == nil {
+		return nil
+	}
+	return activeStream.write
+}
+
+func getHomeTimeline(ctx context.Context, client *mastodon.Client) (interface{}, error) {
+	opts, err := unreadHomeOptions(ctx, client, pageOptions())
 	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
+		return nil, err
 	}
+	return client.HomeTimeline(ctx, opts)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+// getPublicTimeline fetches the public timeline, scoped to this instance for
+// "local" and to other instances for "federated".
+func getPublicTimeline(ctx context.Context, client *mastodon.Client, command string) (interface{}, error) {
+	scope := mastodon.ScopeAll
+	switch command {
+	case "local":
+		scope = mastodon.ScopeLocal
+	case "federated":
+		scope = mastodon.ScopeRemote
 	}
+	return client.PublicTimeline(ctx, scope, pageOptions())
+}
+
+func getTagTimeline(ctx context.Context, client *mastodon.Client, tag string) (interface{}, error) {
+	return client.TagTimeline(ctx, tag, pageOptions())
+}
 
-	return body, nil
+func getBookmarks(ctx context.Context, client *mastodon.Client) (interface{}, error) {
+	return client.Bookmarks(ctx, pageOptions())
 }
 
-func getHomeTimeline(ctx context.Context, token string) (interface{}, error) {
-	body, err := makeRequest(ctx, token, fmt.Sprintf("/api/v1/timelines/home?limit=%d", *flagLimit))
+// getAccountPosts lists posts by ref (or the authenticated user), narrowed
+// by --exclude-replies, --exclude-reblogs, --only-media, --pinned, and
+// --tagged.
+func getAccountPosts(ctx context.Context, client *mastodon.Client, ref string) (interface{}, error) {
+	account, err := accountOrSelf(ctx, client, ref)
 	if err != nil {
 		return nil, err
 	}
-	var statuses []Status
-	if err := json.Unmarshal(body, &statuses); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
+	filter := mastodon.AccountStatusFilter{
+		ExcludeReplies: *flagNoReplies,
+		ExcludeReblogs: *flagNoReblogs,
+		OnlyMedia:      *flagOnlyMedia,
+		Pinned:         *flagPinned,
+		Tagged:         *flagTagged,
 	}
-	return statuses, nil
+	return client.AccountStatuses(ctx, account.ID, filter, pageOptions())
 }
 
-func getUserTweets(ctx context.Context, token string) (interface{}, error) {
-	body, err := makeRequest(ctx, token, "/api/v1/accounts/verify_credentials")
+func getMentions(ctx context.Context, client *mastodon.Client) (interface{}, error) {
+	return client.Notifications(ctx, mastodon.NotificationFilter{Types: []string{"mention"}}, pageOptions())
+}
+
+func getNotifications(ctx context.Context, client *mastodon.Client) (interface{}, error) {
+	types, err := parseNotificationTypes(*flagTypes)
 	if err != nil {
 		return nil, err
 	}
-	var account Account
-	if err := json.Unmarshal(body, &account); err != nil {
-		return nil, fmt.Errorf("parsing account: %w", err)
+	exclude, err := parseNotificationTypes(*flagExclude)
+	if err != nil {
+		return nil, err
 	}
-	if account.ID == "" {
-		return nil, fmt.Errorf("account ID not found")
+	return client.Notifications(ctx, mastodon.NotificationFilter{Types: types, ExcludeTypes: exclude}, pageOptions())
+}
+
+// parseNotificationTypes splits a comma-separated list and rejects types
+// Mastodon does not know about.
+func parseNotificationTypes(list string) ([]string, error) {
+	var types []string
+	for _, t := range strings.Split(list, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		known := false
+		for _, k := range mastodon.NotificationTypes {
+			if t == k {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown notification type %q (want one of %s)", t, strings.Join(mastodon.NotificationTypes, ", "))
+		}
+		types = append(types, t)
 	}
+	return types, nil
+}
+
+func searchAccounts(ctx context.Context, client *mastodon.Client, query string) (interface{}, error) {
+	opts := mastodon.SearchOptions{Resolve: *flagResolve, Following: *flagFollowing}
+	return client.SearchAccounts(ctx, query, opts, pageOptions())
+}
 
-	body, err = makeRequest(ctx, token, fmt.Sprintf("/api/v1/accounts/%s/statuses?limit=%d", account.ID, *flagLimit))
+func search(ctx context.Context, client *mastodon.Client, query string) (interface{}, error) {
+	opts := mastodon.SearchOptions{
+		Type:      *flagSearchType,
+		Resolve:   *flagResolve,
+		AccountID: *flagAccountID,
+		Following: *flagFollowing,
+	}
+	switch opts.Type {
+	case "all":
+		opts.Type = mastodon.SearchAll
+	case mastodon.SearchStatuses, mastodon.SearchAccounts, mastodon.SearchHashtags:
+	default:
+		return nil, fmt.Errorf("invalid --type %q (want statuses, accounts, hashtags, or all)", opts.Type)
+	}
+	// Accept @user@instance as well as a numeric account ID.
+	if strings.HasPrefix(opts.AccountID, "@") {
+		account, err := accountOrSelf(ctx, client, opts.AccountID)
+		if err != nil {
+			return nil, err
+		}
+		opts.AccountID = account.ID
+	}
+	result, err := client.Search(ctx, query, opts, pageOptions())
 	if err != nil {
 		return nil, err
 	}
-	var statuses []Status
-	if err := json.Unmarshal(body, &statuses); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
+	return *result, nil
+}
+
+// readPostText joins the positional arguments into the post body, falling back
+// to stdin when no text is given or the only argument is "-".
+func readPostText(args []string) (string, error) {
+	var text string
+	if len(args) == 0 || (len(args) == 1 && args[0] == "-") {
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("reading stdin: %w", err)
+		}
+		text = string(b)
+	} else {
+		text = strings.Join(args, " ")
 	}
-	return statuses, nil
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", fmt.Errorf("post command requires text (as an argument or on stdin)")
+	}
+	return text, nil
+}
+
+// flagWasSet reports whether the named flag was given on the command line.
+func flagWasSet(name string) bool {
+	set := false
+	cmdFlags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
 }
 
-func getMentions(ctx context.Context, token string) (interface{}, error) {
-	body, err := makeRequest(ctx, token, fmt.Sprintf("/api/v1/notifications?limit=%d&types[]=mention", *flagLimit))
+// createPost publishes or schedules a post. Without --visibility or
+// --language the instance applies the user's preferences. Text over the
+// instance's character limit is rejected before anything is uploaded.
+func createPost(ctx context.Context, client *mastodon.Client, text string) (interface{}, error) {
+	if *flagVisibility != "" {
+		if err := mastodon.ValidateVisibility(*flagVisibility); err != nil {
+			return nil, err
+		}
+	}
+	var at time.Time
+	if *flagSchedule != "" {
+		var err error
+		if at, err = parseScheduleTime(*flagSchedule); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkPostLength(ctx, client, text); err != nil {
+		return nil, err
+	}
+	poll, err := pollFromFlags()
+	if err != nil {
+		return nil, err
+	}
+	mediaIDs, err := attachMedia(ctx, client)
 	if err != nil {
 		return nil, err
 	}
-	var notifications []Notification
-	if err := json.Unmarshal(body, &notifications); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
+	params := mastodon.StatusParams{
+		Status:         text,
+		Visibility:     *flagVisibility,
+		SpoilerText:    *flagSpoiler,
+		Language:       *flagLanguage,
+		MediaIDs:       mediaIDs,
+		Poll:           poll,
+		IdempotencyKey: *flagIdempotency,
+	}
+	if *flagSchedule != "" {
+		scheduled, err := client.ScheduleStatus(ctx, params, at)
+		if err != nil {
+			return nil, err
+		}
+		return *scheduled, nil
+	}
+	status, err := client.PostStatus(ctx, params)
+	if err != nil {
+		return nil, err
 	}
-	return notifications, nil
+	return *status, nil
 }
 
-func searchPosts(ctx context.Context, token, query string) (interface{}, error) {
-	body, err := makeRequest(ctx, token, fmt.Sprintf("/api/v2/search?q=%s&type=statuses&limit=%d",
-		url.QueryEscape(query), *flagLimit))
+// replyToPost replies to the referenced status. The original visibility and
+// content warning are carried over unless --visibility or --spoiler are given,
+// and the original author is mentioned so the reply threads correctly.
+func replyToPost(ctx context.Context, client *mastodon.Client, ref, text string) (interface{}, error) {
+	id, err := client.ResolveStatusID(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	original, err := client.GetStatus(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	var result SearchResult
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
+
+	visibility := original.Visibility
+	if flagWasSet("visibility") || visibility == "" {
+		visibility = *flagVisibility
 	}
-	return result, nil
+	if visibility != "" {
+		if err := mastodon.ValidateVisibility(visibility); err != nil {
+			return nil, err
+		}
+	}
+	spoiler := original.SpoilerText
+	if flagWasSet("spoiler") {
+		spoiler = *flagSpoiler
+	}
+
+	if mention := "@" + original.Account.Acct; original.Account.Acct != "" && !strings.Contains(text, mention) {
+		text = mention + " " + text
+	}
+
+	status, err := client.PostStatus(ctx, mastodon.StatusParams{
+		Status:         text,
+		InReplyToID:    original.ID,
+		Visibility:     visibility,
+		SpoilerText:    spoiler,
+		Language:       *flagLanguage,
+		IdempotencyKey: *flagIdempotency,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return *status, nil
 }
 
-func formatText(command string, data interface{}) {
-	switch command {
-	case "home", "user-tweets":
-		statuses, ok := data.([]Status)
-		if !ok {
-			fmt.Println("Error: unexpected data format")
-			return
+// translateStatus translates a post with the instance's translation
+// service into --to, or the user's interface language.
+func translateStatus(ctx context.Context, client *mastodon.Client, ref string) (interface{}, error) {
+	id, err := client.ResolveStatusID(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	t, err := client.TranslateStatus(ctx, id, *flagTo)
+	if err != nil {
+		var apiErr *mastodon.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusServiceUnavailable {
+			return nil, errors.New("this instance has no translation service enabled")
 		}
-		formatStatuses(statuses)
-	case "mentions":
-		notifications, ok := data.([]Notification)
-		if !ok {
-			fmt.Println("Error: unexpected data format")
-			return
+		return nil, err
+	}
+	return *t, nil
+}
+
+// getStatus fetches a status by local ID or by URL from any instance.
+func getStatus(ctx context.Context, client *mastodon.Client, ref string) (interface{}, error) {
+	id, err := client.ResolveStatusID(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	status, err := client.GetStatus(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return *status, nil
+}
+
+// getStatusAudience lists the accounts that boosted or favourited a status.
+func getStatusAudience(ctx context.Context, client *mastodon.Client, ref, command string) (interface{}, error) {
+	id, err := client.ResolveStatusID(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if command == "boosters" {
+		return client.RebloggedBy(ctx, id, pageOptions())
+	}
+	return client.FavouritedBy(ctx, id, pageOptions())
+}
+
+func deletePost(ctx context.Context, client *mastodon.Client, ref string) (interface{}, error) {
+	id, err := client.ResolveStatusID(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if prompting() {
+		post, err := client.GetStatus(ctx, id)
+		if err != nil {
+			return nil, err
 		}
-		formatMentions(notifications)
-	case "search":
-		result, ok := data.(SearchResult)
-		if !ok {
-			fmt.Println("Error: unexpected data format")
-			return
+		var cancel context.CancelFunc
+		ctx, cancel, err = confirmRequest(ctx, fmt.Sprintf("Delete your post %q? This can't be undone", truncate(post.ContentText(), 60)))
+		if err != nil {
+			return nil, err
 		}
-		formatStatuses(result.Statuses)
+		defer cancel()
+	}
+	status, err := client.DeleteStatus(ctx, id)
+	if err != nil {
+		return nil, err
 	}
+	return *status, nil
 }
 
-// resolvePost returns the displayable post and the booster's username (if it's a boost).
-func resolvePost(s Status) (post Status, boostedBy string) {
-	if s.Reblog != nil {
-		return *s.Reblog, s.Account.Username
+// redraftPost deletes a status and reposts it after the user edits the
+// source text, keeping its visibility, content warning, language, reply
+// target, and media.
+func redraftPost(ctx context.Context, client *mastodon.Client, ref string) (interface{}, error) {
+	id, err := client.ResolveStatusID(ctx, ref)
+	if err != nil {
+		return nil, err
 	}
-	return s, ""
+	deleted, err := client.DeleteStatus(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	text, err := editText(deleted.Text)
+	if err != nil {
+		// The status is already gone; don't lose what the user wrote.
+		return nil, fmt.Errorf("%w; status %s was deleted, its text was:\n%s", err, id, deleted.Text)
+	}
+	// Time spent in the editor shouldn't count against --timeout.
+	ctx, cancel := requestContext()
+	defer cancel()
+	params := mastodon.StatusParams{
+		Status:      text,
+		Visibility:  deleted.Visibility,
+		SpoilerText: deleted.SpoilerText,
+	}
+	if deleted.Language != nil {
+		params.Language = *deleted.Language
+	}
+	if deleted.InReplyToID != nil {
+		params.InReplyToID = *deleted.InReplyToID
+	}
+	for _, m := range deleted.MediaAttachments {
+		params.MediaIDs = append(params.MediaIDs, m.ID)
+	}
+	status, err := client.PostStatus(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("%w; status %s was deleted, its text was:\n%s", err, id, deleted.Text)
+	}
+	return *status, nil
 }
 
-func formatStatuses(statuses []Status) {
-	if len(statuses) == 0 {
-		fmt.Println("No posts found.")
-		return
+// editPost opens a status's source text in the user's editor and saves the
+// result as an edit. Media, language, and an open poll are kept; --spoiler
+// replaces the content warning.
+func editPost(ctx context.Context, client *mastodon.Client, ref string) (interface{}, error) {
+	id, err := client.ResolveStatusID(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	status, err := client.GetStatus(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	source, err := client.GetStatusSource(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	params := mastodon.StatusParams{
+		SpoilerText: source.SpoilerText,
+		Sensitive:   status.Sensitive,
 	}
-	for i, s := range statuses {
-		post, boostedBy := resolvePost(s)
-		fmt.Printf("--- Post %d ---\n", i+1)
-		if boostedBy != "" {
-			fmt.Printf("🔁 @%s boosted\n", boostedBy)
+	if flagWasSet("spoiler") {
+		params.SpoilerText = *flagSpoiler
+	}
+	if status.Language != nil {
+		params.Language = *status.Language
+	}
+	for _, m := range status.MediaAttachments {
+		params.MediaIDs = append(params.MediaIDs, m.ID)
+	}
+	if p := status.Poll; p != nil {
+		// An edit without the poll would delete it.
+		if p.Expired || p.ExpiresAt == nil {
+			return nil, fmt.Errorf("status %s has a closed poll, which editing would remove", id)
+		}
+		expires, err := time.Parse(time.RFC3339, *p.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("parsing poll expiry: %w", err)
+		}
+		params.Poll = &mastodon.PollParams{ExpiresIn: time.Until(expires), Multiple: p.Multiple}
+		for _, o := range p.Options {
+			params.Poll.Options = append(params.Poll.Options, o.Title)
 		}
-		fmt.Printf("@%s (%s)\n", post.Account.Username, post.Account.DisplayName)
-		fmt.Printf("%s\n", post.CreatedAt)
-		fmt.Printf("\n%s\n\n", stripHTML(post.Content))
-		fmt.Printf("💬 %d  🔁 %d  ⭐ %d\n", post.RepliesCount, post.ReblogsCount, post.FavouritesCount)
-		fmt.Printf("🔗 %s\n\n", post.URL)
 	}
+
+	if params.Status, err = editText(source.Text); err != nil {
+		return nil, err
+	}
+	if params.Status == strings.TrimSpace(source.Text) && params.SpoilerText == source.SpoilerText {
+		return nil, errors.New("no changes, not editing")
+	}
+
+	// Time spent in the editor shouldn't count against --timeout.
+	ctx, cancel := requestContext()
+	defer cancel()
+	edited, err := client.EditStatus(ctx, id, params)
+	if err != nil {
+		return nil, err
+	}
+	return *edited, nil
 }
 
-func formatMentions(notifications []Notification) {
-	if len(notifications) == 0 {
-		fmt.Println("No mentions found.")
-		return
+func statusHistory(ctx context.Context, client *mastodon.Client, ref string) (interface{}, error) {
+	id, err := client.ResolveStatusID(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return client.StatusHistory(ctx, id)
+}
+
+// sendDirectMessage posts a direct-visibility status mentioning recipient.
+func sendDirectMessage(ctx context.Context, client *mastodon.Client, recipient, text string) (interface{}, error) {
+	acct := strings.TrimPrefix(recipient, "@")
+	if acct == "" {
+		return nil, fmt.Errorf("invalid recipient %q", recipient)
+	}
+	if mention := "@" + acct; !strings.Contains(text, mention) {
+		text = mention + " " + text
+	}
+	status, err := client.PostStatus(ctx, mastodon.StatusParams{
+		Status:         text,
+		Visibility:     "direct",
+		SpoilerText:    *flagSpoiler,
+		Language:       *flagLanguage,
+		IdempotencyKey: *flagIdempotency,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return *status, nil
+}
+
+// getConversations lists conversations and, with --mark-read, clears the
+// unread flag on each one that had it. The listing still reports which were
+// unread when fetched.
+func getConversations(ctx context.Context, client *mastodon.Client) (interface{}, error) {
+	convs, err := client.Conversations(ctx, pageOptions())
+	if err != nil {
+		return nil, err
 	}
-	for i, n := range notifications {
-		fmt.Printf("--- Mention %d ---\n", i+1)
-		fmt.Printf("@%s (%s) mentioned you\n", n.Account.Username, n.Account.DisplayName)
-		fmt.Printf("%s\n", n.CreatedAt)
-		if n.Status != nil {
-			fmt.Printf("\n%s\n\n", stripHTML(n.Status.Content))
+	if *flagMarkRead {
+		for _, conv := range convs {
+			if !conv.Unread {
+				continue
+			}
+			if _, err := client.MarkConversationRead(ctx, conv.ID); err != nil {
+				return nil, fmt.Errorf("marking conversation %s read: %w", conv.ID, err)
+			}
 		}
 	}
+	return convs, nil
+}
+
+// accountOrSelf resolves ref, or returns the authenticated account when ref
+// is empty.
+func accountOrSelf(ctx context.Context, client *mastodon.Client, ref string) (*mastodon.Account, error) {
+	if ref == "" {
+		return client.VerifyCredentials(ctx)
+	}
+	return client.ResolveAccount(ctx, ref)
+}
+
+// AccountProfile is an account together with its pinned posts.
+type AccountProfile struct {
+	Account mastodon.Account  `json:"account"`
+	Pinned  []mastodon.Status `json:"pinned"`
+}
+
+func getAccountProfile(ctx context.Context, client *mastodon.Client, ref string) (interface{}, error) {
+	account, err := client.ResolveAccount(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	pinned, err := client.PinnedStatuses(ctx, account.ID)
+	if err != nil {
+		return nil, fmt.Errorf("fetching pinned posts: %w", err)
+	}
+	return AccountProfile{Account: *account, Pinned: pinned}, nil
 }
 
-// stripHTML converts block-level tags to newlines, strips all remaining tags,
-// and decodes HTML entities.
-func stripHTML(s string) string {
-	// Convert block-level tags to newlines before stripping
-	s = strings.ReplaceAll(s, "</p><p>", "\n\n")
-	s = strings.ReplaceAll(s, "<br>", "\n")
-	s = strings.ReplaceAll(s, "<br/>", "\n")
-	s = strings.ReplaceAll(s, "<br />", "\n")
+// getPinned lists the authenticated user's pinned posts.
+func getPinned(ctx context.Context, client *mastodon.Client) (interface{}, error) {
+	account, err := client.VerifyCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.PinnedStatuses(ctx, account.ID)
+}
 
-	// Strip all remaining tags
-	var b strings.Builder
-	inTag := false
-	for _, ch := range s {
-		switch {
-		case ch == '<':
-			inTag = true
-		case ch == '>':
-			inTag = false
-		case !inTag:
-			b.WriteRune(ch)
+// getFollowGraph lists the followers of, or accounts followed by, ref.
+func getFollowGraph(ctx context.Context, client *mastodon.Client, ref, command string) (interface{}, error) {
+	account, err := accountOrSelf(ctx, client, ref)
+	if err != nil {
+		return nil, err
+	}
+	if command == "followers" {
+		return client.Followers(ctx, account.ID, pageOptions())
+	}
+	return client.Following(ctx, account.ID, pageOptions())
+}
+
+// AccountRelationship pairs an account with the user's relationship to it.
+type AccountRelationship struct {
+	Account      mastodon.Account      `json:"account"`
+	Relationship mastodon.Relationship `json:"relationship"`
+}
+
+// getRelationships resolves each ref and fetches all relationships in one
+// batch.
+func getRelationships(ctx context.Context, client *mastodon.Client, refs []string) (interface{}, error) {
+	accounts := make([]mastodon.Account, 0, len(refs))
+	ids := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		account, err := client.ResolveAccount(ctx, ref)
+		if err != nil {
+			return nil, err
 		}
+		accounts = append(accounts, *account)
+		ids = append(ids, account.ID)
+	}
+	rels, err := client.Relationships(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]mastodon.Relationship, len(rels))
+	for _, r := range rels {
+		byID[r.ID] = r
+	}
+	result := make([]AccountRelationship, len(accounts))
+	for i, a := range accounts {
+		result[i] = AccountRelationship{Account: a, Relationship: byID[a.ID]}
 	}
+	return result, nil
+}
+
+// accountActions maps CLI commands to the client method that performs them.
+var accountActions = map[string]func(*mastodon.Client, context.Context, string) (*mastodon.Relationship, error){
+	"follow":   (*mastodon.Client).Follow,
+	"unfollow": (*mastodon.Client).Unfollow,
+	"mute": func(c *mastodon.Client, ctx context.Context, id string) (*mastodon.Relationship, error) {
+		return c.Mute(ctx, id, mastodon.MuteOptions{})
+	},
+	"unmute":    (*mastodon.Client).Unmute,
+	"block":     (*mastodon.Client).Block,
+	"unblock":   (*mastodon.Client).Unblock,
+	"endorse":   (*mastodon.Client).Endorse,
+	"unendorse": (*mastodon.Client).Unendorse,
+}
 
-	return html.UnescapeString(b.String())
+// accountAction resolves ref (via WebFinger for remote accounts) and applies
+// the command's action to it.
+func accountAction(ctx context.Context, client *mastodon.Client, ref, command string) (interface{}, error) {
+	account, err := client.ResolveAccount(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if command == "block" {
+		var cancel context.CancelFunc
+		ctx, cancel, err = confirmRequest(ctx, fmt.Sprintf("Block @%s? They will be unfollowed and can no longer follow you or see your posts", account.Acct))
+		if err != nil {
+			return nil, err
+		}
+		defer cancel()
+	}
+	rel, err := accountActions[command](client, ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	return AccountRelationship{Account: *account, Relationship: *rel}, nil
+}
+
+// DomainBlockResult reports a domain-block or domain-unblock.
+type DomainBlockResult struct {
+	Domain  string `json:"domain"`
+	Blocked bool   `json:"blocked"`
+}
+
+func blockDomain(ctx context.Context, client *mastodon.Client, ref string, block bool) (interface{}, error) {
+	domain, err := mastodon.NormalizeDomain(ref)
+	if err != nil {
+		return nil, err
+	}
+	action := client.UnblockDomain
+	if block {
+		var cancel context.CancelFunc
+		ctx, cancel, err = confirmRequest(ctx, fmt.Sprintf("Block everything from %s? You will lose any followers you have there", domain))
+		if err != nil {
+			return nil, err
+		}
+		defer cancel()
+		action = client.BlockDomain
+	}
+	if err := action(ctx, domain); err != nil {
+		return nil, err
+	}
+	return DomainBlockResult{Domain: domain, Blocked: block}, nil
+}
+
+// statusActions maps CLI commands to the client method that performs them.
+var statusActions = map[string]func(*mastodon.Client, context.Context, string) (*mastodon.Status, error){
+	"boost":      (*mastodon.Client).Reblog,
+	"unboost":    (*mastodon.Client).Unreblog,
+	"fav":        (*mastodon.Client).Favourite,
+	"unfav":      (*mastodon.Client).Unfavourite,
+	"bookmark":   (*mastodon.Client).Bookmark,
+	"unbookmark": (*mastodon.Client).Unbookmark,
+	"pin":        (*mastodon.Client).Pin,
+	"unpin":      (*mastodon.Client).Unpin,
+}
+
+// statusAction resolves ref and applies the command's action to it,
+// returning the status the server responds with.
+func statusAction(ctx context.Context, client *mastodon.Client, ref, command string) (interface{}, error) {
+	id, err := client.ResolveStatusID(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	status, err := statusActions[command](client, ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return *status, nil
 }
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// docsCommand generates documentation from the command table. It is added
+// to the table in init, since it reads the table itself.
+var docsCommand = &command{
+	Name: "docs", Args: "man <dir>", Summary: "Generate man pages from the command help",
+	Help:    "Writes mastodon-scout.1 and a mastodon-scout-<command>.1 page per command to <dir>. SOURCE_DATE_EPOCH, if set, dates the pages for reproducible builds.",
+	MinArgs: 2, Requires: "man and a directory",
+	Subcommands: true, NoAuth: true,
+	Examples: []string{
+		"docs man dist/man",
+		"--json docs man /usr/local/share/man/man1",
+	},
+	Run: func(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+		if args[0] != "man" {
+			return nil, fmt.Errorf("unknown docs format %q (only man is supported)", args[0])
+		}
+		return writeManPages(args[1])
+	},
+}
+
+func init() {
+	commands = append(commands, docsCommand)
+}
+
+// ManPages is the result of docs man.
+type ManPages struct {
+	Dir   string   `json:"dir"`
+	Files []string `json:"files"`
+}
+
+// manExitStatus explains the exit statuses, in order.
+var manExitStatus = []struct{ code, meaning string }{
+	{codeError, "Anything not listed below."},
+	{codeUsage, "Unknown command, bad flag, or missing arguments."},
+	{codeUnauthorized, "No token, or the instance rejected it (401/403)."},
+	{codeNotFound, "The post, account, or list doesn't exist (404/410)."},
+	{codeRateLimited, "The instance is throttling requests (429)."},
+	{codeTimeout, "The request timed out."},
+	{codeServerError, "The instance failed (5xx)."},
+	{codeInterrupted, "Stopped by SIGINT or SIGTERM, after printing what had been fetched."},
+}
+
+// manEnvironment lists the environment variables mastodon-scout reads.
+var manEnvironment = []struct{ name, meaning string }{
+	{"MASTODON_TOKEN", "Access token, used when neither --token, --token-file, nor --account is given, for the instance of --instance or MASTODON_INSTANCE rather than the default account's."},
+	{"MASTODON_INSTANCE", "Instance URL, used when neither --instance nor an account gives one."},
+	{"MASTODON_LIMIT, MASTODON_OUTPUT, MASTODON_TIMEZONE, MASTODON_COLOR", "Defaults for --limit, --output, --timezone, and --color; they beat the [defaults] table of config.toml."},
+	{"TZ", "Time zone of local timestamps, unless --timezone or a timezone setting is given."},
+	{"MASTODON_WEBHOOK_SECRET", "Secret for signing webhook payloads, used when --webhook-secret is not given."},
+	{"XDG_CONFIG_HOME", "Base directory of the configuration; defaults to ~/.config."},
+	{"XDG_STATE_HOME", "Base directory of the --watch state; defaults to ~/.local/state."},
+	{"XDG_DATA_HOME", "Base directory of the --archive database; defaults to ~/.local/share."},
+	{"VISUAL, EDITOR", "Editor for composing posts."},
+	{"NO_COLOR", "Turns off colored output under --color=auto."},
+	{"SOURCE_DATE_EPOCH", "Date for the pages written by docs man."},
+}
+
+// manFiles lists the files mastodon-scout keeps.
+var manFiles = []struct{ path, meaning string }{
+	{"$XDG_CONFIG_HOME/mastodon-scout/config.toml", "Accounts, aliases, groups of instances for --instances, the color theme, and [defaults] for instance, limit, output, timezone, and color; see config."},
+	{"$XDG_CONFIG_HOME/mastodon-scout/credentials.json", "Tokens stored by login when no keyring is available."},
+	{"$XDG_STATE_HOME/mastodon-scout/watch.json", "What --watch has already shown."},
+	{"$XDG_DATA_HOME/mastodon-scout/archive.db", "Posts, accounts, and notifications stored by --archive; see archive."},
+}
+
+// writeManPages writes mastodon-scout.1 and a page per command to dir.
+func writeManPages(dir string) (ManPages, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return ManPages{}, err
+	}
+	date, err := manDate()
+	if err != nil {
+		return ManPages{}, err
+	}
+	pages := map[string]string{"mastodon-scout.1": mainManPage(date)}
+	names := []string{"mastodon-scout.1"}
+	for _, c := range commands {
+		name := "mastodon-scout-" + c.Name + ".1"
+		pages[name] = commandManPage(c, date)
+		names = append(names, name)
+	}
+	result := ManPages{Dir: dir}
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(pages[name]), 0o644); err != nil {
+			return result, err
+		}
+		result.Files = append(result.Files, path)
+	}
+	return result, nil
+}
+
+// manDate is the date in the page footers: SOURCE_DATE_EPOCH when it is
+// set, so that packaged pages are reproducible, and otherwise today.
+func manDate() (string, error) {
+	t := time.Now()
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		secs, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return "", errors.New("SOURCE_DATE_EPOCH must be a Unix time")
+		}
+		t = time.Unix(secs, 0)
+	}
+	return t.UTC().Format("January 2006"), nil
+}
+
+// mainManPage renders mastodon-scout(1): the commands, the global flags,
+// and what the whole program shares.
+func mainManPage(date string) string {
+	var b strings.Builder
+	manHeader(&b, "mastodon-scout", date)
+	b.WriteString(".SH NAME\nmastodon\\-scout \\- read and post to Mastodon from the command line\n")
+	b.WriteString(".SH SYNOPSIS\n.B mastodon\\-scout\n.I command\n.RI [ args ]\n.RI [ flags ]\n")
+	b.WriteString(".SH DESCRIPTION\n")
+	b.WriteString("mastodon\\-scout fetches timelines, notifications, and search results from a Mastodon instance and can publish posts. ")
+	b.WriteString("It prints human\\-readable summaries by default, or the API's JSON with \\fB\\-\\-json\\fR.\n")
+	b.WriteString(".PP\nFlags may appear anywhere on the line. Aliases from the [alias] table in config.toml expand like git aliases.\n")
+	b.WriteString(".SH COMMANDS\n")
+	for _, c := range commands {
+		fmt.Fprintf(&b, ".TP\n.B %s\n%s\n", roffCode(strings.TrimSpace(c.Name+" "+c.Args)), roffText(c.Summary))
+	}
+	b.WriteString(".TP\n.BI help \" command\"\nShow help for a command\n")
+	b.WriteString(".SH OPTIONS\n")
+	manFlags(&b, globalFlags)
+	b.WriteString(".SH ENVIRONMENT\n")
+	for _, env := range manEnvironment {
+		fmt.Fprintf(&b, ".TP\n.B %s\n%s\n", env.name, roffText(env.meaning))
+	}
+	b.WriteString(".SH FILES\n")
+	for _, f := range manFiles {
+		fmt.Fprintf(&b, ".TP\n.I %s\n%s\n", roffCode(f.path), roffText(f.meaning))
+	}
+	b.WriteString(".SH EXIT STATUS\n")
+	b.WriteString("Errors are printed to stderr, or with \\fB\\-\\-json\\fR as an envelope on stdout whose code names the class of failure.\n")
+	fmt.Fprintf(&b, ".TP\n.B 0\nSuccess.\n")
+	for _, s := range manExitStatus {
+		fmt.Fprintf(&b, ".TP\n.BR %d \" (%s)\"\n%s\n", exitCodes[s.code], s.code, roffText(s.meaning))
+	}
+	b.WriteString(".SH SEE ALSO\n")
+	for i, c := range commands {
+		sep := ","
+		if i == len(commands)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(&b, ".BR mastodon\\-scout\\-%s (1)%s\n", roffCode(c.Name), sep)
+	}
+	return b.String()
+}
+
+// commandManPage renders mastodon-scout-<command>(1).
+func commandManPage(c *command, date string) string {
+	var b strings.Builder
+	manHeader(&b, "mastodon-scout-"+c.Name, date)
+	fmt.Fprintf(&b, ".SH NAME\nmastodon\\-scout\\-%s \\- %s\n", roffCode(c.Name), roffText(c.Summary))
+	fmt.Fprintf(&b, ".SH SYNOPSIS\n.B mastodon\\-scout %s\n", roffCode(c.Name))
+	if c.Args != "" {
+		fmt.Fprintf(&b, ".I %s\n", roffCode(c.Args))
+	}
+	b.WriteString(".RI [ flags ]\n")
+	b.WriteString(".SH DESCRIPTION\n")
+	fmt.Fprintf(&b, "%s.\n", roffText(strings.TrimSuffix(c.Summary, ".")))
+	if len(c.Aliases) > 0 {
+		fmt.Fprintf(&b, ".PP\nAliases: %s.\n", roffCode(strings.Join(c.Aliases, ", ")))
+	}
+	if c.Help != "" {
+		manHelp(&b, c.Help)
+	}
+	if len(c.Flags) > 0 {
+		b.WriteString(".SH OPTIONS\n")
+		manFlags(&b, c.Flags)
+		b.WriteString(".PP\nThe global flags are described in\n.BR mastodon\\-scout (1).\n")
+	}
+	if len(c.Examples) > 0 {
+		b.WriteString(".SH EXAMPLES\n")
+		for _, example := range c.Examples {
+			fmt.Fprintf(&b, ".PP\n.RS 4\n.EX\nmastodon\\-scout %s\n.EE\n.RE\n", roffCode(example))
+		}
+	}
+	b.WriteString(".SH SEE ALSO\n.BR mastodon\\-scout (1)\n")
+	return b.String()
+}
+
+// manHelp renders a command's Help: indented lines, the tables of actions,
+// keep their layout, and the rest is filled as prose.
+func manHelp(b *strings.Builder, help string) {
+	table := false
+	for _, line := range strings.Split(help, "\n") {
+		indented := strings.HasPrefix(line, " ")
+		switch {
+		case indented && !table:
+			b.WriteString(".PP\n.nf\n")
+		case !indented && table:
+			b.WriteString(".fi\n")
+		}
+		if !indented {
+			b.WriteString(".PP\n")
+		}
+		table = indented
+		b.WriteString(roffText(line) + "\n")
+	}
+	if table {
+		b.WriteString(".fi\n")
+	}
+}
+
+func manHeader(b *strings.Builder, name, date string) {
+	fmt.Fprintf(b, ".TH %s 1 \"%s\" \"mastodon-scout %s\" \"User Commands\"\n", roffCode(strings.ToUpper(name)), date, buildVersion())
+}
+
+// manFlags describes the named flags the way flag.PrintDefaults does.
+func manFlags(b *strings.Builder, names []string) {
+	for _, name := range names {
+		f := flag.Lookup(name)
+		arg, usage := flag.UnquoteUsage(f)
+		if arg == "" {
+			fmt.Fprintf(b, ".TP\n.B \\-\\-%s\n", roffCode(f.Name))
+		} else {
+			fmt.Fprintf(b, ".TP\n.BI \\-\\-%s \" %s\"\n", roffCode(f.Name), arg)
+		}
+		if f.DefValue != "" && f.DefValue != "0" && f.DefValue != "false" && f.DefValue != "0s" {
+			usage += fmt.Sprintf(" (default %s)", f.DefValue)
+		}
+		b.WriteString(roffText(usage) + "\n")
+	}
+}
+
+// roffText escapes prose for roff: backslashes, and the leading dot or
+// quote that would make a line a request.
+func roffText(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
+
+// roffCode escapes text meant to be typed, where a hyphen must stay a
+// hyphen rather than become a dash.
+func roffCode(s string) string {
+	return strings.ReplaceAll(roffText(s), "-", `\-`)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// writeMarkdown renders the posts in data as Markdown on stdout, one section
+// per post, for pasting into notes or assembling digests.
+func writeMarkdown(data interface{}) error {
+	statuses, ok := resultStatuses(data)
+	if !ok {
+		return fmt.Errorf("markdown output needs a command that returns posts")
+	}
+	var b strings.Builder
+	for i, s := range statuses {
+		if i > 0 {
+			b.WriteString("\n---\n\n")
+		}
+		markdownStatus(&b, s)
+	}
+	_, err := os.Stdout.WriteString(b.String())
+	return err
+}
+
+func markdownStatus(b *strings.Builder, s mastodon.Status) {
+	post, boostedBy := resolvePost(s)
+	if boostedBy != "" {
+		fmt.Fprintf(b, "🔁 @%s boosted\n\n", boostedBy)
+	}
+	author := post.Account.DisplayName
+	if author == "" {
+		author = post.Account.Username
+	}
+	fmt.Fprintf(b, "**%s** [@%s](%s)", markdownEscape(author), post.Account.Acct, post.Account.URL)
+	// Saved documents outlive "3h ago", so the default here is local time.
+	when := post.CreatedAt
+	if flagWasSet("timestamps") {
+		when = formatTime(when)
+	} else if t, err := time.Parse(time.RFC3339, when); err == nil {
+		when = t.Local().Format("2006-01-02 15:04 MST")
+	}
+	if post.URL != "" {
+		fmt.Fprintf(b, " · [%s](%s)", when, post.URL)
+	} else {
+		fmt.Fprintf(b, " · %s", when)
+	}
+	b.WriteString("\n\n")
+
+	if post.SpoilerText != "" {
+		fmt.Fprintf(b, "> **CW: %s**\n>\n", markdownEscape(post.SpoilerText))
+	}
+	for _, line := range strings.Split(mastodon.PlainText(post.Content), "\n") {
+		if line == "" {
+			b.WriteString(">\n")
+		} else {
+			fmt.Fprintf(b, "> %s\n", line)
+		}
+	}
+	b.WriteString("\n")
+
+	if post.Poll != nil {
+		for _, o := range post.Poll.Options {
+			votes := 0
+			if o.VotesCount != nil {
+				votes = *o.VotesCount
+			}
+			fmt.Fprintf(b, "- %s (%d votes)\n", markdownEscape(o.Title), votes)
+		}
+		b.WriteString("\n")
+	}
+	for _, m := range post.MediaAttachments {
+		alt := ""
+		if m.Description != nil {
+			alt = *m.Description
+		}
+		if m.Type == "image" || m.Type == "gifv" {
+			fmt.Fprintf(b, "![%s](%s)\n", markdownEscape(alt), m.URL)
+		} else {
+			label := m.Type
+			if alt != "" {
+				label += ": " + alt
+			}
+			fmt.Fprintf(b, "[%s](%s)\n", markdownEscape(label), m.URL)
+		}
+	}
+	if len(post.MediaAttachments) > 0 {
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(b, "💬 %d · 🔁 %d · ⭐ %d\n", post.RepliesCount, post.ReblogsCount, post.FavouritesCount)
+}
+
+// markdownEscape backslash-escapes the characters that would otherwise start
+// emphasis or links in short inline text such as names and alt text.
+func markdownEscape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`,
+	).Replace(s)
+}
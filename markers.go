@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// markerTimelines are the timelines Mastodon keeps read markers for.
+var markerTimelines = []string{mastodon.MarkerHome, mastodon.MarkerNotifications}
+
+// TimelineMarker is a read marker together with its timeline.
+type TimelineMarker struct {
+	Timeline string `json:"timeline"`
+	mastodon.Marker
+}
+
+// runMarkers handles "markers get [timeline...]" and
+// "markers set <timeline> [id]". Without an ID, set marks everything
+// up to the newest item as read.
+func runMarkers(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+	sub, rest := "get", args
+	if len(args) > 0 {
+		sub, rest = args[0], args[1:]
+	}
+	switch sub {
+	case "get":
+		timelines := markerTimelines
+		if len(rest) > 0 {
+			timelines = rest
+		}
+		for _, t := range timelines {
+			if err := validateMarkerTimeline(t); err != nil {
+				return nil, err
+			}
+		}
+		markers, err := client.Markers(ctx, timelines...)
+		if err != nil {
+			return nil, err
+		}
+		result := []TimelineMarker{}
+		for _, t := range timelines {
+			if m, ok := markers[t]; ok {
+				result = append(result, TimelineMarker{Timeline: t, Marker: m})
+			}
+		}
+		return result, nil
+	case "set":
+		if len(rest) == 0 {
+			return nil, errors.New("markers set requires a timeline: home or notifications")
+		}
+		timeline := rest[0]
+		if err := validateMarkerTimeline(timeline); err != nil {
+			return nil, err
+		}
+		id := optionalArg(rest[1:])
+		if id == "" {
+			var err error
+			if id, err = newestID(ctx, client, timeline); err != nil {
+				return nil, err
+			}
+		}
+		marker, err := client.SetMarker(ctx, timeline, id)
+		if err != nil {
+			return nil, err
+		}
+		return TimelineMarker{Timeline: timeline, Marker: *marker}, nil
+	default:
+		return nil, fmt.Errorf("unknown markers subcommand: %s", sub)
+	}
+}
+
+func validateMarkerTimeline(timeline string) error {
+	for _, t := range markerTimelines {
+		if timeline == t {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid timeline %q (want home or notifications)", timeline)
+}
+
+// newestID is the ID of the newest post or notification in timeline.
+func newestID(ctx context.Context, client *mastodon.Client, timeline string) (string, error) {
+	one := mastodon.PageOptions{Limit: 1}
+	var id string
+	if timeline == mastodon.MarkerHome {
+		statuses, err := client.HomeTimeline(ctx, one)
+		if err != nil {
+			return "", err
+		}
+		if len(statuses) > 0 {
+			id = statuses[0].ID
+		}
+	} else {
+		notifications, err := client.Notifications(ctx, mastodon.NotificationFilter{}, one)
+		if err != nil {
+			return "", err
+		}
+		if len(notifications) > 0 {
+			id = notifications[0].ID
+		}
+	}
+	if id == "" {
+		return "", fmt.Errorf("your %s timeline is empty; pass an ID", timeline)
+	}
+	return id, nil
+}
+
+// unreadHomeOptions starts opts after the home marker for --unread, unless
+// they already have a cursor. Without a marker nothing changes.
+func unreadHomeOptions(ctx context.Context, client *mastodon.Client, opts mastodon.PageOptions) (mastodon.PageOptions, error) {
+	if !*flagUnread || opts.SinceID != "" || opts.MinID != "" || opts.MaxID != "" {
+		return opts, nil
+	}
+	markers, err := client.Markers(ctx, mastodon.MarkerHome)
+	if err != nil {
+		return opts, fmt.Errorf("reading the home marker: %w", err)
+	}
+	if m, ok := markers[mastodon.MarkerHome]; ok {
+		opts.MinID = m.LastReadID
+	}
+	return opts, nil
+}
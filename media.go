@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// uploadMedia uploads each file in order. The nth --alt and --focus values
+// apply to the nth file; with --require-alt-text every file needs an --alt
+// before any is uploaded. Images are resized and cleaned first as
+// --max-pixels and --strip-exif ask.
+func uploadMedia(ctx context.Context, client *mastodon.Client, files []string) ([]mastodon.MediaAttachment, error) {
+	if len(files) == 0 {
+		return nil, errors.New("upload requires at least one file")
+	}
+	if len(flagAlt) > len(files) || len(flagFocus) > len(files) {
+		return nil, fmt.Errorf("got more --alt/--focus values than files (%d)", len(files))
+	}
+	if *flagRequireAlt {
+		for i, name := range files {
+			if i >= len(flagAlt) || strings.TrimSpace(flagAlt[i]) == "" {
+				return nil, fmt.Errorf("%s has no alt text; describe it with --alt (or pass --require-alt-text=false)", name)
+			}
+		}
+	}
+	media := make([]mastodon.MediaAttachment, 0, len(files))
+	for i, name := range files {
+		params := mastodon.MediaParams{}
+		if i < len(flagAlt) {
+			params.Description = flagAlt[i]
+		}
+		if i < len(flagFocus) {
+			focus, err := mastodon.ParseFocus(flagFocus[i])
+			if err != nil {
+				return nil, err
+			}
+			params.Focus = focus
+		}
+		m, err := uploadFile(ctx, client, name, params)
+		if err != nil {
+			return nil, err
+		}
+		media = append(media, *m)
+	}
+	return media, nil
+}
+
+func uploadFile(ctx context.Context, client *mastodon.Client, name string, params mastodon.MediaParams) (*mastodon.MediaAttachment, error) {
+	data, filename, err := prepareImage(name)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		params.File = mastodon.Upload{Filename: filename, Content: bytes.NewReader(data)}
+	} else {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		params.File = mastodon.Upload{Filename: name, Content: f}
+	}
+	m, err := client.UploadMedia(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("uploading %s: %w", name, err)
+	}
+	return m, nil
+}
+
+// mediaFiles splits the --media flag into file names.
+func mediaFiles() []string {
+	var files []string
+	for _, f := range strings.Split(*flagMedia, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// attachMedia uploads the --media files, if any, and returns their IDs.
+func attachMedia(ctx context.Context, client *mastodon.Client) ([]string, error) {
+	files := mediaFiles()
+	if len(files) == 0 {
+		return nil, nil
+	}
+	media, err := uploadMedia(ctx, client, files)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(media))
+	for i, m := range media {
+		ids[i] = m.ID
+	}
+	return ids, nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// metricsExporter polls an account and renders what it saw in the
+// Prometheus text exposition format for /metrics.
+type metricsExporter struct {
+	client *mastodon.Client
+
+	// notificationsSince is the newest notification counted so far; the
+	// first poll only records it, so counters start at exporter start.
+	notificationsSince string
+	notifications      map[string]int
+
+	mu   sync.Mutex
+	page []byte
+}
+
+// runMetrics serves /metrics on --listen and refreshes the numbers every
+// --interval until interrupted.
+func runMetrics(ctx context.Context, client *mastodon.Client) error {
+	interval := *flagInterval
+	if interval < minWatchInterval {
+		return fmt.Errorf("--interval must be at least %s", minWatchInterval)
+	}
+	ln, err := net.Listen("tcp", *flagListen)
+	if err != nil {
+		return err
+	}
+	e := &metricsExporter{client: client, notifications: map[string]int{}}
+	e.poll(ctx)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", e.serveMetrics)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, "mastodon-scout metrics exporter: see /metrics")
+	})
+	served := make(chan error, 1)
+	go func() { served <- http.Serve(ln, mux) }()
+	notef("Serving metrics on http://%s/metrics\n", ln.Addr())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-served:
+			return err
+		case <-ctx.Done():
+			ln.Close()
+			return nil
+		case <-ticker.C:
+			e.poll(ctx)
+		}
+	}
+}
+
+func (e *metricsExporter) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	page := e.page
+	e.mu.Unlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(page)
+}
+
+// poll fetches the account, its notifications, and the rate limit, and
+// replaces the page /metrics serves. Failures are reported through
+// mastodon_scout_up rather than ending the exporter.
+func (e *metricsExporter) poll(parent context.Context) {
+	ctx, cancel := withTimeout(parent)
+	defer cancel()
+	start := time.Now()
+	var m metricsPage
+
+	account, err := e.client.VerifyCredentials(ctx)
+	if err != nil {
+		notef("Warning: polling account: %v\n", err)
+		m.gauge("mastodon_scout_up", "Whether the last poll of the account succeeded.", "", 0)
+	} else {
+		acct := fmt.Sprintf(`account=%q`, account.Acct+"@"+instanceHost(*flagInstanceURL))
+		m.gauge("mastodon_scout_up", "Whether the last poll of the account succeeded.", "", 1)
+		m.gauge("mastodon_account_followers", "Accounts following the account.", acct, float64(account.FollowersCount))
+		m.gauge("mastodon_account_following", "Accounts the account follows.", acct, float64(account.FollowingCount))
+		m.gauge("mastodon_account_statuses", "Posts the account has published.", acct, float64(account.StatusesCount))
+		m.gauge("mastodon_scout_last_success_timestamp_seconds", "When a poll last succeeded.", "", float64(time.Now().Unix()))
+	}
+
+	if unread, err := e.client.UnreadNotificationCount(ctx); err == nil {
+		m.gauge("mastodon_notifications_unread", "Notifications not yet read.", "", float64(unread))
+	}
+	e.countNotifications(ctx)
+	types := make([]string, 0, len(e.notifications))
+	for t := range e.notifications {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		m.sample("mastodon_notifications_total", "Notifications received since the exporter started, by type.", "counter",
+			fmt.Sprintf(`type=%q`, t), float64(e.notifications[t]))
+	}
+
+	if rl, ok := e.client.RateLimit(); ok {
+		m.gauge("mastodon_ratelimit_limit", "Requests allowed per rate-limit window.", "", float64(rl.Limit))
+		m.gauge("mastodon_ratelimit_remaining", "Requests left in the current rate-limit window.", "", float64(rl.Remaining))
+		m.gauge("mastodon_ratelimit_reset_timestamp_seconds", "When the rate-limit window resets.", "", float64(rl.Reset.Unix()))
+	}
+	m.gauge("mastodon_scout_poll_duration_seconds", "How long the last poll took.", "", time.Since(start).Seconds())
+
+	e.mu.Lock()
+	e.page = []byte(m.String())
+	e.mu.Unlock()
+}
+
+// countNotifications adds the notifications that arrived since the last
+// poll to the per-type counters.
+func (e *metricsExporter) countNotifications(ctx context.Context) {
+	opts := mastodon.PageOptions{Limit: 80, SinceID: e.notificationsSince}
+	if e.notificationsSince == "" {
+		opts.Limit = 1
+	}
+	list, err := e.client.Notifications(ctx, mastodon.NotificationFilter{}, opts)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			notef("Warning: polling notifications: %v\n", err)
+		}
+		return
+	}
+	first := e.notificationsSince == ""
+	newest := e.notificationsSince
+	for _, n := range list {
+		if compareIDs(n.ID, e.notificationsSince) <= 0 {
+			continue
+		}
+		if !first {
+			e.notifications[n.Type]++
+		}
+		if compareIDs(n.ID, newest) > 0 {
+			newest = n.ID
+		}
+	}
+	e.notificationsSince = newest
+	if first {
+		// Register the counters at zero so rate() works from the start.
+		for _, t := range mastodon.NotificationTypes {
+			e.notifications[t] = 0
+		}
+	}
+}
+
+// metricsPage accumulates metric families in exposition format.
+type metricsPage struct {
+	b    strings.Builder
+	seen map[string]bool
+}
+
+func (m *metricsPage) gauge(name, help, labels string, value float64) {
+	m.sample(name, help, "gauge", labels, value)
+}
+
+// sample writes one sample, preceded by the family's HELP and TYPE lines
+// the first time the family appears.
+func (m *metricsPage) sample(name, help, kind, labels string, value float64) {
+	if m.seen == nil {
+		m.seen = map[string]bool{}
+	}
+	if !m.seen[name] {
+		m.seen[name] = true
+		fmt.Fprintf(&m.b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+	m.b.WriteString(name)
+	if labels != "" {
+		m.b.WriteString("{" + labels + "}")
+	}
+	m.b.WriteString(" " + strconv.FormatFloat(value, 'f', -1, 64) + "\n")
+}
+
+func (m *metricsPage) String() string { return m.b.String() }
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// outputFormat returns the effective --output value; --json is shorthand for
+// --output json.
+func outputFormat() string {
+	if *flagJSON {
+		return "json"
+	}
+	return *flagOutput
+}
+
+// outputTemplate is the parsed --format template, or nil without one.
+var outputTemplate *template.Template
+
+// templateFuncs are available to --format templates on top of the model
+// fields and methods such as .ContentText.
+var templateFuncs = template.FuncMap{
+	"plain": mastodon.PlainText,
+	"join":  strings.Join,
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+var errFieldsFormat = errors.New("--fields works with --output json, ndjson, csv, or tsv")
+
+// validateOutputFormat checks the output flags and parses --format.
+func validateOutputFormat() error {
+	switch *flagTimestamps {
+	case "relative", "local", "utc", "iso":
+	default:
+		return fmt.Errorf("invalid --timestamps %q (want relative, local, utc, or iso)", *flagTimestamps)
+	}
+	if *flagFormat != "" {
+		if outputFormat() != "text" {
+			return errors.New("--format can't be combined with --output")
+		}
+		if *flagOutFields != "" {
+			return errFieldsFormat
+		}
+		tmpl, err := template.New("format").Funcs(templateFuncs).Parse(*flagFormat)
+		if err != nil {
+			return fmt.Errorf("parsing --format: %w", err)
+		}
+		outputTemplate = tmpl
+		return nil
+	}
+	switch outputFormat() {
+	case "json", "ndjson", "csv", "tsv":
+		return nil
+	case "text", "rss", "atom", "markdown":
+		if *flagOutFields != "" {
+			return errFieldsFormat
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q (want text, json, ndjson, csv, tsv, rss, atom, or markdown)", *flagOutput)
+}
+
+// resultItems flattens a result into the values that become lines of
+// NDJSON or rows of CSV: each element of a list, each status, account, and
+// hashtag of a search, or a single object on its own.
+func resultItems(data interface{}) []interface{} {
+	if result, ok := data.(mastodon.SearchResult); ok {
+		var items []interface{}
+		for _, s := range result.Statuses {
+			items = append(items, s)
+		}
+		for _, a := range result.Accounts {
+			items = append(items, a)
+		}
+		for _, t := range result.Hashtags {
+			items = append(items, t)
+		}
+		return items
+	}
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Slice {
+		return []interface{}{data}
+	}
+	items := make([]interface{}, v.Len())
+	for i := range items {
+		items[i] = v.Index(i).Interface()
+	}
+	return items
+}
+
+// writeNDJSON prints results one JSON value per line, without the success
+// envelope.
+func writeNDJSON(data interface{}) error {
+	items := resultItems(data)
+	if *flagOutFields != "" {
+		pruner := newFieldPruner(*flagOutFields)
+		for i, item := range items {
+			v, err := pruner.prune(item)
+			if err != nil {
+				return err
+			}
+			items[i] = v
+		}
+		if err := pruner.check(len(items)); err != nil {
+			return err
+		}
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	for _, item := range items {
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// itemStream writes the items of a listing as NDJSON while it is being
+// fetched, for --all with --output ndjson, so that a listing of any length
+// is printed without being held in memory.
+type itemStream struct {
+	enc    *json.Encoder
+	pruner *fieldPruner
+	// written counts the items printed, after filtering.
+	written int
+}
+
+// activeStream is the stream this run's listing goes to, or nil.
+var activeStream *itemStream
+
+// configureStream sets up activeStream when cmd's output can be printed as
+// it arrives: a listing fetched with --all as NDJSON, with nothing that
+// needs every item first, such as --sort or --instances, or that works on
+// the result, such as --download-media.
+func configureStream(cmd *command) {
+	if !cmd.Listing || !*flagAll || outputFormat() != "ndjson" || outputTemplate != nil ||
+		*flagSort != "" || *flagInstances != "" || *flagDownloadMedia != "" || flagWatch > 0 {
+		return
+	}
+	activeStream = &itemStream{enc: json.NewEncoder(os.Stdout)}
+	activeStream.enc.SetEscapeHTML(false)
+	if *flagOutFields != "" {
+		activeStream.pruner = newFieldPruner(*flagOutFields)
+	}
+}
+
+// write prints one item, unless the post filter drops it.
+func (s *itemStream) write(item interface{}) error {
+	archiveItems(item)
+	if !activeFilter.keepItem(item) {
+		return nil
+	}
+	if s.pruner != nil {
+		v, err := s.pruner.prune(item)
+		if err != nil {
+			return err
+		}
+		item = v
+	}
+	s.written++
+	return s.enc.Encode(item)
+}
+
+// finish checks, once the listing is done, that every --fields path
+// matched something.
+func (s *itemStream) finish() error {
+	if s.pruner == nil {
+		return nil
+	}
+	return s.pruner.check(s.written)
+}
+
+// writeTemplate renders each item with the --format template, ending every
+// rendering with a newline if the template doesn't.
+func writeTemplate(data interface{}) error {
+	var buf bytes.Buffer
+	for _, item := range resultItems(data) {
+		buf.Reset()
+		if err := outputTemplate.Execute(&buf, item); err != nil {
+			return fmt.Errorf("executing --format: %w", err)
+		}
+		if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+			buf.WriteByte('\n')
+		}
+		if _, err := os.Stdout.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// column is one CSV field: its header and the dot path of the JSON value it
+// holds, such as account.acct. An empty path is the value itself.
+type column struct {
+	header, path string
+}
+
+func pathColumns(paths ...string) []column {
+	cols := make([]column, len(paths))
+	for i, p := range paths {
+		cols[i] = column{p, p}
+	}
+	return cols
+}
+
+// defaultColumns are the CSV fields for each kind of result when --fields is
+// not given.
+var defaultColumns = map[reflect.Type][]column{
+	reflect.TypeOf(mastodon.Status{}): pathColumns(
+		"id", "created_at", "account.acct", "url", "content",
+		"replies_count", "reblogs_count", "favourites_count",
+	),
+	reflect.TypeOf(mastodon.Notification{}): pathColumns(
+		"id", "type", "created_at", "account.acct", "status.id", "status.url", "status.content",
+	),
+	reflect.TypeOf(mastodon.Account{}): pathColumns(
+		"id", "acct", "display_name", "url", "followers_count", "following_count",
+		"statuses_count", "created_at", "last_status_at", "locked", "bot",
+	),
+	reflect.TypeOf(mastodon.Tag{}):          pathColumns("name", "url"),
+	reflect.TypeOf(mastodon.Conversation{}): pathColumns("id", "unread", "last_status.id", "last_status.account.acct", "last_status.content"),
+	reflect.TypeOf(AccountRelationship{}):   relationshipCSVColumns(),
+	reflect.TypeOf(""):                      {{"domain", ""}},
+}
+
+func relationshipCSVColumns() []column {
+	cols := []column{{"id", "account.id"}, {"acct", "account.acct"}}
+	for _, c := range relationshipColumns {
+		cols = append(cols, column{c.name, "relationship." + c.name})
+	}
+	return cols
+}
+
+// parseFields splits a --fields list into columns.
+func parseFields(list string) []column {
+	var paths []string
+	for _, f := range strings.Split(list, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			paths = append(paths, f)
+		}
+	}
+	return pathColumns(paths...)
+}
+
+// writeCSV renders results as CSV, or TSV when sep is a tab, on stdout. Each
+// item becomes a row holding the --fields columns, or the defaults for its
+// kind.
+func writeCSV(data interface{}, sep rune) error {
+	items := resultItems(data)
+	cols := parseFields(*flagOutFields)
+	if len(cols) == 0 {
+		cols = defaultColumns[resultType(data, items)]
+		if len(cols) == 0 {
+			return fmt.Errorf("%s output needs --fields for this command", outputFormat())
+		}
+	}
+
+	rows := make([][]string, len(items))
+	found := make([]bool, len(cols))
+	for i, item := range items {
+		v, err := jsonValue(item)
+		if err != nil {
+			return err
+		}
+		row := make([]string, len(cols))
+		for j, c := range cols {
+			value, ok := lookupPath(v, c.path)
+			if ok {
+				found[j] = true
+				row[j] = csvCell(c.path, value)
+			}
+		}
+		rows[i] = row
+	}
+	for j, ok := range found {
+		if !ok && len(items) > 0 {
+			return fmt.Errorf("unknown field %q", cols[j].path)
+		}
+	}
+
+	w := csv.NewWriter(os.Stdout)
+	w.Comma = sep
+	header := make([]string, len(cols))
+	for j, c := range cols {
+		header[j] = c.header
+	}
+	w.Write(header)
+	w.WriteAll(rows)
+	return w.Error()
+}
+
+// resultType is the kind of item a result holds, which picks its default
+// columns even when there are no items: the element type of a list, the
+// type --type searched for, or the type of a single result.
+func resultType(data interface{}, items []interface{}) reflect.Type {
+	if _, ok := data.(mastodon.SearchResult); ok {
+		switch *flagSearchType {
+		case mastodon.SearchAccounts:
+			return reflect.TypeOf(mastodon.Account{})
+		case mastodon.SearchHashtags:
+			return reflect.TypeOf(mastodon.Tag{})
+		}
+		return reflect.TypeOf(mastodon.Status{})
+	}
+	t := reflect.TypeOf(data)
+	if t == nil {
+		return nil
+	}
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Interface && len(items) > 0 {
+		t = reflect.TypeOf(items[0])
+	}
+	return t
+}
+
+// lookupPath walks a decoded JSON value along a dot path. Numeric segments
+// index arrays, so media_attachments.0.url is the first attachment's URL.
+func lookupPath(v interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return v, true
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			next, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			v = next
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 {
+				return nil, false
+			}
+			if i >= len(node) {
+				return nil, true
+			}
+			v = node[i]
+		case nil:
+			// A null parent, such as the status of a follow notification,
+			// leaves the field empty.
+			return nil, true
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// csvCell formats a JSON value for a spreadsheet cell. HTML content and bios
+// are reduced to plain text; objects and arrays stay JSON.
+func csvCell(path string, v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		if name := path[strings.LastIndex(path, ".")+1:]; name == "content" || name == "note" {
+			return mastodon.PlainText(v)
+		}
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
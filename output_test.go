@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+func TestPrintResultFormats(t *testing.T) {
+	home := runCommand(t, "home").([]mastodon.Status)[:3]
+
+	tests := []struct {
+		output string
+		data   interface{}
+		check  func(t *testing.T, out string)
+	}{
+		{"text", home, func(t *testing.T, out string) {
+			for _, want := range []string{"--- Post 1 ---", "@alice (Alice)", "Post 999 & more", "🔗 https://example.social/@alice/999", "--- Post 3 ---"} {
+				if !strings.Contains(out, want) {
+					t.Errorf("output lacks %q:\n%s", want, out)
+				}
+			}
+		}},
+		{"json", home, func(t *testing.T, out string) {
+			var resp struct {
+				Success bool              `json:"success"`
+				Data    []mastodon.Status `json:"data"`
+			}
+			if err := json.Unmarshal([]byte(out), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if !resp.Success || len(resp.Data) != 3 || resp.Data[0].ID != "999" {
+				t.Errorf("got %s, want the success envelope around 3 posts", out)
+			}
+		}},
+		{"ndjson", home, func(t *testing.T, out string) {
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			if len(lines) != 3 {
+				t.Fatalf("got %d lines, want 3:\n%s", len(lines), out)
+			}
+			for _, line := range lines {
+				var s mastodon.Status
+				if err := json.Unmarshal([]byte(line), &s); err != nil || s.ID == "" {
+					t.Errorf("line %q is not a post: %v", line, err)
+				}
+			}
+		}},
+		{"csv", home, func(t *testing.T, out string) {
+			rows, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != 4 {
+				t.Fatalf("got %d rows, want a header and 3 posts:\n%s", len(rows), out)
+			}
+			if got := strings.Join(rows[0], ","); got != "id,created_at,account.acct,url,content,replies_count,reblogs_count,favourites_count" {
+				t.Errorf("header = %s", got)
+			}
+			if rows[1][0] != "999" || rows[1][4] != "Post 999 & more" {
+				t.Errorf("first row = %v, want post 999 as plain text", rows[1])
+			}
+		}},
+		{"csv", []mastodon.Account{}, func(t *testing.T, out string) {
+			if !strings.HasPrefix(out, "id,acct,display_name,") || strings.Count(out, "\n") != 1 {
+				t.Errorf("got %q, want just the account header", out)
+			}
+		}},
+		{"tsv", home, func(t *testing.T, out string) {
+			if !strings.HasPrefix(out, "id\tcreated_at\t") || strings.Count(out, "\n") != 4 {
+				t.Errorf("got %q, want tab-separated rows", out)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.output, func(t *testing.T) {
+			setFlags(t, map[string]string{"output": tt.output})
+			out := captureStdout(t, func() { printResult("home", "Home", tt.data) })
+			tt.check(t, out)
+		})
+	}
+}
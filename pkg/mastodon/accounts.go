@@ -0,0 +1,161 @@
+package mastodon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// VerifyCredentials returns the account that owns the client's token.
+func (c *Client) VerifyCredentials(ctx context.Context) (*Account, error) {
+	var account Account
+	if err := c.get(ctx, "/api/v1/accounts/verify_credentials", &account); err != nil {
+		return nil, err
+	}
+	if account.ID == "" {
+		return nil, errors.New("account ID not found")
+	}
+	return &account, nil
+}
+
+// GetAccount fetches an account by its local ID.
+func (c *Client) GetAccount(ctx context.Context, id string) (*Account, error) {
+	var account Account
+	if err := c.get(ctx, "/api/v1/accounts/"+url.PathEscape(id), &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// ResolveAccount finds an account from a reference such as "@user@instance",
+// "user@instance", a profile URL, or a bare local username. Remote accounts
+// are resolved via WebFinger (search with resolve=true) so they get a local
+// ID on this instance.
+func (c *Client) ResolveAccount(ctx context.Context, ref string) (*Account, error) {
+	acct := strings.TrimPrefix(strings.TrimSpace(ref), "@")
+	if acct == "" {
+		return nil, errors.New("empty account reference")
+	}
+	if !IsURL(acct) && !strings.Contains(acct, "@") {
+		var account Account
+		if err := c.get(ctx, "/api/v1/accounts/lookup?acct="+url.QueryEscape(acct), &account); err != nil {
+			return nil, fmt.Errorf("looking up @%s: %w", acct, err)
+		}
+		return &account, nil
+	}
+
+	var result SearchResult
+	path := fmt.Sprintf("/api/v2/search?q=%s&type=accounts&resolve=true&limit=5", url.QueryEscape(acct))
+	if err := c.get(ctx, path, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Accounts) == 0 {
+		return nil, notFoundf("no account found for %s", ref)
+	}
+	if IsURL(acct) {
+		for i, a := range result.Accounts {
+			if strings.EqualFold(a.URL, acct) {
+				return &result.Accounts[i], nil
+			}
+		}
+		return &result.Accounts[0], nil
+	}
+	// Local accounts come back without a domain in acct.
+	host := ""
+	if u, err := url.Parse(c.baseURL); err == nil {
+		host = u.Hostname()
+	}
+	for i, a := range result.Accounts {
+		if strings.EqualFold(a.Acct, acct) || strings.EqualFold(a.Acct+"@"+host, acct) {
+			return &result.Accounts[i], nil
+		}
+	}
+	return nil, notFoundf("no account found for %s", ref)
+}
+
+// accountAction POSTs to /api/v1/accounts/:id/<action> and returns the
+// updated relationship.
+func (c *Client) accountAction(ctx context.Context, id, action string, form url.Values) (*Relationship, error) {
+	var rel Relationship
+	if err := c.post(ctx, fmt.Sprintf("/api/v1/accounts/%s/%s", url.PathEscape(id), action), form, &rel); err != nil {
+		return nil, err
+	}
+	return &rel, nil
+}
+
+// Follow follows an account. For locked accounts the relationship comes back
+// with Requested set instead of Following.
+func (c *Client) Follow(ctx context.Context, id string) (*Relationship, error) {
+	return c.accountAction(ctx, id, "follow", nil)
+}
+
+// FollowOptions tunes a follow. The zero value shows the account's boosts,
+// doesn't notify about its posts, and lets through posts in any language.
+type FollowOptions struct {
+	// HideReblogs keeps the account's boosts out of the home timeline.
+	HideReblogs bool
+	// Notify sends a notification whenever the account posts.
+	Notify bool
+	// Languages limits the account's posts in the home timeline to these
+	// ISO 639 codes; empty means all.
+	Languages []string
+}
+
+// FollowWithOptions follows an account with opts. Following an account
+// again updates the options of the existing follow.
+func (c *Client) FollowWithOptions(ctx context.Context, id string, opts FollowOptions) (*Relationship, error) {
+	form := url.Values{}
+	form.Set("reblogs", strconv.FormatBool(!opts.HideReblogs))
+	form.Set("notify", strconv.FormatBool(opts.Notify))
+	for _, lang := range opts.Languages {
+		form.Add("languages[]", lang)
+	}
+	return c.accountAction(ctx, id, "follow", form)
+}
+
+// Unfollow unfollows an account or withdraws a follow request.
+func (c *Client) Unfollow(ctx context.Context, id string) (*Relationship, error) {
+	return c.accountAction(ctx, id, "unfollow", nil)
+}
+
+// Endorse features an account the user follows on their profile.
+func (c *Client) Endorse(ctx context.Context, id string) (*Relationship, error) {
+	return c.accountAction(ctx, id, "pin", nil)
+}
+
+// Unendorse stops featuring an account on the user's profile.
+func (c *Client) Unendorse(ctx context.Context, id string) (*Relationship, error) {
+	return c.accountAction(ctx, id, "unpin", nil)
+}
+
+// Endorsements lists the accounts the user features on their profile.
+func (c *Client) Endorsements(ctx context.Context, opts PageOptions) ([]Account, error) {
+	return Paginate[Account](ctx, c, "/api/v1/endorsements", opts)
+}
+
+// Relationships returns the user's relationship with each of the given
+// accounts in one request.
+func (c *Client) Relationships(ctx context.Context, ids []string) ([]Relationship, error) {
+	q := url.Values{}
+	for _, id := range ids {
+		q.Add("id[]", id)
+	}
+	var rels []Relationship
+	if err := c.get(ctx, "/api/v1/accounts/relationships?"+q.Encode(), &rels); err != nil {
+		return nil, err
+	}
+	return rels, nil
+}
+
+// Followers lists accounts following the account with the given ID.
+func (c *Client) Followers(ctx context.Context, id string, opts PageOptions) ([]Account, error) {
+	return Paginate[Account](ctx, c, fmt.Sprintf("/api/v1/accounts/%s/followers", url.PathEscape(id)), opts)
+}
+
+// Following lists accounts the account with the given ID follows.
+func (c *Client) Following(ctx context.Context, id string, opts PageOptions) ([]Account, error) {
+	return Paginate[Account](ctx, c, fmt.Sprintf("/api/v1/accounts/%s/following", url.PathEscape(id)), opts)
+}
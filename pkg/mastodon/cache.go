@@ -0,0 +1,111 @@
+package mastodon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cache stores GET responses by key so they can be revalidated with
+// If-None-Match instead of downloaded again.
+type Cache interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry)
+}
+
+// CacheEntry is a cached response and the ETag it was served with.
+type CacheEntry struct {
+	ETag   string      `json:"etag"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// WithCache makes the client send conditional GET requests for responses it
+// has cached and reuse the cached body when the server answers 304 Not
+// Modified. Only responses that carry an ETag are cached.
+func WithCache(cache Cache) Option {
+	return func(c *Client) {
+		c.cache = cache
+	}
+}
+
+// cacheKey identifies a GET of path for this client's instance and token.
+// A hash of the token is part of the key so that accounts never see each
+// other's entries.
+func (c *Client) cacheKey(path string) string {
+	sum := sha256.Sum256([]byte(c.token))
+	return c.baseURL + path + " " + hex.EncodeToString(sum[:8])
+}
+
+// DirCache is a Cache that keeps one file per entry in a directory. Entries
+// hold private data such as timelines, so the files are readable only by
+// their owner.
+type DirCache struct {
+	dir string
+}
+
+// NewDirCache returns a DirCache in dir, creating the directory if needed.
+func NewDirCache(dir string) (*DirCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+	return &DirCache{dir: dir}, nil
+}
+
+func (d *DirCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:]))
+}
+
+// Get returns the entry stored under key, if any.
+func (d *DirCache) Get(key string) (*CacheEntry, bool) {
+	data, err := os.ReadFile(d.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// Set stores entry under key. Failures are ignored: the cache only saves
+// bandwidth.
+func (d *DirCache) Set(key string, entry *CacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	// Write and rename so a concurrent reader never sees a partial entry.
+	tmp, err := os.CreateTemp(d.dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, werr := tmp.Write(data)
+	cerr := tmp.Close()
+	if werr != nil || cerr != nil || os.Rename(tmp.Name(), d.path(key)) != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// Prune removes entries that have not been written for maxAge.
+func (d *DirCache) Prune(maxAge time.Duration) error {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-maxAge)
+	for _, e := range entries {
+		info, err := e.Info()
+		if err == nil && info.ModTime().Before(cutoff) {
+			os.Remove(filepath.Join(d.dir, e.Name()))
+		}
+	}
+	return nil
+}
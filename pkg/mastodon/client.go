@@ -0,0 +1,294 @@
+// Package mastodon is a small client for the Mastodon REST and streaming
+// APIs. It backs the mastodon-scout CLI and can be used on its own:
+//
+//	client := mastodon.NewClient("https://mastodon.social", token)
+//	statuses, err := client.HomeTimeline(ctx, mastodon.PageOptions{Limit: 40})
+package mastodon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client talks to a single Mastodon instance on behalf of one access token.
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+	userAgent  string
+	retries    int
+	cache      Cache
+	// concurrency is set by WithConcurrency; 0 means DefaultConcurrency.
+	concurrency    int
+	requestTimeout time.Duration
+	// streamTimeouts bound opening a stream, which bypasses httpClient.
+	streamTimeouts Timeouts
+
+	mu        sync.Mutex
+	rateLimit *RateLimit
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient injects the http.Client used for every request. By default
+// all clients share one built by NewHTTPClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		c.userAgent = ua
+	}
+}
+
+// WithRequestTimeout bounds each attempt at a request, reading the response
+// included, on top of any deadline the context carries. It suits long runs
+// of requests, such as a backup, that have no overall deadline but
+// shouldn't hang on a single request either.
+func WithRequestTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.requestTimeout = d
+	}
+}
+
+// NewClient returns a Client for the instance at baseURL. An empty token
+// makes anonymous requests, which many instances allow for public data.
+func NewClient(baseURL, token string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:        strings.TrimSuffix(baseURL, "/"),
+		token:          token,
+		httpClient:     defaultHTTPClient,
+		streamTimeouts: Timeouts{Connect: DefaultConnectTimeout},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// BaseURL returns the instance URL the client was created with.
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// Token returns the access token the client authenticates with.
+func (c *Client) Token() string {
+	return c.token
+}
+
+// Response is a successful API response.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// APIError is returned for any non-2xx response.
+type APIError struct {
+	StatusCode int
+	Header     http.Header
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Body)
+}
+
+// Errors that API failures match with errors.Is, by class.
+var (
+	// ErrUnauthorized is a 401 or 403: a missing, revoked, or
+	// under-scoped token, or an action the account may not take.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrNotFound is a 404 or 410, or a reference nothing resolved to.
+	ErrNotFound = errors.New("not found")
+	// ErrRateLimited is a 429.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrServer is a 5xx.
+	ErrServer = errors.New("server error")
+)
+
+// Is matches the error class of the response's status code.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound || e.StatusCode == http.StatusGone
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// notFoundError is a reference that didn't resolve to anything.
+type notFoundError struct {
+	msg string
+}
+
+func notFoundf(format string, args ...interface{}) error {
+	return &notFoundError{fmt.Sprintf(format, args...)}
+}
+
+func (e *notFoundError) Error() string        { return e.msg }
+func (e *notFoundError) Is(target error) bool { return target == ErrNotFound }
+
+// Do performs an API call against path (which may include a query string).
+// Non-nil form values are sent as an application/x-www-form-urlencoded body.
+func (c *Client) Do(ctx context.Context, method, path string, form url.Values) (*Response, error) {
+	return c.doHeader(ctx, method, path, form, nil)
+}
+
+// DoJSON performs an API call with a JSON request body, for endpoints that
+// take nested parameters a form can't express.
+func (c *Client) DoJSON(ctx context.Context, method, path string, body []byte) (*Response, error) {
+	return c.send(ctx, method, path, nil, bytes.NewReader(body), "application/json")
+}
+
+// doHeader is Do with extra request headers.
+func (c *Client) doHeader(ctx context.Context, method, path string, form url.Values, header http.Header) (*Response, error) {
+	if form == nil {
+		return c.send(ctx, method, path, header, nil, "")
+	}
+	return c.send(ctx, method, path, header, strings.NewReader(form.Encode()), "application/x-www-form-urlencoded")
+}
+
+// send performs a request with an arbitrary body and any extra headers,
+// retrying as configured by WithRetries.
+func (c *Client) send(ctx context.Context, method, path string, header http.Header, body io.Reader, contentType string) (*Response, error) {
+	// The body is buffered so that a retry can send it again.
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = io.ReadAll(body); err != nil {
+			return nil, fmt.Errorf("reading request body: %w", err)
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		resp, respHeader, err := c.sendOnce(ctx, method, path, header, payload, contentType)
+		if err == nil || attempt >= c.retries || !retryable(repeatable(method, header), err) {
+			return resp, err
+		}
+		if !sleepContext(ctx, retryDelay(attempt, respHeader, time.Now())) {
+			return nil, err
+		}
+	}
+}
+
+// sendOnce makes a single attempt at a request. It returns the response
+// headers alongside any error so a retry can honor them.
+func (c *Client) sendOnce(ctx context.Context, method, path string, header http.Header, payload []byte, contentType string) (*Response, http.Header, error) {
+	if c.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.requestTimeout)
+		defer cancel()
+	}
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating request: %w", err)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	var cached *CacheEntry
+	if c.cache != nil && method == http.MethodGet {
+		if entry, ok := c.cache.Get(c.cacheKey(path)); ok {
+			cached = entry
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+	c.recordRateLimit(resp.Header)
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.Header, fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		// The cached headers describe the body (Link cursors included);
+		// the fresh ones carry the current rate limit.
+		header := cached.Header.Clone()
+		for k, v := range resp.Header {
+			header[k] = v
+		}
+		return &Response{StatusCode: http.StatusOK, Header: header, Body: cached.Body}, header, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, resp.Header, &APIError{StatusCode: resp.StatusCode, Header: resp.Header, Body: string(respBody)}
+	}
+
+	if etag := resp.Header.Get("ETag"); etag != "" && c.cache != nil && method == http.MethodGet {
+		c.cache.Set(c.cacheKey(path), &CacheEntry{ETag: etag, Header: resp.Header, Body: respBody})
+	}
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: respBody}, resp.Header, nil
+}
+
+// call performs a request and decodes the JSON response into v (if non-nil).
+func (c *Client) call(ctx context.Context, method, path string, form url.Values, v interface{}) error {
+	resp, err := c.Do(ctx, method, path, form)
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Body, v); err != nil {
+		return fmt.Errorf("parsing response: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) get(ctx context.Context, path string, v interface{}) error {
+	return c.call(ctx, http.MethodGet, path, nil, v)
+}
+
+func (c *Client) post(ctx context.Context, path string, form url.Values, v interface{}) error {
+	return c.call(ctx, http.MethodPost, path, form, v)
+}
+
+// WithQuery sets key=value in the query string of path.
+func WithQuery(path, key, value string) string {
+	u, err := url.Parse(path)
+	if err != nil {
+		return path
+	}
+	q := u.Query()
+	q.Set(key, value)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
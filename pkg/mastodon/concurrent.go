@@ -0,0 +1,114 @@
+package mastodon
+
+import "context"
+
+// DefaultConcurrency is how many requests a fetch that can be split into
+// independent pages has in flight at once, unless WithConcurrency says
+// otherwise.
+const DefaultConcurrency = 4
+
+// WithConcurrency sets how many requests FetchPages, and the listings paged
+// by offset, send at once; 1 fetches one page at a time. Listings paged by
+// Link headers are always fetched in turn, since each page's cursor comes
+// from the one before.
+func WithConcurrency(n int) Option {
+	return func(c *Client) {
+		c.concurrency = max(n, 1)
+	}
+}
+
+// workers returns how many pages to fetch at once: the configured number,
+// or one once the rate limit runs low, so that throttle can pace them.
+func (c *Client) workers() int {
+	if rl, ok := c.RateLimit(); ok && rl.Low() {
+		return 1
+	}
+	if c.concurrency == 0 {
+		return DefaultConcurrency
+	}
+	return c.concurrency
+}
+
+// FetchPages calls fetch for pages 0, 1, 2, and so on, up to the client's
+// concurrency at a time, and returns their items in page order. fetch
+// reports whether pages after this one may hold more; pages past the first
+// that doesn't are discarded. pages caps the number of pages; 0 goes on
+// until the end. Like Paginate, it returns the items of the pages
+// before a failed one along with the error, and it spaces requests out
+// once the rate limit runs low.
+func FetchPages[T any](ctx context.Context, c *Client, pages int, fetch func(ctx context.Context, page int) (items []T, more bool, err error)) ([]T, error) {
+	items := []T{}
+	err := fetchPages(ctx, c, pages, fetch, func(page []T) error {
+		items = append(items, page...)
+		return nil
+	})
+	return items, err
+}
+
+// fetchPages is FetchPages handing each page's items to emit as soon as
+// the pages before it have been, rather than collecting them. An error from
+// emit stops the fetch like a failed page.
+func fetchPages[T any](ctx context.Context, c *Client, pages int, fetch func(ctx context.Context, page int) ([]T, bool, error), emit func(items []T) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		page  int
+		items []T
+		more  bool
+		err   error
+	}
+	results := make(chan result)
+	start := func(page int) {
+		go func() {
+			if page > 0 {
+				if err := c.throttle(ctx); err != nil {
+					results <- result{page: page, err: err}
+					return
+				}
+			}
+			items, more, err := fetch(ctx, page)
+			results <- result{page, items, more, err}
+		}()
+	}
+
+	var err error
+	// Pages finish in any order; done holds them until their turn.
+	done := make(map[int]result)
+	next, emitted, inFlight := 0, 0, 0
+	last := pages - 1 // the last page, or -1 until it is known
+	for {
+		for err == nil && inFlight < c.workers() && (last < 0 || next <= last) {
+			start(next)
+			next++
+			inFlight++
+		}
+		if inFlight == 0 {
+			break
+		}
+		r := <-results
+		inFlight--
+		done[r.page] = r
+		for err == nil && (last < 0 || emitted <= last) {
+			r, ok := done[emitted]
+			if !ok {
+				break
+			}
+			delete(done, emitted)
+			emitted++
+			if r.err == nil {
+				r.err = emit(r.items)
+			}
+			if r.err != nil {
+				// Stop starting pages and cancel the ones in flight.
+				err = r.err
+				cancel()
+				break
+			}
+			if !r.more {
+				last = r.page
+			}
+		}
+	}
+	return err
+}
@@ -0,0 +1,21 @@
+package mastodon
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// Conversations returns the user's direct-message conversations.
+func (c *Client) Conversations(ctx context.Context, opts PageOptions) ([]Conversation, error) {
+	return Paginate[Conversation](ctx, c, "/api/v1/conversations", opts)
+}
+
+// MarkConversationRead clears the unread flag on a conversation.
+func (c *Client) MarkConversationRead(ctx context.Context, id string) (*Conversation, error) {
+	var conv Conversation
+	if err := c.post(ctx, fmt.Sprintf("/api/v1/conversations/%s/read", url.PathEscape(id)), nil, &conv); err != nil {
+		return nil, err
+	}
+	return &conv, nil
+}
@@ -0,0 +1,125 @@
+package mastodon
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FilterContexts are the places a filter can apply.
+var FilterContexts = []string{"home", "notifications", "public", "thread", "account"}
+
+// FilterParams describes a filter to create or the changes to make to one.
+// Empty fields are left unchanged on update.
+type FilterParams struct {
+	Title   string
+	Context []string
+	// Action is "warn" (hide behind a warning), "hide", or "blur" (media only).
+	Action string
+	// ExpiresIn sets when the filter expires; a pointer to 0 removes the
+	// expiry.
+	ExpiresIn *time.Duration
+	Keywords  []FilterKeywordParams
+}
+
+// FilterKeywordParams adds a keyword, or with ID updates or (with Destroy)
+// removes an existing one.
+type FilterKeywordParams struct {
+	ID        string
+	Keyword   string
+	WholeWord bool
+	Destroy   bool
+}
+
+// ValidateFilterContext reports whether every entry is a known filter
+// context.
+func ValidateFilterContext(contexts []string) error {
+	for _, c := range contexts {
+		known := false
+		for _, k := range FilterContexts {
+			known = known || c == k
+		}
+		if !known {
+			return fmt.Errorf("invalid filter context %q (want %s)", c, strings.Join(FilterContexts, ", "))
+		}
+	}
+	return nil
+}
+
+func (p FilterParams) form() url.Values {
+	form := url.Values{}
+	if p.Title != "" {
+		form.Set("title", p.Title)
+	}
+	for _, c := range p.Context {
+		form.Add("context[]", c)
+	}
+	if p.Action != "" {
+		form.Set("filter_action", p.Action)
+	}
+	if p.ExpiresIn != nil {
+		if *p.ExpiresIn > 0 {
+			form.Set("expires_in", strconv.Itoa(int(*p.ExpiresIn/time.Second)))
+		} else {
+			form.Set("expires_in", "")
+		}
+	}
+	for i, k := range p.Keywords {
+		key := func(field string) string { return fmt.Sprintf("keywords_attributes[%d][%s]", i, field) }
+		if k.ID != "" {
+			form.Set(key("id"), k.ID)
+		}
+		if k.Destroy {
+			form.Set(key("_destroy"), "true")
+			continue
+		}
+		form.Set(key("keyword"), k.Keyword)
+		form.Set(key("whole_word"), strconv.FormatBool(k.WholeWord))
+	}
+	return form
+}
+
+// Filters lists the user's filters.
+func (c *Client) Filters(ctx context.Context) ([]Filter, error) {
+	var filters []Filter
+	if err := c.get(ctx, "/api/v2/filters", &filters); err != nil {
+		return nil, err
+	}
+	return filters, nil
+}
+
+// GetFilter fetches a filter by ID.
+func (c *Client) GetFilter(ctx context.Context, id string) (*Filter, error) {
+	var filter Filter
+	if err := c.get(ctx, "/api/v2/filters/"+url.PathEscape(id), &filter); err != nil {
+		return nil, err
+	}
+	return &filter, nil
+}
+
+// CreateFilter creates a filter. Title and Context are required.
+func (c *Client) CreateFilter(ctx context.Context, p FilterParams) (*Filter, error) {
+	var filter Filter
+	if err := c.post(ctx, "/api/v2/filters", p.form(), &filter); err != nil {
+		return nil, err
+	}
+	return &filter, nil
+}
+
+// UpdateFilter changes a filter.
+func (c *Client) UpdateFilter(ctx context.Context, id string, p FilterParams) (*Filter, error) {
+	var filter Filter
+	if err := c.call(ctx, http.MethodPut, "/api/v2/filters/"+url.PathEscape(id), p.form(), &filter); err != nil {
+		return nil, err
+	}
+	return &filter, nil
+}
+
+// DeleteFilter deletes a filter.
+func (c *Client) DeleteFilter(ctx context.Context, id string) error {
+	return c.call(ctx, http.MethodDelete, "/api/v2/filters/"+url.PathEscape(id), nil, nil)
+}
@@ -0,0 +1,65 @@
+package mastodon
+
+import "context"
+
+// Instance returns information about the server. It does not require
+// authentication.
+func (c *Client) Instance(ctx context.Context) (*Instance, error) {
+	var instance Instance
+	if err := c.get(ctx, "/api/v2/instance", &instance); err != nil {
+		return nil, err
+	}
+	return &instance, nil
+}
+
+// InstancePeers returns the domains this server has seen in the fediverse.
+// Some servers disable this endpoint.
+func (c *Client) InstancePeers(ctx context.Context) ([]string, error) {
+	var peers []string
+	if err := c.get(ctx, "/api/v1/instance/peers", &peers); err != nil {
+		return nil, err
+	}
+	return peers, nil
+}
+
+// InstanceActivity returns weekly activity for the last three months, most
+// recent week first.
+func (c *Client) InstanceActivity(ctx context.Context) ([]InstanceActivity, error) {
+	var activity []InstanceActivity
+	if err := c.get(ctx, "/api/v1/instance/activity", &activity); err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
+// InstanceRules returns the server's rules.
+func (c *Client) InstanceRules(ctx context.Context) ([]Rule, error) {
+	var rules []Rule
+	if err := c.get(ctx, "/api/v1/instance/rules", &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// InstanceStats returns the server's totals of users, posts, and known
+// domains, which only version 1 of the instance endpoint reports.
+func (c *Client) InstanceStats(ctx context.Context) (*InstanceStats, error) {
+	var v1 struct {
+		Stats InstanceStats `json:"stats"`
+	}
+	if err := c.get(ctx, "/api/v1/instance", &v1); err != nil {
+		return nil, err
+	}
+	return &v1.Stats, nil
+}
+
+// InstanceDomainBlocks returns the servers this one limits or suspends. Most
+// servers only publish the list to their users, or not at all, and answer
+// 401 or 404.
+func (c *Client) InstanceDomainBlocks(ctx context.Context) ([]InstanceDomainBlock, error) {
+	var blocks []InstanceDomainBlock
+	if err := c.get(ctx, "/api/v1/instance/domain_blocks", &blocks); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
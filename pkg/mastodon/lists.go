@@ -0,0 +1,89 @@
+package mastodon
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Lists returns all of the user's lists.
+func (c *Client) Lists(ctx context.Context) ([]List, error) {
+	var lists []List
+	if err := c.get(ctx, "/api/v1/lists", &lists); err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
+
+// FindList returns the list whose ID or title (case-insensitive) is ref.
+func (c *Client) FindList(ctx context.Context, ref string) (*List, error) {
+	lists, err := c.Lists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, l := range lists {
+		if l.ID == ref {
+			return &lists[i], nil
+		}
+	}
+	for i, l := range lists {
+		if strings.EqualFold(l.Title, ref) {
+			return &lists[i], nil
+		}
+	}
+	return nil, notFoundf("no list found for %q", ref)
+}
+
+// CreateList creates a list with the given title.
+func (c *Client) CreateList(ctx context.Context, title string) (*List, error) {
+	var list List
+	if err := c.post(ctx, "/api/v1/lists", url.Values{"title": {title}}, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// RenameList changes a list's title.
+func (c *Client) RenameList(ctx context.Context, id, title string) (*List, error) {
+	var list List
+	if err := c.call(ctx, http.MethodPut, "/api/v1/lists/"+url.PathEscape(id), url.Values{"title": {title}}, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// DeleteList deletes a list.
+func (c *Client) DeleteList(ctx context.Context, id string) error {
+	return c.call(ctx, http.MethodDelete, "/api/v1/lists/"+url.PathEscape(id), nil, nil)
+}
+
+// ListAccounts returns the members of a list.
+func (c *Client) ListAccounts(ctx context.Context, id string, opts PageOptions) ([]Account, error) {
+	return Paginate[Account](ctx, c, fmt.Sprintf("/api/v1/lists/%s/accounts", url.PathEscape(id)), opts)
+}
+
+// AddToList adds accounts to a list. Mastodon only allows adding accounts
+// the user follows.
+func (c *Client) AddToList(ctx context.Context, id string, accountIDs []string) error {
+	return c.post(ctx, fmt.Sprintf("/api/v1/lists/%s/accounts", url.PathEscape(id)), accountIDsForm(accountIDs), nil)
+}
+
+// RemoveFromList removes accounts from a list.
+func (c *Client) RemoveFromList(ctx context.Context, id string, accountIDs []string) error {
+	return c.call(ctx, http.MethodDelete, fmt.Sprintf("/api/v1/lists/%s/accounts", url.PathEscape(id)), accountIDsForm(accountIDs), nil)
+}
+
+// ListTimeline returns statuses from the members of a list.
+func (c *Client) ListTimeline(ctx context.Context, id string, opts PageOptions) ([]Status, error) {
+	return Paginate[Status](ctx, c, "/api/v1/timelines/list/"+url.PathEscape(id), opts)
+}
+
+func accountIDsForm(ids []string) url.Values {
+	form := url.Values{}
+	for _, id := range ids {
+		form.Add("account_ids[]", id)
+	}
+	return form
+}
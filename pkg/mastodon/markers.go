@@ -0,0 +1,42 @@
+package mastodon
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// The timelines Mastodon keeps read markers for.
+const (
+	MarkerHome          = "home"
+	MarkerNotifications = "notifications"
+)
+
+// Markers returns the user's read positions in the given timelines, keyed
+// by timeline. Timelines that were never marked are missing from the map.
+func (c *Client) Markers(ctx context.Context, timelines ...string) (map[string]Marker, error) {
+	q := url.Values{}
+	for _, t := range timelines {
+		q.Add("timeline[]", t)
+	}
+	markers := map[string]Marker{}
+	if err := c.get(ctx, "/api/v1/markers?"+q.Encode(), &markers); err != nil {
+		return nil, err
+	}
+	return markers, nil
+}
+
+// SetMarker records lastReadID as the read position in timeline, which
+// other clients of the account pick up too.
+func (c *Client) SetMarker(ctx context.Context, timeline, lastReadID string) (*Marker, error) {
+	form := url.Values{timeline + "[last_read_id]": {lastReadID}}
+	var markers map[string]Marker
+	if err := c.post(ctx, "/api/v1/markers", form, &markers); err != nil {
+		return nil, err
+	}
+	marker, ok := markers[timeline]
+	if !ok {
+		return nil, fmt.Errorf("the server didn't save the %s marker", timeline)
+	}
+	return &marker, nil
+}
@@ -0,0 +1,150 @@
+package mastodon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Upload is a file sent as part of a multipart request.
+type Upload struct {
+	Filename string
+	Content  io.Reader
+}
+
+// MediaParams describes a media file to upload.
+type MediaParams struct {
+	File Upload
+	// Description is the alt text shown to screen reader users.
+	Description string
+	// Focus is the focal point used when cropping thumbnails.
+	Focus *MediaFocus
+}
+
+// mediaPollInterval is how often WaitForMedia checks on processing.
+const mediaPollInterval = time.Second
+
+// UploadMedia uploads a file for attaching to a status. Large files are
+// processed asynchronously; UploadMedia waits until processing finishes so
+// the returned attachment is ready to use.
+func (c *Client) UploadMedia(ctx context.Context, p MediaParams) (*MediaAttachment, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	if p.Description != "" {
+		w.WriteField("description", p.Description)
+	}
+	if p.Focus != nil {
+		w.WriteField("focus", p.Focus.String())
+	}
+	if err := writeUpload(w, "file", &p.File); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("encoding media upload: %w", err)
+	}
+
+	var media MediaAttachment
+	resp, err := c.sendMultipart(ctx, http.MethodPost, "/api/v2/media", w, &body, &media)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusAccepted {
+		return c.WaitForMedia(ctx, media.ID)
+	}
+	return &media, nil
+}
+
+// WaitForMedia polls an attachment until the server has finished processing
+// it or ctx is done.
+func (c *Client) WaitForMedia(ctx context.Context, id string) (*MediaAttachment, error) {
+	for {
+		resp, err := c.Do(ctx, http.MethodGet, "/api/v1/media/"+url.PathEscape(id), nil)
+		if err != nil {
+			return nil, err
+		}
+		// 206 Partial Content means the file is still being processed.
+		if resp.StatusCode != http.StatusPartialContent {
+			var media MediaAttachment
+			if err := json.Unmarshal(resp.Body, &media); err != nil {
+				return nil, fmt.Errorf("parsing response: %w", err)
+			}
+			return &media, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for media %s to process: %w", id, ctx.Err())
+		case <-time.After(mediaPollInterval):
+		}
+	}
+}
+
+func (f MediaFocus) String() string {
+	return strconv.FormatFloat(f.X, 'f', -1, 64) + "," + strconv.FormatFloat(f.Y, 'f', -1, 64)
+}
+
+// ParseFocus parses a focal point written as "x,y", each between -1 and 1.
+func ParseFocus(s string) (*MediaFocus, error) {
+	xs, ys, ok := strings.Cut(s, ",")
+	if !ok {
+		return nil, fmt.Errorf("invalid focus %q (want x,y)", s)
+	}
+	x, errX := strconv.ParseFloat(strings.TrimSpace(xs), 64)
+	y, errY := strconv.ParseFloat(strings.TrimSpace(ys), 64)
+	if err := errors.Join(errX, errY); err != nil {
+		return nil, fmt.Errorf("invalid focus %q: %w", s, err)
+	}
+	if x < -1 || x > 1 || y < -1 || y > 1 {
+		return nil, fmt.Errorf("invalid focus %q (x and y must be between -1 and 1)", s)
+	}
+	return &MediaFocus{X: x, Y: y}, nil
+}
+
+// sendMultipart sends the finished multipart body and decodes the JSON
+// response into v.
+func (c *Client) sendMultipart(ctx context.Context, method, path string, w *multipart.Writer, body io.Reader, v interface{}) (*Response, error) {
+	resp, err := c.send(ctx, method, path, nil, body, w.FormDataContentType())
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(resp.Body, v); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	return resp, nil
+}
+
+// writeUpload adds up as a file part, labelled with a content type guessed
+// from the filename (falling back to sniffing the data).
+func writeUpload(w *multipart.Writer, name string, up *Upload) error {
+	if up == nil {
+		return nil
+	}
+	data, err := io.ReadAll(up.Content)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", name, err)
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(up.Filename))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, name, strings.ReplaceAll(filepath.Base(up.Filename), `"`, "")))
+	h.Set("Content-Type", contentType)
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", name, err)
+	}
+	_, err = part.Write(data)
+	return err
+}
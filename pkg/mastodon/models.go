@@ -0,0 +1,447 @@
+package mastodon
+
+// Typed models for the Mastodon REST API entities the CLI reads and writes.
+// Field names follow https://docs.joinmastodon.org/entities/. Nullable
+// attributes are pointers so JSON output preserves null.
+
+// Account represents a Mastodon user account
+type Account struct {
+	ID             string         `json:"id"`
+	Username       string         `json:"username"`
+	Acct           string         `json:"acct"`
+	DisplayName    string         `json:"display_name"`
+	URL            string         `json:"url"`
+	Note           string         `json:"note"`
+	Avatar         string         `json:"avatar"`
+	AvatarStatic   string         `json:"avatar_static"`
+	Header         string         `json:"header"`
+	HeaderStatic   string         `json:"header_static"`
+	Locked         bool           `json:"locked"`
+	Bot            bool           `json:"bot"`
+	Group          bool           `json:"group"`
+	Discoverable   *bool          `json:"discoverable"`
+	CreatedAt      string         `json:"created_at"`
+	LastStatusAt   *string        `json:"last_status_at"`
+	StatusesCount  int            `json:"statuses_count"`
+	FollowersCount int            `json:"followers_count"`
+	FollowingCount int            `json:"following_count"`
+	Fields         []Field        `json:"fields"`
+	Emojis         []CustomEmoji  `json:"emojis"`
+	Moved          *Account       `json:"moved,omitempty"`
+	Source         *AccountSource `json:"source,omitempty"`
+}
+
+// AccountSource holds the plain-text profile data returned by
+// verify_credentials for the authenticated user.
+type AccountSource struct {
+	Note                string  `json:"note"`
+	Fields              []Field `json:"fields"`
+	Privacy             string  `json:"privacy"`
+	Sensitive           bool    `json:"sensitive"`
+	Language            string  `json:"language"`
+	FollowRequestsCount int     `json:"follow_requests_count"`
+}
+
+// Field is a profile metadata key/value pair
+type Field struct {
+	Name       string  `json:"name"`
+	Value      string  `json:"value"`
+	VerifiedAt *string `json:"verified_at"`
+}
+
+// Status represents a Mastodon post
+type Status struct {
+	ID                 string            `json:"id"`
+	URI                string            `json:"uri"`
+	URL                string            `json:"url"`
+	CreatedAt          string            `json:"created_at"`
+	EditedAt           *string           `json:"edited_at"`
+	Account            Account           `json:"account"`
+	Content            string            `json:"content"`
+	Text               string            `json:"text,omitempty"`
+	Visibility         string            `json:"visibility"`
+	Sensitive          bool              `json:"sensitive"`
+	SpoilerText        string            `json:"spoiler_text"`
+	Language           *string           `json:"language"`
+	InReplyToID        *string           `json:"in_reply_to_id"`
+	InReplyToAccountID *string           `json:"in_reply_to_account_id"`
+	RepliesCount       int               `json:"replies_count"`
+	ReblogsCount       int               `json:"reblogs_count"`
+	FavouritesCount    int               `json:"favourites_count"`
+	MediaAttachments   []MediaAttachment `json:"media_attachments"`
+	Mentions           []Mention         `json:"mentions"`
+	Tags               []Tag             `json:"tags"`
+	Emojis             []CustomEmoji     `json:"emojis"`
+	Poll               *Poll             `json:"poll"`
+	Card               *PreviewCard      `json:"card"`
+	Application        *Application      `json:"application,omitempty"`
+	Reblog             *Status           `json:"reblog"`
+	Favourited         bool              `json:"favourited,omitempty"`
+	Reblogged          bool              `json:"reblogged,omitempty"`
+	Muted              bool              `json:"muted,omitempty"`
+	Bookmarked         bool              `json:"bookmarked,omitempty"`
+	Pinned             bool              `json:"pinned,omitempty"`
+}
+
+// MediaAttachment represents an image, video, audio, or GIFV on a status
+type MediaAttachment struct {
+	ID          string     `json:"id"`
+	Type        string     `json:"type"`
+	URL         string     `json:"url"`
+	PreviewURL  string     `json:"preview_url"`
+	RemoteURL   *string    `json:"remote_url"`
+	Description *string    `json:"description"`
+	Blurhash    *string    `json:"blurhash"`
+	Meta        *MediaMeta `json:"meta,omitempty"`
+}
+
+// MediaMeta carries dimensions and the focal point of an attachment
+type MediaMeta struct {
+	Original *MediaDimensions `json:"original,omitempty"`
+	Small    *MediaDimensions `json:"small,omitempty"`
+	Focus    *MediaFocus      `json:"focus,omitempty"`
+}
+
+// MediaDimensions describes one rendition of an attachment
+type MediaDimensions struct {
+	Width    int     `json:"width,omitempty"`
+	Height   int     `json:"height,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+}
+
+// MediaFocus is the focal point, with x and y in the range -1.0 to 1.0
+type MediaFocus struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Poll represents a poll attached to a status
+type Poll struct {
+	ID          string       `json:"id"`
+	ExpiresAt   *string      `json:"expires_at"`
+	Expired     bool         `json:"expired"`
+	Multiple    bool         `json:"multiple"`
+	VotesCount  int          `json:"votes_count"`
+	VotersCount *int         `json:"voters_count"`
+	Options     []PollOption `json:"options"`
+	Voted       bool         `json:"voted,omitempty"`
+	OwnVotes    []int        `json:"own_votes,omitempty"`
+}
+
+// PollOption is one choice in a poll; VotesCount is null while results are
+// hidden
+type PollOption struct {
+	Title      string `json:"title"`
+	VotesCount *int   `json:"votes_count"`
+}
+
+// Mention is an account mentioned in a status
+type Mention struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Acct     string `json:"acct"`
+	URL      string `json:"url"`
+}
+
+// Tag is a hashtag, optionally with daily usage history
+type Tag struct {
+	Name    string       `json:"name"`
+	URL     string       `json:"url"`
+	History []TagHistory `json:"history,omitempty"`
+}
+
+// TagHistory is one day of hashtag usage; the API encodes numbers as strings
+type TagHistory struct {
+	Day      string `json:"day"`
+	Uses     string `json:"uses"`
+	Accounts string `json:"accounts"`
+}
+
+// PreviewCard is the link preview attached to a status
+type PreviewCard struct {
+	URL          string  `json:"url"`
+	Title        string  `json:"title"`
+	Description  string  `json:"description"`
+	Type         string  `json:"type"`
+	AuthorName   string  `json:"author_name"`
+	ProviderName string  `json:"provider_name"`
+	Image        *string `json:"image"`
+}
+
+// TrendingLink is a preview card with daily sharing history
+type TrendingLink struct {
+	PreviewCard
+	History []TagHistory `json:"history"`
+}
+
+// Application is the client that published a status
+type Application struct {
+	Name    string  `json:"name"`
+	Website *string `json:"website"`
+	// Scopes are the OAuth scopes the app's token was granted; only
+	// apps/verify_credentials reports them, on Mastodon 4.3 and later.
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// CustomEmoji is an instance-specific emoji
+type CustomEmoji struct {
+	Shortcode       string `json:"shortcode"`
+	URL             string `json:"url"`
+	StaticURL       string `json:"static_url"`
+	VisibleInPicker bool   `json:"visible_in_picker"`
+}
+
+// Notification represents a Mastodon notification
+type Notification struct {
+	ID        string  `json:"id"`
+	Type      string  `json:"type"`
+	CreatedAt string  `json:"created_at"`
+	Account   Account `json:"account"`
+	Status    *Status `json:"status"`
+}
+
+// Relationship describes how the user relates to another account
+type Relationship struct {
+	ID                  string   `json:"id"`
+	Following           bool     `json:"following"`
+	ShowingReblogs      bool     `json:"showing_reblogs"`
+	Notifying           bool     `json:"notifying"`
+	Languages           []string `json:"languages"`
+	FollowedBy          bool     `json:"followed_by"`
+	Blocking            bool     `json:"blocking"`
+	BlockedBy           bool     `json:"blocked_by"`
+	Muting              bool     `json:"muting"`
+	MutingNotifications bool     `json:"muting_notifications"`
+	Requested           bool     `json:"requested"`
+	RequestedBy         bool     `json:"requested_by"`
+	DomainBlocking      bool     `json:"domain_blocking"`
+	Endorsed            bool     `json:"endorsed"`
+	Note                string   `json:"note"`
+}
+
+// Conversation is a direct-message thread
+type Conversation struct {
+	ID         string    `json:"id"`
+	Unread     bool      `json:"unread"`
+	Accounts   []Account `json:"accounts"`
+	LastStatus *Status   `json:"last_status"`
+}
+
+// SearchResult represents the response from /api/v2/search
+type SearchResult struct {
+	Accounts []Account `json:"accounts"`
+	Statuses []Status  `json:"statuses"`
+	Hashtags []Tag     `json:"hashtags"`
+}
+
+// ScheduledStatus is a status queued for publishing at ScheduledAt
+type ScheduledStatus struct {
+	ID               string                `json:"id"`
+	ScheduledAt      string                `json:"scheduled_at"`
+	Params           ScheduledStatusParams `json:"params"`
+	MediaAttachments []MediaAttachment     `json:"media_attachments"`
+}
+
+// ScheduledStatusParams holds the parameters the status will be posted with
+type ScheduledStatusParams struct {
+	Text          string   `json:"text"`
+	Visibility    string   `json:"visibility"`
+	SpoilerText   *string  `json:"spoiler_text"`
+	InReplyToID   *string  `json:"in_reply_to_id"`
+	Language      *string  `json:"language"`
+	MediaIDs      []string `json:"media_ids"`
+	Sensitive     *bool    `json:"sensitive"`
+	ApplicationID int      `json:"application_id"`
+}
+
+// StatusSource is the plain-text source of a status, used for editing
+type StatusSource struct {
+	ID          string `json:"id"`
+	Text        string `json:"text"`
+	SpoilerText string `json:"spoiler_text"`
+}
+
+// StatusEdit is one revision of a status in its edit history
+type StatusEdit struct {
+	Content          string            `json:"content"`
+	SpoilerText      string            `json:"spoiler_text"`
+	Sensitive        bool              `json:"sensitive"`
+	CreatedAt        string            `json:"created_at"`
+	Account          Account           `json:"account"`
+	Poll             *Poll             `json:"poll,omitempty"`
+	MediaAttachments []MediaAttachment `json:"media_attachments"`
+	Emojis           []CustomEmoji     `json:"emojis"`
+}
+
+// List is a user-curated list of accounts with its own timeline
+type List struct {
+	ID            string `json:"id"`
+	Title         string `json:"title"`
+	RepliesPolicy string `json:"replies_policy"`
+	Exclusive     bool   `json:"exclusive,omitempty"`
+}
+
+// Instance describes a server, as returned by /api/v2/instance
+type Instance struct {
+	Domain        string                `json:"domain"`
+	Title         string                `json:"title"`
+	Version       string                `json:"version"`
+	SourceURL     string                `json:"source_url"`
+	Description   string                `json:"description"`
+	Usage         InstanceUsage         `json:"usage"`
+	Languages     []string              `json:"languages"`
+	Configuration InstanceConfiguration `json:"configuration"`
+	Registrations InstanceRegistrations `json:"registrations"`
+	Contact       InstanceContact       `json:"contact"`
+	Rules         []Rule                `json:"rules"`
+	// MaxTootChars is the character limit on forks that report it here
+	// rather than in Configuration.
+	MaxTootChars int `json:"max_toot_chars"`
+}
+
+// InstanceUsage reports how many people use the server
+type InstanceUsage struct {
+	Users struct {
+		ActiveMonth int `json:"active_month"`
+	} `json:"users"`
+}
+
+// InstanceConfiguration holds the server's limits
+type InstanceConfiguration struct {
+	Statuses struct {
+		MaxCharacters            int `json:"max_characters"`
+		MaxMediaAttachments      int `json:"max_media_attachments"`
+		CharactersReservedPerURL int `json:"characters_reserved_per_url"`
+	} `json:"statuses"`
+	MediaAttachments struct {
+		SupportedMimeTypes  []string `json:"supported_mime_types"`
+		ImageSizeLimit      int64    `json:"image_size_limit"`
+		ImageMatrixLimit    int64    `json:"image_matrix_limit"`
+		VideoSizeLimit      int64    `json:"video_size_limit"`
+		VideoFrameRateLimit int      `json:"video_frame_rate_limit"`
+		VideoMatrixLimit    int64    `json:"video_matrix_limit"`
+	} `json:"media_attachments"`
+	Polls struct {
+		MaxOptions             int `json:"max_options"`
+		MaxCharactersPerOption int `json:"max_characters_per_option"`
+		MinExpiration          int `json:"min_expiration"`
+		MaxExpiration          int `json:"max_expiration"`
+	} `json:"polls"`
+	Translation struct {
+		Enabled bool `json:"enabled"`
+	} `json:"translation"`
+}
+
+// InstanceRegistrations describes whether and how people can sign up
+type InstanceRegistrations struct {
+	Enabled          bool    `json:"enabled"`
+	ApprovalRequired bool    `json:"approval_required"`
+	Message          *string `json:"message"`
+}
+
+// InstanceContact is who to contact about the server
+type InstanceContact struct {
+	Email   string   `json:"email"`
+	Account *Account `json:"account"`
+}
+
+// Rule is one of the server's rules
+type Rule struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+	Hint string `json:"hint,omitempty"`
+}
+
+// InstanceActivity is one week of server activity; the API encodes numbers
+// as strings
+type InstanceActivity struct {
+	Week          string `json:"week"`
+	Statuses      string `json:"statuses"`
+	Logins        string `json:"logins"`
+	Registrations string `json:"registrations"`
+}
+
+// InstanceStats holds the server's totals
+type InstanceStats struct {
+	UserCount   int `json:"user_count"`
+	StatusCount int `json:"status_count"`
+	DomainCount int `json:"domain_count"`
+}
+
+// InstanceDomainBlock is a server that an instance moderates. Domain may be
+// partly obfuscated with asterisks; Severity is "silence" or "suspend".
+type InstanceDomainBlock struct {
+	Domain   string `json:"domain"`
+	Digest   string `json:"digest"`
+	Severity string `json:"severity"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// Filter is a server-side keyword filter (v2)
+type Filter struct {
+	ID           string          `json:"id"`
+	Title        string          `json:"title"`
+	Context      []string        `json:"context"`
+	ExpiresAt    *string         `json:"expires_at"`
+	FilterAction string          `json:"filter_action"`
+	Keywords     []FilterKeyword `json:"keywords"`
+	Statuses     []FilterStatus  `json:"statuses"`
+}
+
+// FilterKeyword is one keyword a filter matches
+type FilterKeyword struct {
+	ID        string `json:"id"`
+	Keyword   string `json:"keyword"`
+	WholeWord bool   `json:"whole_word"`
+}
+
+// FilterStatus is a single status a filter matches
+type FilterStatus struct {
+	ID       string `json:"id"`
+	StatusID string `json:"status_id"`
+}
+
+// Report is a report filed with the moderators
+type Report struct {
+	ID            string   `json:"id"`
+	ActionTaken   bool     `json:"action_taken"`
+	Category      string   `json:"category"`
+	Comment       string   `json:"comment"`
+	Forwarded     bool     `json:"forwarded"`
+	CreatedAt     string   `json:"created_at"`
+	StatusIDs     []string `json:"status_ids"`
+	RuleIDs       []string `json:"rule_ids"`
+	TargetAccount Account  `json:"target_account"`
+}
+
+// Marker is a saved read position in the home or notifications timeline
+type Marker struct {
+	LastReadID string `json:"last_read_id"`
+	Version    int    `json:"version"`
+	UpdatedAt  string `json:"updated_at"`
+}
+
+// Preferences are the user's posting and reading defaults, set in the web
+// interface
+type Preferences struct {
+	PostingVisibility string  `json:"posting:default:visibility"`
+	PostingSensitive  bool    `json:"posting:default:sensitive"`
+	PostingLanguage   *string `json:"posting:default:language"`
+	ReadingMedia      string  `json:"reading:expand:media"`
+	ReadingSpoilers   bool    `json:"reading:expand:spoilers"`
+}
+
+// Translation is a status translated by the instance's translation service
+type Translation struct {
+	Content                string                 `json:"content"`
+	SpoilerText            string                 `json:"spoiler_text"`
+	MediaAttachments       []TranslatedAttachment `json:"media_attachments"`
+	DetectedSourceLanguage string                 `json:"detected_source_language"`
+	Provider               string                 `json:"provider"`
+}
+
+// TranslatedAttachment is the translated alt text of an attachment
+type TranslatedAttachment struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+}
@@ -0,0 +1,95 @@
+package mastodon
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MuteOptions tunes a mute. The zero value mutes notifications too, forever.
+type MuteOptions struct {
+	// KeepNotifications leaves notifications from the account unmuted.
+	KeepNotifications bool
+	// Duration expires the mute after this long; 0 means indefinitely.
+	Duration time.Duration
+}
+
+// Mute hides an account's posts (and, by default, notifications).
+func (c *Client) Mute(ctx context.Context, id string, opts MuteOptions) (*Relationship, error) {
+	form := url.Values{}
+	form.Set("notifications", strconv.FormatBool(!opts.KeepNotifications))
+	if opts.Duration > 0 {
+		form.Set("duration", strconv.Itoa(int(opts.Duration/time.Second)))
+	}
+	return c.accountAction(ctx, id, "mute", form)
+}
+
+// Unmute removes a mute.
+func (c *Client) Unmute(ctx context.Context, id string) (*Relationship, error) {
+	return c.accountAction(ctx, id, "unmute", nil)
+}
+
+// Block blocks an account, which also removes any follow in either direction.
+func (c *Client) Block(ctx context.Context, id string) (*Relationship, error) {
+	return c.accountAction(ctx, id, "block", nil)
+}
+
+// Unblock removes a block.
+func (c *Client) Unblock(ctx context.Context, id string) (*Relationship, error) {
+	return c.accountAction(ctx, id, "unblock", nil)
+}
+
+// Mutes lists the accounts the user has muted.
+func (c *Client) Mutes(ctx context.Context, opts PageOptions) ([]Account, error) {
+	return Paginate[Account](ctx, c, "/api/v1/mutes", opts)
+}
+
+// Blocks lists the accounts the user has blocked.
+func (c *Client) Blocks(ctx context.Context, opts PageOptions) ([]Account, error) {
+	return Paginate[Account](ctx, c, "/api/v1/blocks", opts)
+}
+
+// DomainBlocks lists the domains the user has blocked.
+func (c *Client) DomainBlocks(ctx context.Context, opts PageOptions) ([]string, error) {
+	return Paginate[string](ctx, c, "/api/v1/domain_blocks", opts)
+}
+
+// BlockDomain hides all content from domain and removes followers on it.
+func (c *Client) BlockDomain(ctx context.Context, domain string) error {
+	d, err := NormalizeDomain(domain)
+	if err != nil {
+		return err
+	}
+	return c.post(ctx, "/api/v1/domain_blocks", url.Values{"domain": {d}}, nil)
+}
+
+// UnblockDomain removes a domain block.
+func (c *Client) UnblockDomain(ctx context.Context, domain string) error {
+	d, err := NormalizeDomain(domain)
+	if err != nil {
+		return err
+	}
+	return c.call(ctx, http.MethodDelete, "/api/v1/domain_blocks", url.Values{"domain": {d}}, nil)
+}
+
+// NormalizeDomain reduces a bare host, "@user@host", or URL to a lowercase
+// host name.
+func NormalizeDomain(domain string) (string, error) {
+	d := strings.TrimSpace(domain)
+	if IsURL(d) {
+		if u, err := url.Parse(d); err == nil {
+			d = u.Hostname()
+		}
+	} else if i := strings.LastIndex(d, "@"); i >= 0 {
+		d = d[i+1:]
+	}
+	d = strings.ToLower(strings.TrimSuffix(d, "."))
+	if d == "" {
+		return "", errors.New("empty domain")
+	}
+	return d, nil
+}
@@ -0,0 +1,99 @@
+package mastodon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// OOBRedirectURI is the out-of-band redirect for flows where the user pastes
+// the authorization code by hand.
+const OOBRedirectURI = "urn:ietf:wg:oauth:2.0:oob"
+
+// App is a registered OAuth application.
+type App struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+}
+
+// Token is an OAuth access token.
+type Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	Scope       string `json:"scope"`
+	CreatedAt   int64  `json:"created_at"`
+}
+
+// RegisterApp registers an OAuth application with the instance.
+func (c *Client) RegisterApp(ctx context.Context, name, redirectURI, scopes, website string) (*App, error) {
+	var app App
+	err := c.post(ctx, "/api/v1/apps", url.Values{
+		"client_name":   {name},
+		"redirect_uris": {redirectURI},
+		"scopes":        {scopes},
+		"website":       {website},
+	}, &app)
+	if err != nil {
+		return nil, fmt.Errorf("registering application: %w", err)
+	}
+	if app.ClientID == "" || app.ClientSecret == "" {
+		return nil, errors.New("instance did not return client credentials")
+	}
+	return &app, nil
+}
+
+// AuthorizeURL builds the URL the user visits to grant access. The PKCE
+// challenge is optional.
+func (c *Client) AuthorizeURL(app *App, redirectURI, scopes, state, codeChallenge string) string {
+	q := url.Values{
+		"client_id":     {app.ClientID},
+		"redirect_uri":  {redirectURI},
+		"response_type": {"code"},
+		"scope":         {scopes},
+	}
+	if state != "" {
+		q.Set("state", state)
+	}
+	if codeChallenge != "" {
+		q.Set("code_challenge", codeChallenge)
+		q.Set("code_challenge_method", "S256")
+	}
+	return c.baseURL + "/oauth/authorize?" + q.Encode()
+}
+
+// ExchangeCode trades an authorization code for an access token.
+func (c *Client) ExchangeCode(ctx context.Context, app *App, redirectURI, scopes, code, codeVerifier string) (*Token, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"client_id":     {app.ClientID},
+		"client_secret": {app.ClientSecret},
+		"redirect_uri":  {redirectURI},
+		"scope":         {scopes},
+	}
+	if codeVerifier != "" {
+		form.Set("code_verifier", codeVerifier)
+	}
+	var tok Token
+	if err := c.post(ctx, "/oauth/token", form, &tok); err != nil {
+		return nil, fmt.Errorf("exchanging authorization code: %w", err)
+	}
+	if tok.AccessToken == "" {
+		return nil, errors.New("instance did not return an access token")
+	}
+	if tok.Scope == "" {
+		tok.Scope = scopes
+	}
+	return &tok, nil
+}
+
+// VerifyAppCredentials returns the application the client's token was
+// issued to.
+func (c *Client) VerifyAppCredentials(ctx context.Context) (*Application, error) {
+	var app Application
+	if err := c.get(ctx, "/api/v1/apps/verify_credentials", &app); err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
@@ -0,0 +1,234 @@
+package mastodon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// MaxPageSize is the largest page Mastodon returns for most listings.
+const MaxPageSize = 40
+
+// DefaultLimit is the number of items fetched when PageOptions.Limit is 0.
+const DefaultLimit = 20
+
+// PageOptions controls how a listing endpoint is paginated.
+type PageOptions struct {
+	// Limit is the number of items to collect; 0 means DefaultLimit.
+	Limit int
+	// All follows pagination until the listing is exhausted, ignoring Limit.
+	All bool
+	// MaxPages stops after this many requests; 0 means no limit.
+	MaxPages int
+	// MaxID, SinceID, and MinID are Mastodon's native ID cursors. With MinID
+	// the listing is walked forward in time.
+	MaxID   string
+	SinceID string
+	MinID   string
+	// Offset skips this many items on endpoints paginated by offset, such
+	// as search and trends.
+	Offset int
+	// Each, if set, is handed every item as it is decoded, instead of the
+	// items being collected, so that memory stays flat however long the
+	// listing is; the listing then returns no items. An error from Each
+	// stops the pagination and is returned.
+	Each func(item interface{}) error
+}
+
+// target returns the number of items wanted, or -1 for "everything".
+func (o PageOptions) target() int {
+	switch {
+	case o.All:
+		return -1
+	case o.Limit <= 0:
+		return DefaultLimit
+	}
+	return o.Limit
+}
+
+// pageSize returns the per-request limit given how many items are still
+// wanted (negative means "as many as possible").
+func pageSize(remaining int) int {
+	if remaining < 0 || remaining > MaxPageSize {
+		return MaxPageSize
+	}
+	return remaining
+}
+
+// applyCursors adds the ID cursors to the query string of path.
+func (o PageOptions) applyCursors(path string) string {
+	if o.MaxID != "" {
+		path = WithQuery(path, "max_id", o.MaxID)
+	}
+	if o.SinceID != "" {
+		path = WithQuery(path, "since_id", o.SinceID)
+	}
+	if o.MinID != "" {
+		path = WithQuery(path, "min_id", o.MinID)
+	}
+	return path
+}
+
+// Paginate follows the Link header's next cursor (or prev, with MinID),
+// collecting items of type T from path until opts are satisfied. Once the
+// rate limit runs low, later pages are spaced out until it resets. When a
+// page fails, as when ctx is canceled part way, the items collected so far
+// are returned along with the error.
+func Paginate[T any](ctx context.Context, c *Client, path string, opts PageOptions) ([]T, error) {
+	limit := opts.target()
+	rel := "next"
+	if opts.MinID != "" {
+		rel = "prev"
+	}
+	items := []T{}
+	count := 0
+	emit := func(item T) error {
+		if limit >= 0 && count >= limit {
+			return nil
+		}
+		count++
+		if opts.Each != nil {
+			return opts.Each(item)
+		}
+		items = append(items, item)
+		return nil
+	}
+	next := opts.applyCursors(path)
+	for pages := 0; next != "" && (limit < 0 || count < limit); pages++ {
+		if opts.MaxPages > 0 && pages >= opts.MaxPages {
+			break
+		}
+		if pages > 0 {
+			if err := c.throttle(ctx); err != nil {
+				return items, err
+			}
+		}
+		remaining := -1
+		if limit >= 0 {
+			remaining = limit - count
+		}
+		resp, err := c.Do(ctx, http.MethodGet, WithQuery(next, "limit", fmt.Sprint(pageSize(remaining))), nil)
+		if err != nil {
+			return items, err
+		}
+		n, err := decodeItems(resp.Body, emit)
+		if err != nil {
+			return items, err
+		}
+		if n == 0 {
+			break
+		}
+		next = ParseLinkHeader(resp.Header.Get("Link"))[rel]
+		// Mastodon drops since_id from its cursors; keep it so deep pages
+		// never reach past the requested lower bound.
+		if next != "" && opts.SinceID != "" {
+			next = WithQuery(next, "since_id", opts.SinceID)
+		}
+	}
+	return items, nil
+}
+
+// decodeItems decodes a page, a JSON array (or null), one element at a
+// time, handing each to emit. It returns the number of elements.
+func decodeItems[T any](body []byte, emit func(T) error) (int, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if tok, err := dec.Token(); err != nil {
+		return 0, fmt.Errorf("parsing response: %w", err)
+	} else if tok == nil {
+		return 0, nil
+	} else if tok != json.Delim('[') {
+		return 0, fmt.Errorf("parsing response: expected an array, got %v", tok)
+	}
+	n := 0
+	for dec.More() {
+		var item T
+		if err := dec.Decode(&item); err != nil {
+			return n, fmt.Errorf("parsing response: %w", err)
+		}
+		n++
+		if err := emit(item); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// PaginateOffset collects items of type T from an endpoint that pages with
+// limit and offset parameters instead of Link headers. maxPage is the
+// endpoint's largest allowed limit. Since every page's offset is known up
+// front, the pages are fetched concurrently (see FetchPages), and each is
+// handed to opts.Each as soon as the pages before it have been. Like
+// Paginate it returns what it collected along with an error.
+func PaginateOffset[T any](ctx context.Context, c *Client, path string, opts PageOptions, maxPage int) ([]T, error) {
+	return paginateOffset(ctx, c, path, opts, maxPage, func(ctx context.Context, path string) ([]T, error) {
+		var items []T
+		err := c.get(ctx, path, &items)
+		return items, err
+	})
+}
+
+// paginateOffset is PaginateOffset with the page fetched and unwrapped by
+// get, for endpoints like search whose items sit inside a larger response.
+func paginateOffset[T any](ctx context.Context, c *Client, path string, opts PageOptions, maxPage int, get func(ctx context.Context, path string) ([]T, error)) ([]T, error) {
+	limit := opts.target()
+	pages := opts.MaxPages
+	if limit >= 0 {
+		wanted := (limit + maxPage - 1) / maxPage
+		if pages == 0 || wanted < pages {
+			pages = wanted
+		}
+	}
+	items := []T{}
+	err := fetchPages(ctx, c, pages, func(ctx context.Context, page int) ([]T, bool, error) {
+		size := maxPage
+		if limit >= 0 {
+			size = min(size, limit-page*maxPage)
+		}
+		items, err := get(ctx, WithQuery(WithQuery(path, "limit", fmt.Sprint(size)), "offset", fmt.Sprint(opts.Offset+page*maxPage)))
+		if err != nil {
+			return nil, false, err
+		}
+		return items, len(items) == size, nil
+	}, func(page []T) error {
+		if opts.Each == nil {
+			items = append(items, page...)
+			return nil
+		}
+		for _, item := range page {
+			if err := opts.Each(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return items, err
+}
+
+// ParseLinkHeader extracts rel => path pairs from an RFC 8288 Link header.
+// Absolute URLs are reduced to their path and query so they can be passed
+// back to Client.Do against the same instance.
+func ParseLinkHeader(h string) map[string]string {
+	links := make(map[string]string)
+	for _, part := range strings.Split(h, ",") {
+		segments := strings.Split(part, ";")
+		if len(segments) < 2 {
+			continue
+		}
+		target := strings.Trim(strings.TrimSpace(segments[0]), "<>")
+		u, err := url.Parse(target)
+		if err != nil {
+			continue
+		}
+		for _, param := range segments[1:] {
+			param = strings.TrimSpace(param)
+			if rel, ok := strings.CutPrefix(param, "rel="); ok {
+				links[strings.Trim(rel, `"`)] = u.RequestURI()
+			}
+		}
+	}
+	return links
+}
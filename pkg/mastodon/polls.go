@@ -0,0 +1,77 @@
+package mastodon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PollParams describes a poll to attach to a new status.
+type PollParams struct {
+	Options []string
+	// ExpiresIn is how long the poll stays open.
+	ExpiresIn time.Duration
+	// Multiple allows choosing more than one option.
+	Multiple bool
+	// HideTotals hides vote counts until the poll ends.
+	HideTotals bool
+}
+
+func (p PollParams) addTo(form url.Values) {
+	for _, o := range p.Options {
+		form.Add("poll[options][]", o)
+	}
+	form.Set("poll[expires_in]", strconv.Itoa(int(p.ExpiresIn/time.Second)))
+	if p.Multiple {
+		form.Set("poll[multiple]", "true")
+	}
+	if p.HideTotals {
+		form.Set("poll[hide_totals]", "true")
+	}
+}
+
+// GetPoll fetches a poll by ID.
+func (c *Client) GetPoll(ctx context.Context, id string) (*Poll, error) {
+	var poll Poll
+	if err := c.get(ctx, "/api/v1/polls/"+url.PathEscape(id), &poll); err != nil {
+		return nil, err
+	}
+	return &poll, nil
+}
+
+// Vote casts votes in a poll. Choices are zero-based option indexes.
+func (c *Client) Vote(ctx context.Context, pollID string, choices []int) (*Poll, error) {
+	if len(choices) == 0 {
+		return nil, errors.New("no poll choices given")
+	}
+	form := url.Values{}
+	for _, i := range choices {
+		form.Add("choices[]", strconv.Itoa(i))
+	}
+	var poll Poll
+	if err := c.post(ctx, fmt.Sprintf("/api/v1/polls/%s/votes", url.PathEscape(pollID)), form, &poll); err != nil {
+		return nil, err
+	}
+	return &poll, nil
+}
+
+// ChoiceIndex maps a choice given as a 1-based option number or an option's
+// title (case-insensitive) to its zero-based index.
+func (p *Poll) ChoiceIndex(choice string) (int, error) {
+	if n, err := strconv.Atoi(choice); err == nil {
+		if n < 1 || n > len(p.Options) {
+			return 0, fmt.Errorf("poll choice %d out of range (1-%d)", n, len(p.Options))
+		}
+		return n - 1, nil
+	}
+	for i, o := range p.Options {
+		if strings.EqualFold(o.Title, choice) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("no poll option matches %q", choice)
+}
@@ -0,0 +1,77 @@
+package mastodon
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+)
+
+// ProfileUpdate lists the profile attributes to change. Nil fields are left
+// as they are; a non-nil Fields replaces all profile metadata fields.
+type ProfileUpdate struct {
+	DisplayName  *string
+	Note         *string
+	Fields       []Field
+	Locked       *bool
+	Bot          *bool
+	Discoverable *bool
+	Avatar       *Upload
+	Header       *Upload
+}
+
+// UpdateProfile changes the authenticated user's profile and returns the
+// updated account.
+func (c *Client) UpdateProfile(ctx context.Context, u ProfileUpdate) (*Account, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	set := func(name, value string) {
+		w.WriteField(name, value)
+	}
+	if u.DisplayName != nil {
+		set("display_name", *u.DisplayName)
+	}
+	if u.Note != nil {
+		set("note", *u.Note)
+	}
+	for i, f := range u.Fields {
+		set(fmt.Sprintf("fields_attributes[%d][name]", i), f.Name)
+		set(fmt.Sprintf("fields_attributes[%d][value]", i), f.Value)
+	}
+	setBool := func(name string, v *bool) {
+		if v != nil {
+			set(name, strconv.FormatBool(*v))
+		}
+	}
+	setBool("locked", u.Locked)
+	setBool("bot", u.Bot)
+	setBool("discoverable", u.Discoverable)
+	if err := writeUpload(w, "avatar", u.Avatar); err != nil {
+		return nil, err
+	}
+	if err := writeUpload(w, "header", u.Header); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("encoding profile update: %w", err)
+	}
+
+	var account Account
+	if _, err := c.sendMultipart(ctx, http.MethodPatch, "/api/v1/accounts/update_credentials", w, &body, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// Preferences returns the user's posting and reading defaults. The server
+// applies the posting defaults to statuses that don't set visibility,
+// language, or sensitivity themselves.
+func (c *Client) Preferences(ctx context.Context) (*Preferences, error) {
+	var prefs Preferences
+	if err := c.get(ctx, "/api/v1/preferences", &prefs); err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
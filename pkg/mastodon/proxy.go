@@ -0,0 +1,224 @@
+package mastodon
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ParseProxyURL parses a proxy address for use as http.Transport.Proxy.
+// Supported schemes are http, https, socks5, and socks5h; with SOCKS,
+// hostnames are always resolved by the proxy, so lookups don't leak around
+// Tor.
+func ParseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q (want http, https, socks5, or socks5h)", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", raw)
+	}
+	return u, nil
+}
+
+// proxyFor returns the proxy the client's HTTP transport would use for target,
+// so that connections made outside it (the streaming WebSocket) take the
+// same route. It returns nil for a direct connection.
+func (c *Client) proxyFor(target *url.URL) (*url.URL, error) {
+	t, ok := c.httpClient.Transport.(*http.Transport)
+	if c.httpClient.Transport == nil {
+		t, ok = http.DefaultTransport.(*http.Transport)
+	}
+	if !ok || t.Proxy == nil {
+		return nil, nil
+	}
+	return t.Proxy(&http.Request{URL: target})
+}
+
+// dialProxy connects to addr (host:port) through proxy. tlsConfig supplies
+// the TLS settings for an https proxy.
+func dialProxy(ctx context.Context, proxy *url.URL, addr string, tlsConfig func(serverName string) *tls.Config, connect time.Duration) (net.Conn, error) {
+	port := proxy.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443", "socks5": "1080", "socks5h": "1080"}[proxy.Scheme]
+	}
+	dialer := net.Dialer{Timeout: connect}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(proxy.Hostname(), port))
+	if err != nil {
+		return nil, fmt.Errorf("connecting to proxy: %w", err)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	switch proxy.Scheme {
+	case "https":
+		tlsConn := tls.Client(conn, tlsConfig(proxy.Hostname()))
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("proxy TLS handshake: %w", err)
+		}
+		conn = tlsConn
+		fallthrough
+	case "http":
+		err = httpConnect(conn, proxy, addr)
+	case "socks5", "socks5h":
+		err = socks5Connect(conn, proxy, addr)
+	default:
+		err = fmt.Errorf("unsupported proxy scheme %q", proxy.Scheme)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// httpConnect opens a tunnel to addr with an HTTP CONNECT request.
+func httpConnect(conn net.Conn, proxy *url.URL, addr string) error {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if proxy.User != nil {
+		pass, _ := proxy.User.Password()
+		creds := proxy.User.Username() + ":" + pass
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(creds)))
+	}
+	if err := req.Write(conn); err != nil {
+		return fmt.Errorf("sending proxy CONNECT: %w", err)
+	}
+	// The tunnel's first bytes belong to the caller, so read the reply
+	// without buffering past it.
+	resp, err := http.ReadResponse(bufio.NewReader(&byteReader{conn}), req)
+	if err != nil {
+		return fmt.Errorf("reading proxy CONNECT reply: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("proxy refused tunnel: %s", resp.Status)
+	}
+	return nil
+}
+
+// byteReader reads one byte at a time so a bufio.Reader wrapped around it
+// never consumes more of the connection than it returns.
+type byteReader struct {
+	r io.Reader
+}
+
+func (b *byteReader) Read(p []byte) (int, error) {
+	if len(p) > 1 {
+		p = p[:1]
+	}
+	return b.r.Read(p)
+}
+
+// socks5Connect performs a SOCKS5 (RFC 1928) CONNECT to addr, passing the
+// hostname to the proxy for resolution and authenticating with the URL's
+// user info if present (RFC 1929).
+func socks5Connect(conn net.Conn, proxy *url.URL, addr string) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("invalid port in %q", addr)
+	}
+	if len(host) > 255 {
+		return errors.New("SOCKS5: hostname too long")
+	}
+
+	methods := []byte{0x00}
+	if proxy.User != nil {
+		methods = []byte{0x00, 0x02}
+	}
+	if _, err := conn.Write(append([]byte{0x05, byte(len(methods))}, methods...)); err != nil {
+		return fmt.Errorf("SOCKS5 greeting: %w", err)
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("SOCKS5 greeting: %w", err)
+	}
+	if reply[0] != 0x05 {
+		return errors.New("SOCKS5: proxy is not a SOCKS5 server")
+	}
+	switch reply[1] {
+	case 0x00:
+	case 0x02:
+		if proxy.User == nil {
+			return errors.New("SOCKS5: proxy requires a username and password")
+		}
+		user := proxy.User.Username()
+		pass, _ := proxy.User.Password()
+		if len(user) > 255 || len(pass) > 255 {
+			return errors.New("SOCKS5: credentials too long")
+		}
+		msg := []byte{0x01, byte(len(user))}
+		msg = append(msg, user...)
+		msg = append(msg, byte(len(pass)))
+		msg = append(msg, pass...)
+		if _, err := conn.Write(msg); err != nil {
+			return fmt.Errorf("SOCKS5 authentication: %w", err)
+		}
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return fmt.Errorf("SOCKS5 authentication: %w", err)
+		}
+		if reply[1] != 0x00 {
+			return errors.New("SOCKS5: authentication failed")
+		}
+	default:
+		return errors.New("SOCKS5: no acceptable authentication method")
+	}
+
+	req := []byte{0x05, 0x01, 0x00, 0x03, byte(len(host))}
+	req = append(req, host...)
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		return fmt.Errorf("SOCKS5 connect: %w", err)
+	}
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return fmt.Errorf("SOCKS5 connect: %w", err)
+	}
+	if head[1] != 0x00 {
+		return fmt.Errorf("SOCKS5: connect failed (code %d)", head[1])
+	}
+	// Skip the bound address the proxy reports.
+	var skip int
+	switch head[3] {
+	case 0x01:
+		skip = net.IPv4len + 2
+	case 0x04:
+		skip = net.IPv6len + 2
+	case 0x03:
+		n := make([]byte, 1)
+		if _, err := io.ReadFull(conn, n); err != nil {
+			return fmt.Errorf("SOCKS5 connect: %w", err)
+		}
+		skip = int(n[0]) + 2
+	default:
+		return errors.New("SOCKS5: malformed reply")
+	}
+	if _, err := io.CopyN(io.Discard, conn, int64(skip)); err != nil {
+		return fmt.Errorf("SOCKS5 connect: %w", err)
+	}
+	return nil
+}
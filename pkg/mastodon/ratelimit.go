@@ -0,0 +1,79 @@
+package mastodon
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimit is the request quota an instance reported in its
+// X-RateLimit-* response headers.
+type RateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+// Low reports whether less than a tenth of the quota is left.
+func (r RateLimit) Low() bool {
+	return r.Remaining*10 < r.Limit
+}
+
+// ParseRateLimit reads the rate limit headers of a response. It returns false
+// if they are missing or malformed.
+func ParseRateLimit(h http.Header) (RateLimit, bool) {
+	limit, err := strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return RateLimit{}, false
+	}
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return RateLimit{}, false
+	}
+	reset, err := time.Parse(time.RFC3339, h.Get("X-RateLimit-Reset"))
+	if err != nil {
+		return RateLimit{}, false
+	}
+	return RateLimit{Limit: limit, Remaining: remaining, Reset: reset}, true
+}
+
+// RateLimit returns the quota reported by the most recent response, and
+// false if no response has carried rate limit headers yet.
+func (c *Client) RateLimit() (RateLimit, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rateLimit == nil {
+		return RateLimit{}, false
+	}
+	return *c.rateLimit, true
+}
+
+func (c *Client) recordRateLimit(h http.Header) {
+	rl, ok := ParseRateLimit(h)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	c.rateLimit = &rl
+	c.mu.Unlock()
+}
+
+// throttle paces paginated fetches once the quota runs low, spreading the
+// requests that are left over the time until it resets, so that a long
+// --all fetch slows down instead of hitting 429s.
+func (c *Client) throttle(ctx context.Context) error {
+	rl, ok := c.RateLimit()
+	if !ok || !rl.Low() {
+		return nil
+	}
+	wait := time.Until(rl.Reset) / time.Duration(rl.Remaining+1)
+	if wait <= 0 {
+		return nil
+	}
+	if !sleepContext(ctx, wait) {
+		return fmt.Errorf("rate limit nearly exhausted (%d of %d requests left until %s)", rl.Remaining, rl.Limit, rl.Reset.Local().Format("15:04:05"))
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package mastodon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// ReportParams describes a report to file.
+type ReportParams struct {
+	AccountID string
+	StatusIDs []string
+	Comment   string
+	// Forward sends a copy to the remote account's server.
+	Forward bool
+	// Category is "spam", "legal", "violation", or "other".
+	Category string
+	// RuleIDs are the instance rules broken; only used with "violation".
+	RuleIDs []string
+}
+
+// ValidateReportCategory reports whether c is a category Mastodon accepts.
+func ValidateReportCategory(c string) error {
+	switch c {
+	case "spam", "legal", "violation", "other":
+		return nil
+	}
+	return fmt.Errorf("invalid report category %q (want spam, legal, violation, or other)", c)
+}
+
+// Report files a report with the moderators of the user's instance.
+func (c *Client) Report(ctx context.Context, p ReportParams) (*Report, error) {
+	if p.AccountID == "" {
+		return nil, errors.New("report requires an account")
+	}
+	form := url.Values{"account_id": {p.AccountID}}
+	for _, id := range p.StatusIDs {
+		form.Add("status_ids[]", id)
+	}
+	if p.Comment != "" {
+		form.Set("comment", p.Comment)
+	}
+	if p.Forward {
+		form.Set("forward", "true")
+	}
+	if p.Category != "" {
+		form.Set("category", p.Category)
+	}
+	for _, id := range p.RuleIDs {
+		form.Add("rule_ids[]", id)
+	}
+	var report Report
+	if err := c.post(ctx, "/api/v1/reports", form, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
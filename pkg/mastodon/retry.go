@@ -0,0 +1,126 @@
+package mastodon
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 30 * time.Second
+)
+
+// WithRetries makes the client retry a failed request up to n more times.
+// Rate-limited (429) requests are always retried; server errors (5xx) and
+// transient network errors are retried only for idempotent methods and
+// requests with an Idempotency-Key, since the server may have already acted
+// on a POST. Waits honor Retry-After and
+// X-RateLimit-Reset and otherwise back off exponentially, and a retry that
+// could not finish before the context's deadline is not attempted.
+func WithRetries(n int) Option {
+	return func(c *Client) {
+		c.retries = n
+	}
+}
+
+// retryable reports whether a request that failed with err is worth
+// repeating; safe says whether repeating it can't act twice.
+func retryable(safe bool, err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return safe && transient(err)
+	}
+	switch {
+	case apiErr.StatusCode == http.StatusTooManyRequests:
+		return true
+	case apiErr.StatusCode >= 500 && apiErr.StatusCode != http.StatusNotImplemented:
+		return safe
+	}
+	return false
+}
+
+// repeatable reports whether a request can be sent again without acting
+// twice: the instance answers a repeated Idempotency-Key with the result of
+// the first request.
+func repeatable(method string, header http.Header) bool {
+	return idempotent(method) || header.Get("Idempotency-Key") != ""
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// transient reports whether err is a network failure that may succeed on a
+// second attempt.
+func transient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return connectionFailed(err) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// retryDelay returns how long to wait before retry number attempt (starting
+// at 0), preferring what the server asked for.
+func retryDelay(attempt int, header http.Header, now time.Time) time.Duration {
+	if header != nil {
+		if d, ok := retryAfter(header.Get("Retry-After"), now); ok {
+			return d
+		}
+		if rl, ok := ParseRateLimit(header); ok && rl.Remaining == 0 {
+			return max(rl.Reset.Sub(now), 0)
+		}
+	}
+	d := retryBaseDelay << attempt
+	if d <= 0 || d > retryMaxDelay {
+		d = retryMaxDelay
+	}
+	// Jitter keeps parallel clients from retrying in lockstep.
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retryAfter parses a Retry-After value, which is either a number of seconds
+// or an HTTP date.
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(secs)*time.Second, 0), true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// sleepContext waits for d, returning false if ctx ends first or its deadline
+// would pass before d is up.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(d).After(deadline) {
+		return false
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
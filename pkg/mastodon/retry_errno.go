@@ -0,0 +1,14 @@
+//go:build !plan9
+
+package mastodon
+
+import (
+	"errors"
+	"syscall"
+)
+
+// connectionFailed reports whether err is the server resetting or refusing
+// the connection.
+func connectionFailed(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED)
+}
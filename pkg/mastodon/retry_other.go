@@ -0,0 +1,16 @@
+//go:build plan9
+
+package mastodon
+
+import (
+	"errors"
+	"net"
+)
+
+// connectionFailed reports whether err is the server resetting or refusing
+// the connection. Plan 9 has no errno values to match, so any failure to
+// connect, or to read once connected, counts.
+func connectionFailed(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && (opErr.Op == "dial" || opErr.Op == "read")
+}
@@ -0,0 +1,40 @@
+package mastodon
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ScheduleStatus queues a status to be published at the given time, which
+// Mastodon requires to be at least five minutes in the future.
+func (c *Client) ScheduleStatus(ctx context.Context, params StatusParams, at time.Time) (*ScheduledStatus, error) {
+	form := params.form()
+	form.Set("scheduled_at", at.UTC().Format(time.RFC3339))
+	var scheduled ScheduledStatus
+	if err := c.postStatusForm(ctx, params, form, &scheduled); err != nil {
+		return nil, err
+	}
+	return &scheduled, nil
+}
+
+// ScheduledStatuses lists statuses waiting to be published.
+func (c *Client) ScheduledStatuses(ctx context.Context, opts PageOptions) ([]ScheduledStatus, error) {
+	return Paginate[ScheduledStatus](ctx, c, "/api/v1/scheduled_statuses", opts)
+}
+
+// RescheduleStatus moves a scheduled status to a new publishing time.
+func (c *Client) RescheduleStatus(ctx context.Context, id string, at time.Time) (*ScheduledStatus, error) {
+	form := url.Values{"scheduled_at": {at.UTC().Format(time.RFC3339)}}
+	var scheduled ScheduledStatus
+	if err := c.call(ctx, http.MethodPut, "/api/v1/scheduled_statuses/"+url.PathEscape(id), form, &scheduled); err != nil {
+		return nil, err
+	}
+	return &scheduled, nil
+}
+
+// CancelScheduledStatus deletes a scheduled status before it is published.
+func (c *Client) CancelScheduledStatus(ctx context.Context, id string) error {
+	return c.call(ctx, http.MethodDelete, "/api/v1/scheduled_statuses/"+url.PathEscape(id), nil, nil)
+}
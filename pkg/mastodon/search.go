@@ -0,0 +1,134 @@
+package mastodon
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// Search result types for SearchOptions.Type.
+const (
+	SearchAll      = ""
+	SearchStatuses = "statuses"
+	SearchAccounts = "accounts"
+	SearchHashtags = "hashtags"
+)
+
+// SearchOptions narrows Search. The zero value searches all result types.
+type SearchOptions struct {
+	// Type limits results to SearchStatuses, SearchAccounts, or
+	// SearchHashtags.
+	Type string
+	// Resolve looks up remote accounts and post URLs the instance hasn't
+	// seen yet. It requires a token.
+	Resolve bool
+	// AccountID limits statuses to those posted by this account.
+	AccountID string
+	// Following limits accounts to those the user follows.
+	Following bool
+}
+
+func (o SearchOptions) apply(path string) string {
+	if o.Type != SearchAll {
+		path = WithQuery(path, "type", o.Type)
+	}
+	if o.Resolve {
+		path = WithQuery(path, "resolve", "true")
+	}
+	if o.AccountID != "" {
+		path = WithQuery(path, "account_id", o.AccountID)
+	}
+	if o.Following {
+		path = WithQuery(path, "following", "true")
+	}
+	return path
+}
+
+// Search queries /api/v2/search. Search results carry no Link header, so
+// with a single Type pagination advances with the offset parameter instead,
+// and the pages are fetched concurrently like PaginateOffset's; a search of
+// all types returns one page, since one offset can't page three lists at
+// once. With opts.Each the results are handed over as they arrive, and the
+// returned result is empty.
+func (c *Client) Search(ctx context.Context, query string, so SearchOptions, opts PageOptions) (*SearchResult, error) {
+	path := opts.applyCursors(so.apply("/api/v2/search?q=" + url.QueryEscape(query)))
+	result := &SearchResult{Accounts: []Account{}, Statuses: []Status{}, Hashtags: []Tag{}}
+	var err error
+	switch so.Type {
+	case SearchStatuses:
+		result.Statuses, err = searchPages(ctx, c, path, opts, func(r *SearchResult) []Status { return r.Statuses })
+	case SearchAccounts:
+		result.Accounts, err = searchPages(ctx, c, path, opts, func(r *SearchResult) []Account { return r.Accounts })
+	case SearchHashtags:
+		result.Hashtags, err = searchPages(ctx, c, path, opts, func(r *SearchResult) []Tag { return r.Hashtags })
+	default:
+		limit := opts.target()
+		if limit < 0 || limit > MaxPageSize {
+			limit = MaxPageSize
+		}
+		path = WithQuery(WithQuery(path, "limit", fmt.Sprint(limit)), "offset", fmt.Sprint(opts.Offset))
+		var page SearchResult
+		if err := c.get(ctx, path, &page); err != nil {
+			return nil, err
+		}
+		if opts.Each == nil {
+			result.Accounts = append(result.Accounts, page.Accounts...)
+			result.Statuses = append(result.Statuses, page.Statuses...)
+			result.Hashtags = append(result.Hashtags, page.Hashtags...)
+			break
+		}
+		for _, item := range page.items() {
+			if err := opts.Each(item); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// searchPages pages through the results of one type, which items picks out
+// of each response.
+func searchPages[T any](ctx context.Context, c *Client, path string, opts PageOptions, items func(*SearchResult) []T) ([]T, error) {
+	return paginateOffset(ctx, c, path, opts, MaxPageSize, func(ctx context.Context, path string) ([]T, error) {
+		var page SearchResult
+		if err := c.get(ctx, path, &page); err != nil {
+			return nil, err
+		}
+		return items(&page), nil
+	})
+}
+
+// SearchStatuses pages through /api/v2/search for statuses matching query.
+func (c *Client) SearchStatuses(ctx context.Context, query string, opts PageOptions) (*SearchResult, error) {
+	return c.Search(ctx, query, SearchOptions{Type: SearchStatuses}, opts)
+}
+
+// maxAccountSearch is the largest page /api/v1/accounts/search returns.
+const maxAccountSearch = 80
+
+// SearchAccounts finds accounts by username, display name, or address with
+// /api/v1/accounts/search. Only the Resolve and Following options apply.
+func (c *Client) SearchAccounts(ctx context.Context, query string, so SearchOptions, opts PageOptions) ([]Account, error) {
+	path := "/api/v1/accounts/search?q=" + url.QueryEscape(query)
+	path = SearchOptions{Resolve: so.Resolve, Following: so.Following}.apply(path)
+	return PaginateOffset[Account](ctx, c, path, opts, maxAccountSearch)
+}
+
+// items returns the results of every type: statuses, then accounts, then
+// hashtags.
+func (r *SearchResult) items() []interface{} {
+	items := make([]interface{}, 0, len(r.Statuses)+len(r.Accounts)+len(r.Hashtags))
+	for _, s := range r.Statuses {
+		items = append(items, s)
+	}
+	for _, a := range r.Accounts {
+		items = append(items, a)
+	}
+	for _, t := range r.Hashtags {
+		items = append(items, t)
+	}
+	return items
+}
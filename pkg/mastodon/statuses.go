@@ -0,0 +1,254 @@
+package mastodon
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// StatusParams describes a status to publish.
+type StatusParams struct {
+	Status      string
+	InReplyToID string
+	Visibility  string
+	SpoilerText string
+	Language    string
+	MediaIDs    []string
+	Poll        *PollParams
+	Sensitive   bool
+	// IdempotencyKey identifies this post to the instance, which answers a
+	// repeated key with the status the first request created instead of
+	// posting again. When empty a random key is used, so retries of this
+	// one call are safe; set it to make separate runs safe too.
+	IdempotencyKey string
+}
+
+func (p StatusParams) form() url.Values {
+	form := url.Values{}
+	form.Set("status", p.Status)
+	if p.InReplyToID != "" {
+		form.Set("in_reply_to_id", p.InReplyToID)
+	}
+	if p.Visibility != "" {
+		form.Set("visibility", p.Visibility)
+	}
+	if p.SpoilerText != "" {
+		form.Set("spoiler_text", p.SpoilerText)
+	}
+	if p.Language != "" {
+		form.Set("language", p.Language)
+	}
+	for _, id := range p.MediaIDs {
+		form.Add("media_ids[]", id)
+	}
+	if p.Poll != nil {
+		p.Poll.addTo(form)
+	}
+	if p.Sensitive {
+		form.Set("sensitive", "true")
+	}
+	return form
+}
+
+// header holds the Idempotency-Key the status is posted with.
+func (p StatusParams) header() (http.Header, error) {
+	key := p.IdempotencyKey
+	if key == "" {
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("generating idempotency key: %w", err)
+		}
+		key = hex.EncodeToString(buf)
+	}
+	return http.Header{"Idempotency-Key": {key}}, nil
+}
+
+// postStatusForm posts a status form with the params' Idempotency-Key and
+// decodes the response into v.
+func (c *Client) postStatusForm(ctx context.Context, params StatusParams, form url.Values, v interface{}) error {
+	header, err := params.header()
+	if err != nil {
+		return err
+	}
+	resp, err := c.doHeader(ctx, http.MethodPost, "/api/v1/statuses", form, header)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(resp.Body, v); err != nil {
+		return fmt.Errorf("parsing response: %w", err)
+	}
+	return nil
+}
+
+// ValidateVisibility reports whether v is a visibility Mastodon accepts.
+func ValidateVisibility(v string) error {
+	switch v {
+	case "public", "unlisted", "private", "direct":
+		return nil
+	}
+	return fmt.Errorf("invalid visibility %q (want public, unlisted, private, or direct)", v)
+}
+
+// PostStatus publishes a new status.
+func (c *Client) PostStatus(ctx context.Context, params StatusParams) (*Status, error) {
+	var status Status
+	if err := c.postStatusForm(ctx, params, params.form(), &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// GetStatus fetches a single status by its local ID.
+func (c *Client) GetStatus(ctx context.Context, id string) (*Status, error) {
+	var status Status
+	if err := c.get(ctx, "/api/v1/statuses/"+url.PathEscape(id), &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// GetStatusSource fetches the plain-text source of one of the user's
+// statuses.
+func (c *Client) GetStatusSource(ctx context.Context, id string) (*StatusSource, error) {
+	var source StatusSource
+	if err := c.get(ctx, fmt.Sprintf("/api/v1/statuses/%s/source", url.PathEscape(id)), &source); err != nil {
+		return nil, err
+	}
+	return &source, nil
+}
+
+// TranslateStatus translates a status into lang, an ISO 639 code; an empty
+// lang means the user's interface language. It fails on instances without
+// a translation service configured.
+func (c *Client) TranslateStatus(ctx context.Context, id, lang string) (*Translation, error) {
+	form := url.Values{}
+	if lang != "" {
+		form.Set("lang", lang)
+	}
+	var t Translation
+	if err := c.post(ctx, fmt.Sprintf("/api/v1/statuses/%s/translate", url.PathEscape(id)), form, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// EditStatus replaces the content of one of the user's statuses. Visibility
+// and InReplyToID cannot be changed and are ignored by the server. Media and
+// polls not included in params are removed, so pass the existing MediaIDs
+// and Poll to keep them.
+func (c *Client) EditStatus(ctx context.Context, id string, params StatusParams) (*Status, error) {
+	var status Status
+	if err := c.call(ctx, http.MethodPut, "/api/v1/statuses/"+url.PathEscape(id), params.form(), &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// StatusHistory lists every revision of a status, oldest first.
+func (c *Client) StatusHistory(ctx context.Context, id string) ([]StatusEdit, error) {
+	var edits []StatusEdit
+	if err := c.get(ctx, fmt.Sprintf("/api/v1/statuses/%s/history", url.PathEscape(id)), &edits); err != nil {
+		return nil, err
+	}
+	return edits, nil
+}
+
+// DeleteStatus deletes one of the user's statuses. The returned status
+// carries the source Text and keeps its media attachments, so it can be
+// redrafted.
+func (c *Client) DeleteStatus(ctx context.Context, id string) (*Status, error) {
+	var status Status
+	if err := c.call(ctx, http.MethodDelete, "/api/v1/statuses/"+url.PathEscape(id), nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// statusAction POSTs to /api/v1/statuses/:id/<action> and returns the
+// status the server responds with.
+func (c *Client) statusAction(ctx context.Context, id, action string) (*Status, error) {
+	var status Status
+	if err := c.post(ctx, fmt.Sprintf("/api/v1/statuses/%s/%s", url.PathEscape(id), action), nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Reblog boosts a status. The returned status is the boost wrapping the
+// original.
+func (c *Client) Reblog(ctx context.Context, id string) (*Status, error) {
+	return c.statusAction(ctx, id, "reblog")
+}
+
+// Unreblog undoes a boost.
+func (c *Client) Unreblog(ctx context.Context, id string) (*Status, error) {
+	return c.statusAction(ctx, id, "unreblog")
+}
+
+// Favourite favourites a status.
+func (c *Client) Favourite(ctx context.Context, id string) (*Status, error) {
+	return c.statusAction(ctx, id, "favourite")
+}
+
+// Unfavourite removes a favourite.
+func (c *Client) Unfavourite(ctx context.Context, id string) (*Status, error) {
+	return c.statusAction(ctx, id, "unfavourite")
+}
+
+// Bookmark bookmarks a status.
+func (c *Client) Bookmark(ctx context.Context, id string) (*Status, error) {
+	return c.statusAction(ctx, id, "bookmark")
+}
+
+// Unbookmark removes a bookmark.
+func (c *Client) Unbookmark(ctx context.Context, id string) (*Status, error) {
+	return c.statusAction(ctx, id, "unbookmark")
+}
+
+// Pin features one of the user's statuses on their profile.
+func (c *Client) Pin(ctx context.Context, id string) (*Status, error) {
+	return c.statusAction(ctx, id, "pin")
+}
+
+// Unpin removes a status from the user's profile.
+func (c *Client) Unpin(ctx context.Context, id string) (*Status, error) {
+	return c.statusAction(ctx, id, "unpin")
+}
+
+// RebloggedBy lists the accounts that boosted a status.
+func (c *Client) RebloggedBy(ctx context.Context, id string, opts PageOptions) ([]Account, error) {
+	return Paginate[Account](ctx, c, fmt.Sprintf("/api/v1/statuses/%s/reblogged_by", url.PathEscape(id)), opts)
+}
+
+// FavouritedBy lists the accounts that favourited a status.
+func (c *Client) FavouritedBy(ctx context.Context, id string, opts PageOptions) ([]Account, error) {
+	return Paginate[Account](ctx, c, fmt.Sprintf("/api/v1/statuses/%s/favourited_by", url.PathEscape(id)), opts)
+}
+
+// IsURL reports whether ref looks like a URL rather than a local ID.
+func IsURL(ref string) bool {
+	return strings.HasPrefix(ref, "https://") || strings.HasPrefix(ref, "http://")
+}
+
+// ResolveStatusID accepts either a local status ID or a post URL from any
+// instance. URLs are resolved through the search API so remote posts get a
+// local ID on this instance.
+func (c *Client) ResolveStatusID(ctx context.Context, ref string) (string, error) {
+	if !IsURL(ref) {
+		return ref, nil
+	}
+	var result SearchResult
+	path := fmt.Sprintf("/api/v2/search?q=%s&type=statuses&resolve=true&limit=1", url.QueryEscape(ref))
+	if err := c.get(ctx, path, &result); err != nil {
+		return "", err
+	}
+	if len(result.Statuses) == 0 {
+		return "", notFoundf("no status found for %s", ref)
+	}
+	return result.Statuses[0].ID, nil
+}
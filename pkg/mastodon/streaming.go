@@ -0,0 +1,127 @@
+package mastodon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// StreamEvent is a single message from the streaming API. Payload is decoded
+// from the JSON-encoded string Mastodon sends; delete events carry the bare
+// status ID as a JSON string.
+type StreamEvent struct {
+	Stream  []string        `json:"stream,omitempty"`
+	Event   string          `json:"event"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Stream is an open connection to the streaming API.
+type Stream struct {
+	conn *wsConn
+}
+
+// StreamingURL asks the instance where its streaming server lives, falling
+// back to the instance host itself. The result uses the ws/wss scheme.
+func (c *Client) StreamingURL(ctx context.Context) string {
+	base := c.baseURL
+	var instance struct {
+		Configuration struct {
+			URLs struct {
+				Streaming string `json:"streaming"`
+			} `json:"urls"`
+		} `json:"configuration"`
+	}
+	if err := c.get(ctx, "/api/v2/instance", &instance); err == nil && instance.Configuration.URLs.Streaming != "" {
+		base = instance.Configuration.URLs.Streaming
+	}
+	base = strings.TrimSuffix(base, "/")
+	if rest, ok := strings.CutPrefix(base, "https://"); ok {
+		base = "wss://" + rest
+	} else if rest, ok := strings.CutPrefix(base, "http://"); ok {
+		base = "ws://" + rest
+	}
+	return base + "/api/v1/streaming"
+}
+
+// OpenStream connects to streamURL (from StreamingURL) and subscribes to the
+// stream described by params (e.g. stream=hashtag&tag=golang). The
+// connection is closed when ctx is cancelled.
+func (c *Client) OpenStream(ctx context.Context, streamURL string, params url.Values) (*Stream, error) {
+	header := http.Header{}
+	if c.token != "" {
+		header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.userAgent != "" {
+		header.Set("User-Agent", c.userAgent)
+	}
+	rawURL := streamURL + "?" + params.Encode()
+	proxy, err := c.streamProxy(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dialWebSocket(ctx, rawURL, header, proxy, c.tlsConfigFor, c.streamTimeouts)
+	if err != nil {
+		return nil, err
+	}
+	return &Stream{conn: conn}, nil
+}
+
+// streamProxy returns the proxy for a streaming URL: whatever the HTTP
+// transport would use for the equivalent http(s) URL.
+func (c *Client) streamProxy(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing streaming URL: %w", err)
+	}
+	target := *u
+	switch u.Scheme {
+	case "wss":
+		target.Scheme = "https"
+	case "ws":
+		target.Scheme = "http"
+	}
+	proxy, err := c.proxyFor(&target)
+	if err != nil {
+		return nil, fmt.Errorf("choosing proxy: %w", err)
+	}
+	return proxy, nil
+}
+
+// Next blocks until the next event arrives.
+func (s *Stream) Next() (StreamEvent, error) {
+	for {
+		msg, err := s.conn.ReadMessage()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return StreamEvent{}, errors.New("closed by server")
+			}
+			return StreamEvent{}, err
+		}
+		var raw struct {
+			Stream  []string `json:"stream"`
+			Event   string   `json:"event"`
+			Payload string   `json:"payload"`
+		}
+		if err := json.Unmarshal(msg, &raw); err != nil {
+			continue
+		}
+		event := StreamEvent{Stream: raw.Stream, Event: raw.Event}
+		switch {
+		case strings.HasPrefix(raw.Payload, "{") && json.Valid([]byte(raw.Payload)):
+			event.Payload = json.RawMessage(raw.Payload)
+		case raw.Payload != "":
+			event.Payload, _ = json.Marshal(raw.Payload)
+		}
+		return event, nil
+	}
+}
+
+// Close closes the connection.
+func (s *Stream) Close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,67 @@
+package mastodon
+
+import (
+	"html"
+	"strings"
+)
+
+// PlainText converts the HTML Mastodon uses for post content and bios to
+// plain text: paragraphs are separated by a blank line, line breaks become
+// newlines, all other tags are stripped, and HTML entities, named and
+// numeric, are decoded. It makes a single pass over s, so long posts cost
+// no more than short ones per byte.
+func PlainText(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for len(s) > 0 {
+		start := strings.IndexByte(s, '<')
+		if start < 0 {
+			b.WriteString(s)
+			break
+		}
+		b.WriteString(s[:start])
+		end := strings.IndexByte(s[start:], '>')
+		if end < 0 {
+			// An unterminated tag runs to the end, as in a browser.
+			break
+		}
+		switch tagName(s[start+1 : start+end]) {
+		case "p":
+			// Paragraphs after the first start after a blank line.
+			if b.Len() > 0 {
+				b.WriteString("\n\n")
+			}
+		case "br":
+			b.WriteByte('\n')
+		}
+		s = s[start+end+1:]
+	}
+	return html.UnescapeString(b.String())
+}
+
+// tagName returns the lowercase name of an opening or self-closing tag, or
+// "" for a closing tag, so that only the start of a paragraph adds a break.
+func tagName(tag string) string {
+	if strings.HasPrefix(tag, "/") {
+		return ""
+	}
+	if i := strings.IndexAny(tag, " \t\r\n/"); i >= 0 {
+		tag = tag[:i]
+	}
+	return strings.ToLower(tag)
+}
+
+// ContentText returns the status content as plain text.
+func (s Status) ContentText() string {
+	return PlainText(s.Content)
+}
+
+// ContentText returns the translated content as plain text.
+func (t Translation) ContentText() string {
+	return PlainText(t.Content)
+}
+
+// NoteText returns the account's bio as plain text.
+func (a Account) NoteText() string {
+	return PlainText(a.Note)
+}
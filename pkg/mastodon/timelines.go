@@ -0,0 +1,139 @@
+package mastodon
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Public timeline scopes for PublicTimeline.
+const (
+	ScopeAll    = ""
+	ScopeLocal  = "local"
+	ScopeRemote = "remote"
+)
+
+// HomeTimeline returns statuses from accounts the user follows.
+func (c *Client) HomeTimeline(ctx context.Context, opts PageOptions) ([]Status, error) {
+	return Paginate[Status](ctx, c, "/api/v1/timelines/home", opts)
+}
+
+// PublicTimeline returns the instance's public timeline, optionally limited to
+// local (ScopeLocal) or remote (ScopeRemote) posts.
+func (c *Client) PublicTimeline(ctx context.Context, scope string, opts PageOptions) ([]Status, error) {
+	path := "/api/v1/timelines/public"
+	switch scope {
+	case ScopeLocal:
+		path += "?local=true"
+	case ScopeRemote:
+		path += "?remote=true"
+	}
+	return Paginate[Status](ctx, c, path, opts)
+}
+
+// TagTimeline returns public statuses with the hashtag tag (with or without
+// the leading #).
+func (c *Client) TagTimeline(ctx context.Context, tag string, opts PageOptions) ([]Status, error) {
+	path := "/api/v1/timelines/tag/" + url.PathEscape(strings.TrimPrefix(tag, "#"))
+	return Paginate[Status](ctx, c, path, opts)
+}
+
+// AccountStatusFilter narrows AccountStatuses. The zero value returns every
+// status the user can see.
+type AccountStatusFilter struct {
+	ExcludeReplies bool
+	ExcludeReblogs bool
+	OnlyMedia      bool
+	Pinned         bool
+	// Tagged limits results to statuses with this hashtag (without the #).
+	Tagged string
+}
+
+func (f AccountStatusFilter) apply(path string) string {
+	for key, on := range map[string]bool{
+		"exclude_replies": f.ExcludeReplies,
+		"exclude_reblogs": f.ExcludeReblogs,
+		"only_media":      f.OnlyMedia,
+		"pinned":          f.Pinned,
+	} {
+		if on {
+			path = WithQuery(path, key, "true")
+		}
+	}
+	if f.Tagged != "" {
+		path = WithQuery(path, "tagged", strings.TrimPrefix(f.Tagged, "#"))
+	}
+	return path
+}
+
+// AccountStatuses returns statuses posted by the account with the given ID.
+func (c *Client) AccountStatuses(ctx context.Context, accountID string, filter AccountStatusFilter, opts PageOptions) ([]Status, error) {
+	path := fmt.Sprintf("/api/v1/accounts/%s/statuses", url.PathEscape(accountID))
+	return Paginate[Status](ctx, c, filter.apply(path), opts)
+}
+
+// PinnedStatuses returns the statuses the account has pinned to its profile.
+func (c *Client) PinnedStatuses(ctx context.Context, accountID string) ([]Status, error) {
+	var statuses []Status
+	if err := c.get(ctx, fmt.Sprintf("/api/v1/accounts/%s/statuses?pinned=true", url.PathEscape(accountID)), &statuses); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// Bookmarks returns the user's bookmarked statuses.
+func (c *Client) Bookmarks(ctx context.Context, opts PageOptions) ([]Status, error) {
+	return Paginate[Status](ctx, c, "/api/v1/bookmarks", opts)
+}
+
+// Favourites returns the statuses the user has favourited.
+func (c *Client) Favourites(ctx context.Context, opts PageOptions) ([]Status, error) {
+	return Paginate[Status](ctx, c, "/api/v1/favourites", opts)
+}
+
+// FollowedTags returns the hashtags the user follows.
+func (c *Client) FollowedTags(ctx context.Context, opts PageOptions) ([]Tag, error) {
+	return Paginate[Tag](ctx, c, "/api/v1/followed_tags", opts)
+}
+
+// NotificationFilter narrows Notifications to (or away from) specific
+// notification types such as "mention", "follow", or "favourite".
+type NotificationFilter struct {
+	Types        []string
+	ExcludeTypes []string
+}
+
+// NotificationTypes lists the notification types Mastodon can send.
+var NotificationTypes = []string{
+	"mention", "status", "reblog", "follow", "follow_request", "favourite",
+	"poll", "update", "admin.sign_up", "admin.report",
+}
+
+// Notifications returns the user's notifications matching filter.
+func (c *Client) Notifications(ctx context.Context, filter NotificationFilter, opts PageOptions) ([]Notification, error) {
+	q := url.Values{}
+	for _, t := range filter.Types {
+		q.Add("types[]", t)
+	}
+	for _, t := range filter.ExcludeTypes {
+		q.Add("exclude_types[]", t)
+	}
+	path := "/api/v1/notifications"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	return Paginate[Notification](ctx, c, path, opts)
+}
+
+// UnreadNotificationCount returns how many notifications arrived since the
+// user last read them. Servers before Mastodon 4.3 answer 404.
+func (c *Client) UnreadNotificationCount(ctx context.Context) (int, error) {
+	var resp struct {
+		Count int `json:"count"`
+	}
+	if err := c.get(ctx, "/api/v1/notifications/unread_count", &resp); err != nil {
+		return 0, err
+	}
+	return resp.Count, nil
+}
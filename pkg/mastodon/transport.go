@@ -0,0 +1,145 @@
+package mastodon
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// defaultHTTPClient is shared by clients created without WithHTTPClient, so
+// they pool connections with each other.
+var defaultHTTPClient = NewHTTPClient()
+
+// NewHTTPClient returns an http.Client suited to many requests against one
+// instance: connections are kept alive and reused, HTTP/2 is negotiated where
+// the server supports it, and responses are transparently gzip-decoded. It
+// carries no overall timeout; bound requests with their context instead, as
+// streaming responses stay open indefinitely.
+func NewHTTPClient() *http.Client {
+	return &http.Client{Transport: NewTransport()}
+}
+
+// DefaultConnectTimeout bounds connecting to a server, and the TLS
+// handshake, unless Timeouts says otherwise.
+const DefaultConnectTimeout = 10 * time.Second
+
+// NewTransport returns the transport NewHTTPClient uses, for callers that
+// want to wrap or adjust it.
+func NewTransport() *http.Transport {
+	t := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          32,
+		MaxIdleConnsPerHost:   8,
+		IdleConnTimeout:       90 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	Timeouts{Connect: DefaultConnectTimeout}.Apply(t)
+	return t
+}
+
+// Timeouts bounds the phases of a request in the transport. A context
+// deadline bounds a whole call instead; these catch a server that never
+// answers well before that, or bound requests that have no deadline.
+type Timeouts struct {
+	// Connect bounds dialing the server (or proxy) and, separately, the
+	// TLS handshake; 0 leaves them to the context.
+	Connect time.Duration
+	// Response bounds the wait for the response headers once the request
+	// has been sent; 0 waits as long as the context allows. Reading the
+	// body isn't bounded, so large downloads aren't cut off.
+	Response time.Duration
+}
+
+// WithStreamTimeouts bounds connecting to the streaming API, which doesn't
+// go through the HTTP client: Connect bounds dialing and the TLS handshake,
+// and Response the WebSocket upgrade, or Connect does when Response is 0.
+// Pass the same Timeouts as the transport's.
+func WithStreamTimeouts(o Timeouts) Option {
+	return func(c *Client) {
+		c.streamTimeouts = o
+	}
+}
+
+// upgrade is how long a WebSocket upgrade may take.
+func (o Timeouts) upgrade() time.Duration {
+	if o.Response > 0 {
+		return o.Response
+	}
+	return o.Connect
+}
+
+// Apply sets the timeouts on t.
+func (o Timeouts) Apply(t *http.Transport) {
+	dialer := &net.Dialer{
+		Timeout:   o.Connect,
+		KeepAlive: 30 * time.Second,
+	}
+	t.DialContext = dialer.DialContext
+	t.TLSHandshakeTimeout = o.Connect
+	t.ResponseHeaderTimeout = o.Response
+}
+
+// TLSOptions customizes how the client verifies and authenticates to
+// servers, for self-hosted instances behind a private CA or an mTLS proxy.
+type TLSOptions struct {
+	// CACertFile names a PEM file of extra root certificates to trust in
+	// addition to the system's.
+	CACertFile string
+	// ClientCertFile and ClientKeyFile name a PEM certificate and key to
+	// present to servers that ask for one.
+	ClientCertFile string
+	ClientKeyFile  string
+	// InsecureSkipVerify accepts any server certificate. It leaves the
+	// connection, and the access token sent over it, open to interception.
+	InsecureSkipVerify bool
+}
+
+// Config builds the tls.Config the options describe.
+func (o TLSOptions) Config() (*tls.Config, error) {
+	cfg := &tls.Config{InsecureSkipVerify: o.InsecureSkipVerify}
+	if o.CACertFile != "" {
+		pem, err := os.ReadFile(o.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA certificate: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in %s", o.CACertFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if (o.ClientCertFile == "") != (o.ClientKeyFile == "") {
+		return nil, errors.New("a client certificate requires both a certificate and a key file")
+	}
+	if o.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.ClientCertFile, o.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// tlsConfigFor returns the TLS settings the client's HTTP transport uses,
+// adjusted to verify serverName, so that connections made outside it (the
+// streaming WebSocket) are secured the same way.
+func (c *Client) tlsConfigFor(serverName string) *tls.Config {
+	var cfg *tls.Config
+	if t, ok := c.httpClient.Transport.(*http.Transport); ok && t.TLSClientConfig != nil {
+		cfg = t.TLSClientConfig.Clone()
+	} else {
+		cfg = &tls.Config{}
+	}
+	cfg.ServerName = serverName
+	return cfg
+}
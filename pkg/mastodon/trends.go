@@ -0,0 +1,26 @@
+package mastodon
+
+import "context"
+
+// Largest page sizes the trends endpoints accept.
+const (
+	maxTrendingTags     = 20
+	maxTrendingStatuses = 40
+	maxTrendingLinks    = 20
+)
+
+// TrendingTags returns hashtags being used more than usual, with daily usage
+// history.
+func (c *Client) TrendingTags(ctx context.Context, opts PageOptions) ([]Tag, error) {
+	return PaginateOffset[Tag](ctx, c, "/api/v1/trends/tags", opts, maxTrendingTags)
+}
+
+// TrendingStatuses returns statuses getting more interaction than usual.
+func (c *Client) TrendingStatuses(ctx context.Context, opts PageOptions) ([]Status, error) {
+	return PaginateOffset[Status](ctx, c, "/api/v1/trends/statuses", opts, maxTrendingStatuses)
+}
+
+// TrendingLinks returns links shared more than usual.
+func (c *Client) TrendingLinks(ctx context.Context, opts PageOptions) ([]TrendingLink, error) {
+	return PaginateOffset[TrendingLink](ctx, c, "/api/v1/trends/links", opts, maxTrendingLinks)
+}
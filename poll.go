@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// pollFromFlags returns the poll described by --poll-option and friends, or
+// nil when no options were given.
+func pollFromFlags() (*mastodon.PollParams, error) {
+	if len(flagPollOptions) == 0 {
+		return nil, nil
+	}
+	if len(flagPollOptions) < 2 {
+		return nil, errors.New("a poll needs at least two --poll-option values")
+	}
+	if *flagMedia != "" {
+		return nil, errors.New("a post cannot have both media and a poll")
+	}
+	if *flagPollExpires < 5*time.Minute {
+		return nil, fmt.Errorf("--poll-expires %s is too short (minimum 5m)", *flagPollExpires)
+	}
+	return &mastodon.PollParams{
+		Options:   flagPollOptions,
+		ExpiresIn: *flagPollExpires,
+		Multiple:  *flagPollMulti,
+	}, nil
+}
+
+// votePoll votes in the poll attached to the referenced status.
+func votePoll(ctx context.Context, client *mastodon.Client, ref string, choices []string) (interface{}, error) {
+	id, err := client.ResolveStatusID(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	status, err := client.GetStatus(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	post, _ := resolvePost(*status)
+	if post.Poll == nil {
+		return nil, fmt.Errorf("status %s has no poll", id)
+	}
+	if len(choices) > 1 && !post.Poll.Multiple {
+		return nil, errors.New("this poll allows only one choice")
+	}
+	indexes := make([]int, 0, len(choices))
+	for _, c := range choices {
+		i, err := post.Poll.ChoiceIndex(c)
+		if err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, i)
+	}
+	poll, err := client.Vote(ctx, post.Poll.ID, indexes)
+	if err != nil {
+		return nil, err
+	}
+	return *poll, nil
+}
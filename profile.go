@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// stringList is a flag that may be given more than once.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ", ") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// runProfile handles "profile set", "profile avatar <file>", and
+// "profile header <file>".
+func runProfile(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, errors.New("profile command requires a subcommand: set, avatar <file>, header <file>")
+	}
+	var update mastodon.ProfileUpdate
+	switch args[0] {
+	case "set":
+		var err error
+		if update, err = profileUpdateFromFlags(); err != nil {
+			return nil, err
+		}
+	case "avatar", "header":
+		if len(args) < 2 {
+			return nil, fmt.Errorf("profile %s requires an image file", args[0])
+		}
+		f, err := os.Open(args[1])
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		upload := &mastodon.Upload{Filename: args[1], Content: f}
+		if args[0] == "avatar" {
+			update.Avatar = upload
+		} else {
+			update.Header = upload
+		}
+	default:
+		return nil, fmt.Errorf("unknown profile subcommand: %s", args[0])
+	}
+
+	account, err := client.UpdateProfile(ctx, update)
+	if err != nil {
+		return nil, err
+	}
+	return AccountProfile{Account: *account}, nil
+}
+
+// profileUpdateFromFlags builds an update from the profile flags that were
+// given on the command line; flags that were not given are left unchanged.
+func profileUpdateFromFlags() (mastodon.ProfileUpdate, error) {
+	var u mastodon.ProfileUpdate
+	changed := false
+	if flagWasSet("display-name") {
+		u.DisplayName = flagDisplayName
+		changed = true
+	}
+	if flagWasSet("bio") {
+		u.Note = flagBio
+		changed = true
+	}
+	if len(flagFields) > 0 {
+		for _, kv := range flagFields {
+			name, value, ok := strings.Cut(kv, "=")
+			if !ok || strings.TrimSpace(name) == "" {
+				return u, fmt.Errorf("invalid --field %q (want name=value)", kv)
+			}
+			u.Fields = append(u.Fields, mastodon.Field{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+		}
+		changed = true
+	}
+	if flagWasSet("locked") {
+		u.Locked = flagLocked
+		changed = true
+	}
+	if flagWasSet("bot") {
+		u.Bot = flagBot
+		changed = true
+	}
+	if flagWasSet("discoverable") {
+		u.Discoverable = flagDiscover
+		changed = true
+	}
+	if !changed {
+		return u, errors.New("profile set needs at least one of --display-name, --bio, --field, --locked, --bot, --discoverable")
+	}
+	return u, nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// getRateLimit makes a cheap authenticated request and reports the quota the
+// instance returned with it.
+func getRateLimit(ctx context.Context, client *mastodon.Client) (interface{}, error) {
+	if _, err := client.VerifyCredentials(ctx); err != nil {
+		return nil, err
+	}
+	rl, ok := client.RateLimit()
+	if !ok {
+		return nil, fmt.Errorf("%s did not report a rate limit", client.BaseURL())
+	}
+	return rl, nil
+}
+
+// warnRateLimit tells the user on stderr when the run left little of the
+// quota, so a script can back off before requests start failing.
+func warnRateLimit(client *mastodon.Client) {
+	if rl, ok := client.RateLimit(); ok && rl.Low() {
+		notef("Warning: only %d of %d API requests left until %s\n", rl.Remaining, rl.Limit, rl.Reset.Local().Format("15:04:05"))
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/patelhiren/mastodon-scout/internal/mastotest"
+)
+
+// configureReplay answers every request of the run from the HAR fixtures
+// in the --replay directory instead of the network, so commands and their
+// output can be checked offline. It runs before configureTracing, so
+// --verbose still shows the replayed requests.
+func configureReplay() error {
+	if *flagReplay == "" {
+		return nil
+	}
+	replayer, err := mastotest.Load(*flagReplay)
+	if err != nil {
+		return err
+	}
+	httpClient.Transport = replayer
+	return nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/patelhiren/mastodon-scout/internal/mastotest"
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// replayClient returns a client of a fake server answering from the
+// fixtures in internal/mastotest/testdata.
+func replayClient(t *testing.T) *mastodon.Client {
+	t.Helper()
+	replayer, err := mastotest.Load("internal/mastotest/testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(replayer)
+	t.Cleanup(srv.Close)
+	return mastodon.NewClient(srv.URL, "test-token", mastodon.WithHTTPClient(srv.Client()), mastodon.WithRetries(0))
+}
+
+// setFlags sets global flags for one test and restores them afterwards.
+func setFlags(t *testing.T, values map[string]string) {
+	t.Helper()
+	for name, value := range values {
+		f := flag.Lookup(name)
+		if f == nil {
+			t.Fatalf("no flag %q", name)
+		}
+		old := f.Value.String()
+		if err := f.Value.Set(value); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { f.Value.Set(old) })
+	}
+}
+
+// runCommand runs a command line against the fixtures.
+func runCommand(t *testing.T, args ...string) interface{} {
+	t.Helper()
+	cmd := lookupCommand(args[0])
+	if cmd == nil {
+		t.Fatalf("no command %q", args[0])
+	}
+	if err := cmd.checkArgs(args[1:]); err != nil {
+		t.Fatal(err)
+	}
+	data, err := cmd.Run(context.Background(), replayClient(t), args[1:])
+	if err != nil {
+		t.Fatalf("%v: %v", args, err)
+	}
+	return data
+}
+
+// captureStdout returns what f prints on stdout.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+	defer func() { os.Stdout = stdout }()
+	f()
+	w.Close()
+	return <-out
+}
+
+func TestCommandsReplay(t *testing.T) {
+	tests := []struct {
+		args    []string
+		flags   map[string]string
+		wantIDs []string // the IDs of the statuses returned
+		check   func(t *testing.T, data interface{})
+	}{
+		{args: []string{"home"}, check: func(t *testing.T, data interface{}) {
+			statuses := data.([]mastodon.Status)
+			if len(statuses) != 20 || statuses[0].ID != "999" || statuses[19].ID != "980" {
+				t.Errorf("got %d posts, want 999 down to 980", len(statuses))
+			}
+		}},
+		{args: []string{"notifications"}, check: func(t *testing.T, data interface{}) {
+			notifications := data.([]mastodon.Notification)
+			if len(notifications) != 2 || notifications[0].Type != "mention" || notifications[1].Status != nil {
+				t.Errorf("got %+v, want a mention and a follow", notifications)
+			}
+		}},
+		{args: []string{"status", "996"}, wantIDs: []string{"996"}},
+		{args: []string{"posts", "@alice"}, flags: map[string]string{"pinned": "true"}, check: func(t *testing.T, data interface{}) {
+			statuses := data.([]mastodon.Status)
+			if len(statuses) != 20 || statuses[0].Account.Acct != "alice" {
+				t.Errorf("got %d posts, want alice's 20 pinned posts", len(statuses))
+			}
+		}},
+		{args: []string{"instance"}, check: func(t *testing.T, data interface{}) {
+			info := data.(InstanceInfo)
+			if info.Instance.Domain != "example.social" || len(info.Activity) == 0 {
+				t.Errorf("got %+v, want example.social with its activity", info)
+			}
+		}},
+		{args: []string{"trends", "tags"}, check: func(t *testing.T, data interface{}) {
+			tags := data.([]mastodon.Tag)
+			if len(tags) == 0 || tags[0].Name != "tag0" {
+				t.Errorf("got %d tags, want tag0 first", len(tags))
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args[0], func(t *testing.T) {
+			setFlags(t, tt.flags)
+			data := runCommand(t, tt.args...)
+			if tt.wantIDs != nil {
+				var ids []string
+				switch v := data.(type) {
+				case []mastodon.Status:
+					for _, s := range v {
+						ids = append(ids, s.ID)
+					}
+				case mastodon.Status:
+					ids = append(ids, v.ID)
+				default:
+					t.Fatalf("got %T, want statuses", data)
+				}
+				if len(ids) != len(tt.wantIDs) {
+					t.Fatalf("got IDs %v, want %v", ids, tt.wantIDs)
+				}
+				for i := range ids {
+					if ids[i] != tt.wantIDs[i] {
+						t.Fatalf("got IDs %v, want %v", ids, tt.wantIDs)
+					}
+				}
+			}
+			if tt.check != nil {
+				tt.check(t, data)
+			}
+		})
+	}
+}
+
+// TestListingEndsReplay checks that following a recorded listing stops where
+// the recording does, without fetching any page twice.
+func TestListingEndsReplay(t *testing.T) {
+	for _, flags := range []map[string]string{{}, {"all": "true"}} {
+		t.Run(fmt.Sprint(flags), func(t *testing.T) {
+			setFlags(t, flags)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			data, err := lookupCommand("home").Run(ctx, replayClient(t), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			seen := map[string]bool{}
+			for _, s := range data.([]mastodon.Status) {
+				if seen[s.ID] {
+					t.Fatalf("post %s was returned twice", s.ID)
+				}
+				seen[s.ID] = true
+			}
+			if want := map[bool]int{false: 20, true: 80}[flags["all"] == "true"]; len(seen) != want {
+				t.Errorf("got %d posts, want %d", len(seen), want)
+			}
+		})
+	}
+}
+
+// TestOffsetListingEachReplay checks that an offset-paged listing hands its
+// items to Each in order instead of returning them.
+func TestOffsetListingEachReplay(t *testing.T) {
+	var names []string
+	tags, err := replayClient(t).TrendingTags(context.Background(), mastodon.PageOptions{All: true, Each: func(item interface{}) error {
+		names = append(names, item.(mastodon.Tag).Name)
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 0 {
+		t.Errorf("got %d tags back, want them all handed to Each", len(tags))
+	}
+	if len(names) != 20 || names[0] != "tag0" || names[19] != "tag19" {
+		t.Errorf("Each got %v, want tag0 to tag19 in order", names)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// reportAccount reports an account, optionally with specific statuses (IDs
+// or URLs), to the instance moderators.
+func reportAccount(ctx context.Context, client *mastodon.Client, ref string, statusRefs []string) (interface{}, error) {
+	if err := mastodon.ValidateReportCategory(*flagCategory); err != nil {
+		return nil, err
+	}
+	if len(flagRuleIDs) > 0 && *flagCategory != "violation" {
+		return nil, errors.New("--rule-id only applies to --category violation")
+	}
+	account, err := client.ResolveAccount(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	params := mastodon.ReportParams{
+		AccountID: account.ID,
+		Comment:   *flagComment,
+		Forward:   *flagForward,
+		Category:  *flagCategory,
+		RuleIDs:   flagRuleIDs,
+	}
+	for _, s := range statusRefs {
+		id, err := client.ResolveStatusID(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		params.StatusIDs = append(params.StatusIDs, id)
+	}
+	question := fmt.Sprintf("Report @%s to your moderators", account.Acct)
+	if n := len(params.StatusIDs); n > 0 {
+		question += fmt.Sprintf(" with %d post(s)", n)
+	}
+	if *flagForward {
+		question += " and forward it to their server"
+	}
+	ctx, cancel, err := confirmRequest(ctx, question+"? Reports can't be withdrawn")
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	report, err := client.Report(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if report.TargetAccount.ID == "" {
+		report.TargetAccount = *account
+	}
+	return *report, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// parseScheduleTime parses an RFC 3339 timestamp such as
+// 2026-01-02T15:04:05+01:00.
+func parseScheduleTime(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid schedule time %q (want RFC 3339, e.g. 2026-01-02T15:04:05Z)", s)
+	}
+	if !t.After(time.Now()) {
+		return time.Time{}, fmt.Errorf("schedule time %s is in the past", s)
+	}
+	return t, nil
+}
+
+// CancelledScheduledStatus reports a cancelled scheduled status.
+type CancelledScheduledStatus struct {
+	ID string `json:"id"`
+}
+
+// runScheduled handles "scheduled list", "scheduled cancel <id>", and
+// "scheduled reschedule <id> <time>".
+func runScheduled(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, errors.New("scheduled command requires a subcommand: list, cancel <id>, reschedule <id> <time>")
+	}
+	switch args[0] {
+	case "list":
+		return client.ScheduledStatuses(ctx, pageOptions())
+	case "cancel":
+		if len(args) < 2 {
+			return nil, errors.New("scheduled cancel requires a scheduled status ID")
+		}
+		if err := client.CancelScheduledStatus(ctx, args[1]); err != nil {
+			return nil, err
+		}
+		return CancelledScheduledStatus{ID: args[1]}, nil
+	case "reschedule":
+		if len(args) < 3 {
+			return nil, errors.New("scheduled reschedule requires a scheduled status ID and a time")
+		}
+		at, err := parseScheduleTime(args[2])
+		if err != nil {
+			return nil, err
+		}
+		scheduled, err := client.RescheduleStatus(ctx, args[1], at)
+		if err != nil {
+			return nil, err
+		}
+		return *scheduled, nil
+	default:
+		return nil, fmt.Errorf("unknown scheduled subcommand: %s", args[0])
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// commandScopes maps the commands that change something, by their
+// formatKey, to the OAuth scope Mastodon requires for them.
+var commandScopes = map[string]string{
+	"post":                 "write:statuses",
+	"reply":                "write:statuses",
+	"dm":                   "write:statuses",
+	"delete":               "write:statuses",
+	"redraft":              "write:statuses",
+	"edit":                 "write:statuses",
+	"vote":                 "write:statuses",
+	"boost":                "write:statuses",
+	"unboost":              "write:statuses",
+	"markers set":          "write:statuses",
+	"scheduled cancel":     "write:statuses",
+	"scheduled reschedule": "write:statuses",
+	"fav":                  "write:favourites",
+	"unfav":                "write:favourites",
+	"bookmark":             "write:bookmarks",
+	"unbookmark":           "write:bookmarks",
+	"upload":               "write:media",
+	"lists create":         "write:lists",
+	"lists rename":         "write:lists",
+	"lists delete":         "write:lists",
+	"lists add":            "write:lists",
+	"lists remove":         "write:lists",
+	"filters create":       "write:filters",
+	"filters edit":         "write:filters",
+	"filters delete":       "write:filters",
+	"profile set":          "write:accounts",
+	"profile avatar":       "write:accounts",
+	"profile header":       "write:accounts",
+	"pin":                  "write:accounts",
+	"unpin":                "write:accounts",
+	"endorse":              "write:accounts",
+	"unendorse":            "write:accounts",
+	"follow":               "write:follows",
+	"unfollow":             "write:follows",
+	"mute":                 "write:mutes",
+	"unmute":               "write:mutes",
+	"block":                "write:blocks",
+	"unblock":              "write:blocks",
+	"domain-block":         "write:blocks",
+	"domain-unblock":       "write:blocks",
+	"report":               "write:reports",
+}
+
+// requiredScopes lists the scopes an invocation needs, by its formatKey.
+func requiredScopes(key string) []string {
+	if key == "conversations" && *flagMarkRead {
+		// Listing only reads; marking them read writes.
+		return []string{"write:conversations"}
+	}
+	scope, ok := commandScopes[key]
+	if !ok {
+		return nil
+	}
+	scopes := []string{scope}
+	if *flagMedia != "" && scope == "write:statuses" {
+		scopes = append(scopes, "write:media")
+	}
+	return scopes
+}
+
+// legacyScopes are the pre-2.4 scopes that still grant the granular ones.
+var legacyScopes = map[string][]string{
+	"follow": {"read:blocks", "write:blocks", "read:follows", "write:follows", "read:mutes", "write:mutes"},
+}
+
+// hasScope reports whether the granted scopes cover need: exactly, through
+// the top-level scope ("write" covers "write:statuses"), or through a
+// legacy scope.
+func hasScope(granted []string, need string) bool {
+	top, _, _ := strings.Cut(need, ":")
+	for _, g := range granted {
+		if g == need || g == top {
+			return true
+		}
+		for _, s := range legacyScopes[g] {
+			if s == need {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// scopeError is a command refused, or bound to be refused, because the
+// token wasn't granted the scope it needs. It matches ErrUnauthorized.
+type scopeError struct {
+	need    string
+	granted []string
+	err     error // the instance's refusal, if it got that far
+}
+
+func (e *scopeError) Error() string {
+	scopes := append([]string{}, e.granted...)
+	if len(scopes) == 0 {
+		scopes = []string{"read"}
+	}
+	if !hasScope(scopes, e.need) {
+		scopes = append(scopes, e.need)
+	}
+	login := "mastodon-scout login"
+	if activeAccount != nil {
+		login += " --account " + activeAccount.Name
+	}
+	return fmt.Sprintf("token lacks %s; re-run `%s --scopes %q`", e.need, login, strings.Join(scopes, " "))
+}
+
+func (e *scopeError) Unwrap() error { return e.err }
+
+func (e *scopeError) Is(target error) bool { return target == mastodon.ErrUnauthorized }
+
+// grantedScopes returns the scopes of the run's token: as the instance
+// reports them on Mastodon 4.3 and later, and otherwise as login recorded
+// them. It returns nil when neither knows.
+func grantedScopes(ctx context.Context, client *mastodon.Client) []string {
+	if app, err := client.VerifyAppCredentials(ctx); err == nil && len(app.Scopes) > 0 {
+		return app.Scopes
+	}
+	return strings.Fields(storedScope())
+}
+
+// checkScopes fails before a command that changes something runs, when the
+// token is known to lack the scope it needs. Unknown scopes are left for
+// the instance to judge.
+func checkScopes(ctx context.Context, client *mastodon.Client, key string) error {
+	need := requiredScopes(key)
+	if len(need) == 0 {
+		return nil
+	}
+	granted := grantedScopes(ctx, client)
+	if len(granted) == 0 {
+		return nil
+	}
+	for _, scope := range need {
+		if !hasScope(granted, scope) {
+			return &scopeError{need: scope, granted: granted}
+		}
+	}
+	return nil
+}
+
+// explainScopeError turns the instance's 403 for a command that changes
+// something into advice on the scope to log in with, when the token's
+// scopes are why it was refused.
+func explainScopeError(key string, err error) error {
+	need := requiredScopes(key)
+	var apiErr *mastodon.APIError
+	if len(need) == 0 || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+		return err
+	}
+	granted := strings.Fields(storedScope())
+	outOfScope := strings.Contains(apiErr.Body, "outside the authorized scopes")
+	for _, scope := range need {
+		if !hasScope(granted, scope) && (outOfScope || len(granted) > 0) {
+			return &scopeError{need: scope, granted: granted, err: err}
+		}
+	}
+	return err
+}
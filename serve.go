@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// serveWriteParams are the parameters that make a read command change
+// something, so they need POST too.
+var serveWriteParams = map[string]bool{"mark-read": true}
+
+// serveExcluded can't run behind the API: they open $EDITOR, manage
+// credentials, write local files, or run forever.
+var serveExcluded = map[string]bool{
+	"edit": true, "redraft": true, "login": true, "auth": true,
+	"serve": true, "metrics": true, "stream": true, "export": true,
+	"import": true,
+}
+
+// serveTextArgs is how many arguments come before the text of commands that
+// would otherwise read it from stdin.
+var serveTextArgs = map[string]int{"post": 0, "reply": 1, "dm": 1}
+
+// serveFilterFlags are the global flags a request may set; the others, such
+// as --instance, belong to the session.
+var serveFilterFlags = []string{"cw-only", "include", "exclude", "lang", "no-boosts", "no-replies", "only-media", "min-boosts", "min-favs", "min-replies", "sort"}
+
+// apiServer runs commands for HTTP requests with one client, so every
+// caller shares the session's token, connections, and response cache.
+type apiServer struct {
+	client *mastodon.Client
+	// baseline holds each flag's value when serve started; requests start
+	// from it.
+	baseline map[string]string
+	// mu serializes requests, since commands read package-level flags.
+	mu sync.Mutex
+}
+
+// runServe serves the command API on --listen until interrupted.
+func runServe(ctx context.Context, client *mastodon.Client) error {
+	ln, err := net.Listen("tcp", *flagListen)
+	if err != nil {
+		return err
+	}
+	if addr, ok := ln.Addr().(*net.TCPAddr); ok && !addr.IP.IsLoopback() {
+		fmt.Fprintf(os.Stderr, "WARNING: %s is reachable from other machines, and anyone who can connect can use your account\n", ln.Addr())
+	}
+	// API callers can't answer prompts; a POST is their confirmation.
+	*flagYes = true
+	s := &apiServer{client: client, baseline: map[string]string{}}
+	flag.VisitAll(func(f *flag.Flag) {
+		if _, ok := f.Value.(*stringList); !ok {
+			s.baseline[f.Name] = f.Value.String()
+		}
+	})
+	srv := &http.Server{Handler: s}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	notef("Serving the API on http://%s/\n", ln.Addr())
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// ServeHTTP maps /<command>/<arg>... to a command. Query parameters, or the
+// fields of a JSON object in a POST body, set the command's flags; "q" and
+// "text" are appended to the arguments.
+func (s *apiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Browsers attach an Origin to cross-site requests, and a Host that
+	// isn't ours means DNS rebinding; neither may reach the account.
+	if r.Header.Get("Origin") != "" || !localHost(r.Host) {
+		serveError(w, http.StatusForbidden, errors.New("cross-origin requests are not allowed"))
+		return
+	}
+	path := strings.Trim(r.URL.Path, "/")
+	if path == "" {
+		serveJSON(w, http.StatusOK, MastodonResponse{Success: true, Data: serveCommandNames()})
+		return
+	}
+	args := strings.Split(path, "/")
+	cmd := lookupCommand(args[0])
+	if cmd == nil || serveExcluded[cmd.Name] || cmd.NoAuth {
+		serveError(w, http.StatusNotFound, fmt.Errorf("unknown command: %s", args[0]))
+		return
+	}
+	args = args[1:]
+	switch r.Method {
+	case http.MethodGet:
+		// Commands that only read answer GET; see command.ReadOnly.
+		if !cmd.readOnly(args) {
+			serveError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s changes your account; use POST", cmd.Name))
+			return
+		}
+	case http.MethodPost:
+		// JSON bodies can't be sent cross-site without a preflight.
+		if ct := r.Header.Get("Content-Type"); r.ContentLength != 0 && !strings.HasPrefix(ct, "application/json") {
+			serveError(w, http.StatusUnsupportedMediaType, errors.New("POST bodies must be application/json"))
+			return
+		}
+	default:
+		serveError(w, http.StatusMethodNotAllowed, errors.New("use GET or POST"))
+		return
+	}
+	params, err := requestParams(r)
+	if err != nil {
+		serveError(w, http.StatusBadRequest, err)
+		return
+	}
+	if r.Method == http.MethodGet {
+		for name := range params {
+			if serveWriteParams[name] {
+				serveError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s changes your account; use POST", name))
+				return
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	args, err = s.applyParams(cmd, args, params)
+	if err == nil {
+		if n, ok := serveTextArgs[cmd.Name]; ok && len(args) <= n && !(cmd.Name == "post" && *flagMedia != "") {
+			err = errors.New(`missing "text"`)
+		} else {
+			err = cmd.checkArgs(args)
+		}
+	}
+	if err == nil {
+		err = configureFilter()
+	}
+	if err != nil {
+		serveError(w, http.StatusBadRequest, err)
+		return
+	}
+	ctx, cancel := requestContext()
+	defer cancel()
+	data, err := cmd.Run(ctx, s.client, args)
+	err = explainScopeError(cmd.formatKey(args), err)
+	if err != nil {
+		status := http.StatusBadGateway
+		var apiErr *mastodon.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode >= 400 && apiErr.StatusCode < 500 {
+			status = apiErr.StatusCode
+		}
+		serveError(w, status, err)
+		return
+	}
+	serveJSON(w, http.StatusOK, MastodonResponse{Success: true, Data: sortResults(activeFilter.apply(data))})
+}
+
+// applyParams resets the flags to the session's baseline and applies a
+// request's parameters, returning the command's arguments.
+func (s *apiServer) applyParams(cmd *command, args []string, params map[string][]string) ([]string, error) {
+	allowed := withFlags(cmd.Flags, serveFilterFlags)
+	cmdFlags = flagSet(cmd.Name, allowed)
+	activeFilter = nil
+	for _, name := range allowed {
+		f := flag.Lookup(name)
+		if l, ok := f.Value.(*stringList); ok {
+			*l = nil
+		} else {
+			f.Value.Set(s.baseline[name])
+		}
+	}
+	for name, values := range params {
+		if name == "q" || name == "text" {
+			args = append(args, values...)
+			continue
+		}
+		if cmdFlags.Lookup(name) == nil {
+			return nil, fmt.Errorf("unknown parameter %q for %s", name, cmd.Name)
+		}
+		for _, v := range values {
+			if err := cmdFlags.Set(name, v); err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", name, err)
+			}
+		}
+	}
+	return args, nil
+}
+
+// requestParams reads the query string, merged with a POST's JSON object
+// whose values may be strings, numbers, booleans, or arrays of them.
+func requestParams(r *http.Request) (map[string][]string, error) {
+	params := map[string][]string(r.URL.Query())
+	if r.Method != http.MethodPost || r.ContentLength == 0 {
+		return params, nil
+	}
+	var body map[string]interface{}
+	dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	dec.UseNumber()
+	if err := dec.Decode(&body); err != nil {
+		return nil, fmt.Errorf("reading JSON body: %w", err)
+	}
+	for name, v := range body {
+		values, ok := v.([]interface{})
+		if !ok {
+			values = []interface{}{v}
+		}
+		for _, item := range values {
+			switch item := item.(type) {
+			case string:
+				params[name] = append(params[name], item)
+			case json.Number:
+				params[name] = append(params[name], item.String())
+			case bool:
+				params[name] = append(params[name], strconv.FormatBool(item))
+			default:
+				return nil, fmt.Errorf("unsupported value for %q", name)
+			}
+		}
+	}
+	return params, nil
+}
+
+// localHost reports whether a request's Host names this machine.
+func localHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsUnspecified() || isLocalAddr(ip))
+}
+
+// isLocalAddr reports whether ip belongs to one of this machine's
+// interfaces, for servers listening beyond loopback.
+func isLocalAddr(ip net.IP) bool {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok && n.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// serveCommandNames lists the commands the API serves.
+func serveCommandNames() []string {
+	var names []string
+	for _, c := range commands {
+		if !serveExcluded[c.Name] && !c.NoAuth {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+func serveJSON(w http.ResponseWriter, status int, resp MastodonResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func serveError(w http.ResponseWriter, status int, err error) {
+	serveJSON(w, status, errorResponse(err))
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServeGET(t *testing.T) {
+	tests := []struct {
+		path   string
+		wantOK bool // whether GET may run it
+	}{
+		{"/home", true},
+		{"/whoami", true},
+		{"/count/hello", true},
+		{"/markers", true},
+		{"/markers/get/home", true},
+		{"/markers/set/home", false},
+		{"/audit", true},
+		{"/preferences", true},
+		{"/filters", true},
+		{"/filters/list", true},
+		{"/filters/delete/Spoilers", false},
+		{"/scheduled/list", true},
+		{"/scheduled/cancel/42", false},
+		{"/lists", true},
+		{"/lists/timeline/Friends", true},
+		{"/lists/delete/Friends", false},
+		{"/compare-instances/a.example/b.example", true},
+		{"/instance/peers", true},
+		{"/trends/links", true},
+		{"/conversations", true},
+		{"/post", false},
+		{"/follow/@alice", false},
+		{"/profile/set", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			args := strings.Split(strings.Trim(tt.path, "/"), "/")
+			if got := lookupCommand(args[0]).readOnly(args[1:]); got != tt.wantOK {
+				t.Errorf("readOnly = %v, want %v", got, tt.wantOK)
+			}
+			if tt.wantOK {
+				return
+			}
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Host = "127.0.0.1:9877"
+			rec := httptest.NewRecorder()
+			(&apiServer{}).ServeHTTP(rec, req)
+			if rec.Code != http.StatusMethodNotAllowed {
+				t.Errorf("GET %s = %d, want 405", tt.path, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting is a global flag whose default can also come from an environment
+// variable or the [defaults] table of config.toml. A flag on the command
+// line beats the environment, which beats the config file.
+type setting struct {
+	key   string // in [defaults] and for `config get|set`
+	flag  string
+	env   string
+	check func(string) error
+}
+
+var settings = []setting{
+	{"instance", "instance", "MASTODON_INSTANCE", checkInstance},
+	{"limit", "limit", "MASTODON_LIMIT", checkLimit},
+	{"output", "output", "MASTODON_OUTPUT", checkOutput},
+	{"timezone", "timezone", "MASTODON_TIMEZONE", checkTimezone},
+	{"color", "color", "MASTODON_COLOR", checkColor},
+}
+
+// Where a setting's value came from.
+const (
+	sourceFlag    = "flag"
+	sourceEnv     = "env"
+	sourceConfig  = "config"
+	sourceAccount = "account"
+	sourceDefault = "default"
+)
+
+// settingSources records, by key, where each setting's value came from.
+var settingSources = map[string]string{}
+
+// configKeys are the top-level keys of config.toml outside [defaults].
+var configKeys = map[string]func(string) error{
+	"default_account":  func(string) error { return nil },
+	"require_alt_text": checkBool,
+}
+
+func lookupSetting(key string) *setting {
+	for i := range settings {
+		if settings[i].key == key {
+			return &settings[i]
+		}
+	}
+	return nil
+}
+
+// applyDefaults fills in the settings not given as flags, from the
+// environment or else the config file.
+func applyDefaults(cfg *Config) error {
+	defaults := cfg.Table("defaults")
+	for key := range defaults {
+		if lookupSetting(key) == nil {
+			return fmt.Errorf("unknown setting %q in [defaults] (known: %s)", key, strings.Join(settingKeys(), ", "))
+		}
+	}
+	for _, s := range settings {
+		if flagWasSet(s.flag) || (s.key == "output" && (flagWasSet("json") || flagWasSet("format"))) {
+			settingSources[s.key] = sourceFlag
+			continue
+		}
+		value, source, origin := os.Getenv(s.env), sourceEnv, s.env
+		if value == "" {
+			value, source, origin = defaults[s.key], sourceConfig, "[defaults] "+s.key
+		}
+		if value == "" {
+			settingSources[s.key] = sourceDefault
+			continue
+		}
+		if err := s.check(value); err != nil {
+			return fmt.Errorf("%s: %w", origin, err)
+		}
+		if err := flag.Set(s.flag, value); err != nil {
+			return fmt.Errorf("%s: %w", origin, err)
+		}
+		settingSources[s.key] = source
+	}
+	return nil
+}
+
+// configureTimezone makes --timezone the zone of local timestamps. Without
+// it the runtime's zone stands, which honors TZ in every form the system
+// does, POSIX rules included.
+func configureTimezone() error {
+	if *flagTimezone == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(*flagTimezone)
+	if err != nil {
+		return fmt.Errorf("invalid --timezone %q: %w", *flagTimezone, err)
+	}
+	time.Local = loc
+	return nil
+}
+
+func settingKeys() []string {
+	keys := make([]string, 0, len(settings))
+	for _, s := range settings {
+		keys = append(keys, s.key)
+	}
+	return keys
+}
+
+func checkInstance(value string) error {
+	_, err := normalizeInstanceURL(value)
+	return err
+}
+
+func checkLimit(value string) error {
+	if n, err := strconv.Atoi(value); err != nil || n < 1 {
+		return fmt.Errorf("invalid limit %q (want a positive number)", value)
+	}
+	return nil
+}
+
+func checkOutput(value string) error {
+	switch value {
+	case "text", "json", "ndjson", "csv", "tsv", "rss", "atom", "markdown":
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q (want text, json, ndjson, csv, tsv, rss, atom, or markdown)", value)
+}
+
+func checkTimezone(value string) error {
+	if _, err := time.LoadLocation(value); err != nil {
+		return fmt.Errorf("unknown time zone %q (want an IANA name such as Europe/Berlin)", value)
+	}
+	return nil
+}
+
+func checkColor(value string) error {
+	switch value {
+	case "auto", "always", "never":
+		return nil
+	}
+	return fmt.Errorf("invalid color %q (want auto, always, or never)", value)
+}
+
+func checkBool(value string) error {
+	if _, err := strconv.ParseBool(value); err != nil {
+		return fmt.Errorf("invalid value %q (want true or false)", value)
+	}
+	return nil
+}
+
+// ConfigValue is a setting or config file entry, for config get and list.
+type ConfigValue struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source,omitempty"`
+}
+
+// ConfigSetResult is the result of config set.
+type ConfigSetResult struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Path  string `json:"path"`
+}
+
+// runConfig handles `config [list]`, `config get <key>`, and
+// `config set <key> <value>`. Keys are the settings, the top-level keys,
+// and table.key for anything else, such as alias.h or accounts.work.instance.
+func runConfig(args []string) (interface{}, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	action := "list"
+	if len(args) > 0 {
+		action = args[0]
+	}
+	switch action {
+	case "list":
+		return configValues(cfg), nil
+	case "get":
+		if len(args) < 2 {
+			return nil, errors.New("config get requires a key")
+		}
+		for _, v := range configValues(cfg) {
+			if v.Key == args[1] {
+				return v, nil
+			}
+		}
+		return nil, fmt.Errorf("%s is not set", args[1])
+	case "set":
+		if len(args) < 3 {
+			return nil, errors.New("config set requires a key and a value")
+		}
+		return setConfig(args[1], args[2])
+	}
+	return nil, fmt.Errorf("unknown config action %q (want list, get, or set)", action)
+}
+
+// configValues lists the settings with their effective values and sources,
+// then everything else in the config file. Tokens are redacted.
+func configValues(cfg *Config) []ConfigValue {
+	values := []ConfigValue{}
+	for _, s := range settings {
+		f := flag.Lookup(s.flag)
+		values = append(values, ConfigValue{Key: s.key, Value: f.Value.String(), Source: settingSources[s.key]})
+	}
+	names := make([]string, 0, len(cfg.tables))
+	for name := range cfg.tables {
+		if name != "defaults" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		keys := make([]string, 0, len(cfg.tables[name]))
+		for key := range cfg.tables[name] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value := cfg.tables[name][key]
+			if key == "token" {
+				value = redacted
+			}
+			if name != "" {
+				key = name + "." + key
+			}
+			values = append(values, ConfigValue{Key: key, Value: value, Source: sourceConfig})
+		}
+	}
+	return values
+}
+
+// setConfig checks value for key and writes it to config.toml.
+func setConfig(key, value string) (ConfigSetResult, error) {
+	table, name := "defaults", key
+	if s := lookupSetting(key); s != nil {
+		if err := s.check(value); err != nil {
+			return ConfigSetResult{}, err
+		}
+		if key == "instance" {
+			value, _ = normalizeInstanceURL(value)
+		}
+	} else if check, ok := configKeys[key]; ok {
+		if err := check(value); err != nil {
+			return ConfigSetResult{}, err
+		}
+		table = ""
+	} else if i := strings.LastIndex(key, "."); i > 0 && i < len(key)-1 {
+		table, name = key[:i], key[i+1:]
+		if table == "defaults" {
+			return ConfigSetResult{}, fmt.Errorf("unknown setting %q (known: %s)", name, strings.Join(settingKeys(), ", "))
+		}
+	} else {
+		known := append(settingKeys(), "default_account", "require_alt_text")
+		return ConfigSetResult{}, fmt.Errorf("unknown key %q (known: %s, or table.key)", key, strings.Join(known, ", "))
+	}
+	path, err := setConfigValue(table, name, value)
+	if err != nil {
+		return ConfigSetResult{}, err
+	}
+	return ConfigSetResult{Key: key, Value: value, Path: path}, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+)
+
+// errInterrupted is why runContext is canceled on SIGINT or SIGTERM.
+var errInterrupted = errors.New("interrupted")
+
+// runContext is the context every command runs under. The first SIGINT or
+// SIGTERM cancels it, so that a command stops fetching but still prints
+// what it has and saves its state; a second one exits at once.
+var runContext, cancelRun = context.WithCancelCause(context.Background())
+
+// handleSignals starts watching for SIGINT and SIGTERM.
+func handleSignals() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		notef("Interrupted; finishing up (interrupt again to quit at once)\n")
+		cancelRun(errInterrupted)
+		<-signals
+		os.Exit(exitCodes[codeInterrupted])
+	}()
+}
+
+// interrupted reports whether a signal has canceled runContext.
+func interrupted() bool {
+	return errors.Is(context.Cause(runContext), errInterrupted)
+}
+
+// exitIfInterrupted exits with the interrupted status if a signal stopped
+// the command, once it has printed and saved what it had.
+func exitIfInterrupted() {
+	if interrupted() {
+		os.Exit(exitCodes[codeInterrupted])
+	}
+}
+
+// partialResult reports whether data, returned with an error, holds
+// anything worth printing: a non-empty list, or a summary of the work done.
+func partialResult(data interface{}) bool {
+	if data == nil {
+		return false
+	}
+	v := reflect.ValueOf(data)
+	if v.Kind() == reflect.Slice {
+		return v.Len() > 0
+	}
+	return true
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+const (
+	streamInitialBackoff = time.Second
+	streamMaxBackoff     = time.Minute
+)
+
+// streamParams maps the CLI timeline name (and optional argument) to the
+// streaming API's stream query parameters.
+func streamParams(args []string) (url.Values, error) {
+	if len(args) == 0 {
+		return nil, errors.New("stream command requires a timeline (user, public, local, federated, tag <name>, list <id>, direct, notifications)")
+	}
+	q := url.Values{}
+	switch args[0] {
+	case "user", "public", "direct":
+		q.Set("stream", args[0])
+	case "local":
+		q.Set("stream", "public:local")
+	case "federated", "remote":
+		q.Set("stream", "public:remote")
+	case "notifications":
+		q.Set("stream", "user:notification")
+	case "tag", "hashtag":
+		if len(args) < 2 {
+			return nil, errors.New("stream tag requires a hashtag")
+		}
+		q.Set("stream", "hashtag")
+		q.Set("tag", strings.TrimPrefix(args[1], "#"))
+	case "list":
+		if len(args) < 2 {
+			return nil, errors.New("stream list requires a list ID")
+		}
+		q.Set("stream", "list")
+		q.Set("list", args[1])
+	default:
+		return nil, fmt.Errorf("unknown stream timeline: %s", args[0])
+	}
+	return q, nil
+}
+
+// runStream connects to the streaming API and prints events until ctx is
+// cancelled, reconnecting with exponential backoff whenever the connection
+// drops.
+func runStream(ctx context.Context, client *mastodon.Client, args []string) error {
+	params, err := streamParams(args)
+	if err != nil {
+		return err
+	}
+	lookupCtx, cancel := withTimeout(ctx)
+	streamURL := client.StreamingURL(lookupCtx)
+	cancel()
+
+	backoff := streamInitialBackoff
+	count := 0
+	for {
+		stream, err := client.OpenStream(ctx, streamURL, params)
+		if err == nil {
+			backoff = streamInitialBackoff
+			err = readStream(stream, &count)
+			stream.Close()
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		notef("Stream disconnected (%v); reconnecting in %s\n", err, backoff)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > streamMaxBackoff {
+			backoff = streamMaxBackoff
+		}
+	}
+}
+
+// readStream emits events from stream until it fails. count numbers the
+// statuses printed in text mode across reconnects.
+func readStream(stream *mastodon.Stream, count *int) error {
+	for {
+		event, err := stream.Next()
+		if err != nil {
+			return err
+		}
+		emitStreamEvent(event, count)
+	}
+}
+
+func emitStreamEvent(event mastodon.StreamEvent, count *int) {
+	archiveItems(streamEventItem(event))
+	if !streamEventKept(event) {
+		return
+	}
+	if forwarding() {
+		defer forwardItem(streamEventItem(event))
+	}
+	if f := outputFormat(); f == "json" || f == "ndjson" {
+		line, err := json.Marshal(event)
+		if err == nil {
+			fmt.Println(string(line))
+		}
+		return
+	}
+	switch event.Event {
+	case "update", "status.update":
+		var s mastodon.Status
+		if json.Unmarshal(event.Payload, &s) != nil {
+			return
+		}
+		if outputTemplate != nil {
+			writeTemplate(s)
+			return
+		}
+		*count++
+		formatStatus(*count, s)
+	case "notification":
+		var n mastodon.Notification
+		if json.Unmarshal(event.Payload, &n) != nil {
+			return
+		}
+		if outputTemplate != nil {
+			writeTemplate(n)
+			return
+		}
+		*count++
+		formatNotification(*count, n)
+	case "delete":
+		if outputTemplate != nil {
+			return
+		}
+		var id string
+		if json.Unmarshal(event.Payload, &id) == nil {
+			fmt.Printf("🗑 Post %s was deleted\n\n", id)
+		}
+	}
+}
+
+// streamEventKept applies the post filter to the post an update or
+// notification carries; other events always pass.
+func streamEventKept(event mastodon.StreamEvent) bool {
+	if activeFilter == nil {
+		return true
+	}
+	switch event.Event {
+	case "update", "status.update":
+		var s mastodon.Status
+		return json.Unmarshal(event.Payload, &s) != nil || activeFilter.keep(s)
+	case "notification":
+		var n mastodon.Notification
+		if json.Unmarshal(event.Payload, &n) != nil {
+			return true
+		}
+		return activeFilter.keepNotification(n)
+	}
+	return true
+}
+
+// streamEventItem decodes the post or notification an event carries, or
+// returns nil for other events.
+func streamEventItem(event mastodon.StreamEvent) interface{} {
+	switch event.Event {
+	case "update", "status.update":
+		var s mastodon.Status
+		if json.Unmarshal(event.Payload, &s) == nil {
+			return s
+		}
+	case "notification":
+		var n mastodon.Notification
+		if json.Unmarshal(event.Payload, &n) == nil {
+			return n
+		}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// postThread publishes text as a chain of self-replies, split to fit the
+// instance's character limit and numbered "1/n". Each part keeps the
+// visibility, content warning, and language; attachments go on the first.
+// Text that fits in one post is posted as is.
+func postThread(ctx context.Context, client *mastodon.Client, text string) (interface{}, error) {
+	if *flagSchedule != "" || len(flagPollOptions) > 0 {
+		return nil, errors.New("--thread can't be combined with --schedule or --poll-option")
+	}
+	if *flagVisibility != "" {
+		if err := mastodon.ValidateVisibility(*flagVisibility); err != nil {
+			return nil, err
+		}
+	}
+	limit, urlLength, _ := postLimits(ctx, client)
+	// The content warning counts toward every part's limit.
+	limit -= postLength(*flagSpoiler, urlLength)
+	parts, err := splitThread(text, limit, urlLength)
+	if err != nil {
+		return nil, err
+	}
+	mediaIDs, err := attachMedia(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	posted := []mastodon.Status{}
+	for i, part := range parts {
+		params := mastodon.StatusParams{
+			Status:      part,
+			Visibility:  *flagVisibility,
+			SpoilerText: *flagSpoiler,
+			Language:    *flagLanguage,
+		}
+		if *flagIdempotency != "" {
+			params.IdempotencyKey = fmt.Sprintf("%s-%d", *flagIdempotency, i+1)
+		}
+		if i == 0 {
+			params.MediaIDs = mediaIDs
+		} else {
+			params.InReplyToID = posted[i-1].ID
+		}
+		status, err := client.PostStatus(ctx, params)
+		if err != nil {
+			if len(posted) > 0 {
+				return nil, fmt.Errorf("posting part %d of %d: %w (parts 1-%d were posted, the last as %s)", i+1, len(parts), err, i, posted[i-1].ID)
+			}
+			return nil, err
+		}
+		posted = append(posted, *status)
+	}
+	return posted, nil
+}
+
+// splitThread splits text into posts of at most limit characters, as the
+// instance counts them, and numbers them when there is more than one. Cuts
+// fall between sentences, or failing that between words.
+func splitThread(text string, limit, urlLength int) ([]string, error) {
+	if postLength(text, urlLength) <= limit {
+		return []string{text}, nil
+	}
+	segments := threadSegments(text)
+	// Reserve room for the "\n\ni/n" numbering, which grows with n.
+	for digits := 1; ; digits++ {
+		budget := limit - (3 + 2*digits)
+		if budget < 20 {
+			return nil, fmt.Errorf("the character limit of %d leaves no room to split the text", limit)
+		}
+		parts := packSegments(segments, budget, urlLength)
+		if len(strconv.Itoa(len(parts))) > digits {
+			continue
+		}
+		for i := range parts {
+			parts[i] += fmt.Sprintf("\n\n%d/%d", i+1, len(parts))
+		}
+		return parts, nil
+	}
+}
+
+// threadSegment is a sentence or line of text, with the whitespace that
+// separates it from the one before.
+type threadSegment struct {
+	sep, text string
+}
+
+// threadSegments breaks text at line breaks and after sentences.
+func threadSegments(text string) []threadSegment {
+	runes := []rune(strings.TrimSpace(text))
+	var segments []threadSegment
+	start, sep := 0, ""
+	for i := 0; i < len(runes); {
+		if !unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && unicode.IsSpace(runes[j]) {
+			j++
+		}
+		space := string(runes[i:j])
+		if strings.Contains(space, "\n") || endsSentence(runes[start:i]) {
+			segments = append(segments, threadSegment{sep, string(runes[start:i])})
+			start, sep = j, lineBreak(space)
+		}
+		i = j
+	}
+	return append(segments, threadSegment{sep, string(runes[start:])})
+}
+
+// endsSentence reports whether s ends with sentence punctuation, possibly
+// followed by closing quotes or brackets.
+func endsSentence(s []rune) bool {
+	i := len(s) - 1
+	for i >= 0 && strings.ContainsRune(`"'”’)]»`, s[i]) {
+		i--
+	}
+	return i >= 0 && strings.ContainsRune(".!?…", s[i])
+}
+
+// lineBreak keeps paragraph and line breaks between segments and turns any
+// other whitespace into a space.
+func lineBreak(space string) string {
+	switch strings.Count(space, "\n") {
+	case 0:
+		return " "
+	case 1:
+		return "\n"
+	}
+	return "\n\n"
+}
+
+// packSegments fills posts of at most budget characters with segments in
+// order. Segments too long for a post are split into words, and words
+// into runs of characters.
+func packSegments(segments []threadSegment, budget, urlLength int) []string {
+	var parts []string
+	var current string
+	add := func(seg threadSegment) {
+		switch {
+		case current == "":
+			current = seg.text
+		case postLength(current+seg.sep+seg.text, urlLength) <= budget:
+			current += seg.sep + seg.text
+		default:
+			parts = append(parts, current)
+			current = seg.text
+		}
+	}
+	for _, seg := range segments {
+		if postLength(seg.text, urlLength) <= budget {
+			add(seg)
+			continue
+		}
+		sep := seg.sep
+		for _, word := range strings.Fields(seg.text) {
+			for _, piece := range splitWord(word, budget, urlLength) {
+				add(threadSegment{sep, piece})
+				sep = " "
+			}
+		}
+	}
+	if current != "" {
+		parts = append(parts, current)
+	}
+	return parts
+}
+
+// splitWord cuts a word longer than budget into pieces that fit.
+func splitWord(word string, budget, urlLength int) []string {
+	if postLength(word, urlLength) <= budget {
+		return []string{word}
+	}
+	var pieces []string
+	runes := []rune(word)
+	for len(runes) > budget {
+		pieces = append(pieces, string(runes[:budget]))
+		runes = runes[budget:]
+	}
+	return append(pieces, string(runes))
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// redacted stands in for tokens and secrets in logs and traces.
+const redacted = "[REDACTED]"
+
+// secretHeaders are never logged as they are.
+var secretHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"Set-Cookie":    true,
+}
+
+// secretFields are the query, form, and JSON fields that carry credentials.
+var secretFields = []string{"access_token", "client_secret", "password"}
+
+var secretJSONPattern = regexp.MustCompile(`("(?:` + strings.Join(secretFields, "|") + `)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// tracingTransport logs the requests made through the shared HTTP client:
+// a line per request with --verbose, its headers as well with --debug, and
+// the whole exchange, bodies included, in the --trace-file and the --har
+// archive.
+type tracingTransport struct {
+	next    http.RoundTripper
+	verbose bool
+	headers bool
+	trace   io.Writer
+	har     *harRecorder
+
+	mu sync.Mutex
+}
+
+// configureTracing wraps the shared HTTP client's transport for --verbose,
+// --debug, --trace-file, and --har. It runs after configureTransport so that
+// traces show what actually goes over the wire.
+func configureTracing() error {
+	verbose := *flagVerbose || *flagDebug
+	if !verbose && *flagTraceFile == "" && *flagHAR == "" {
+		return nil
+	}
+	t := &tracingTransport{next: httpClient.Transport, verbose: verbose, headers: *flagDebug}
+	if *flagTraceFile != "" {
+		f, err := os.OpenFile(*flagTraceFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return fmt.Errorf("--trace-file: %w", err)
+		}
+		t.trace = f
+	}
+	if *flagHAR != "" {
+		t.har = newHARRecorder(*flagHAR)
+	}
+	httpClient.Transport = t
+	return nil
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	capture := t.trace != nil || t.har != nil
+	var reqBody []byte
+	if capture && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			reqBody, _ = io.ReadAll(body)
+			body.Close()
+		}
+	}
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	var respBody []byte
+	if err == nil && capture && textBody(resp.Header.Get("Content-Type")) {
+		respBody, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			resp = nil
+		} else {
+			resp.Body = io.NopCloser(bytes.NewReader(respBody))
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.verbose {
+		t.logRequest(req, resp, err, elapsed)
+	}
+	if t.trace != nil {
+		t.writeTrace(req, reqBody, resp, respBody, err, elapsed)
+	}
+	if t.har != nil {
+		t.har.record(start, req, reqBody, resp, respBody, err, elapsed)
+	}
+	return resp, err
+}
+
+// logRequest writes the --verbose line for a request, and with --debug its
+// headers, to stderr.
+func (t *tracingTransport) logRequest(req *http.Request, resp *http.Response, err error, elapsed time.Duration) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s ", req.Method, redactURL(req.URL))
+	if err != nil {
+		fmt.Fprintf(&b, "failed after %s: %v\n", elapsed, err)
+	} else {
+		fmt.Fprintf(&b, "→ %s in %s", resp.Status, elapsed)
+		if rl, ok := mastodon.ParseRateLimit(resp.Header); ok {
+			fmt.Fprintf(&b, " (rate limit %d/%d, resets %s)", rl.Remaining, rl.Limit, rl.Reset.Local().Format("15:04:05"))
+		}
+		b.WriteString("\n")
+	}
+	if t.headers {
+		writeHeaders(&b, "  > ", req.Header)
+		if resp != nil {
+			writeHeaders(&b, "  < ", resp.Header)
+		}
+	}
+	fmt.Fprint(os.Stderr, b.String())
+}
+
+// writeTrace appends a request and its response to the --trace-file.
+func (t *tracingTransport) writeTrace(req *http.Request, reqBody []byte, resp *http.Response, respBody []byte, err error, elapsed time.Duration) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== %s %s %s\n", time.Now().Format(time.RFC3339), req.Method, redactURL(req.URL))
+	writeHeaders(&b, "> ", req.Header)
+	writeBody(&b, req.Header.Get("Content-Type"), reqBody, req.ContentLength)
+	if err != nil {
+		fmt.Fprintf(&b, "! failed after %s: %v\n\n", elapsed, err)
+		fmt.Fprint(t.trace, b.String())
+		return
+	}
+	fmt.Fprintf(&b, "< %s %s (%s)\n", resp.Proto, resp.Status, elapsed)
+	writeHeaders(&b, "< ", resp.Header)
+	writeBody(&b, resp.Header.Get("Content-Type"), respBody, resp.ContentLength)
+	b.WriteString("\n")
+	fmt.Fprint(t.trace, b.String())
+}
+
+// writeHeaders writes h one header per line, sorted, with credentials
+// redacted.
+func writeHeaders(b *strings.Builder, prefix string, h http.Header) {
+	for _, header := range harHeaders(h) {
+		fmt.Fprintf(b, "%s%s: %s\n", prefix, header.Name, header.Value)
+	}
+}
+
+// writeBody writes a text body with credentials redacted, or a note of its
+// size for binary bodies and those that weren't captured, such as streams.
+func writeBody(b *strings.Builder, contentType string, body []byte, length int64) {
+	switch {
+	case len(body) > 0 && textBody(contentType):
+		b.WriteString("\n")
+		b.WriteString(redactBody(string(body)))
+		b.WriteString("\n")
+	case len(body) > 0:
+		fmt.Fprintf(b, "\n[%d bytes of %s]\n", len(body), contentType)
+	case length > 0 || contentType != "":
+		fmt.Fprintf(b, "\n[body of %s not captured]\n", contentType)
+	}
+}
+
+// textBody reports whether a body of this type is worth capturing. Event
+// streams never end, so they are left alone.
+func textBody(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case mediaType == "text/event-stream":
+		return false
+	case strings.HasPrefix(mediaType, "text/"), mediaType == "application/x-www-form-urlencoded":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "json") || strings.HasSuffix(mediaType, "xml")
+}
+
+// redactURL hides a token passed in the query string.
+func redactURL(u *url.URL) string {
+	q := u.Query()
+	changed := false
+	for _, field := range secretFields {
+		if q.Has(field) {
+			q.Set(field, redacted)
+			changed = true
+		}
+	}
+	if !changed {
+		return u.String()
+	}
+	copied := *u
+	copied.RawQuery = q.Encode()
+	return copied.String()
+}
+
+// redactHeader hides a secret header's value but keeps the scheme of an
+// Authorization header, so a trace still shows that a token was sent.
+func redactHeader(name, value string) string {
+	if scheme, _, ok := strings.Cut(value, " "); ok && name == "Authorization" {
+		return scheme + " " + redacted
+	}
+	return redacted
+}
+
+// redactBody hides credentials in JSON and form bodies.
+func redactBody(body string) string {
+	body = secretJSONPattern.ReplaceAllString(body, `$1"`+redacted+`"`)
+	if form, err := url.ParseQuery(body); err == nil && !strings.ContainsAny(body, "{[\n") {
+		changed := false
+		for _, field := range secretFields {
+			if form.Has(field) {
+				form.Set(field, redacted)
+				changed = true
+			}
+		}
+		if changed {
+			return form.Encode()
+		}
+	}
+	return body
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// runTrends handles "trends tags|posts|links"; tags is the default.
+func runTrends(ctx context.Context, client *mastodon.Client, args []string) (interface{}, error) {
+	kind := "tags"
+	if len(args) > 0 {
+		kind = args[0]
+	}
+	switch kind {
+	case "tags":
+		return client.TrendingTags(ctx, pageOptions())
+	case "posts", "statuses":
+		return client.TrendingStatuses(ctx, pageOptions())
+	case "links":
+		return client.TrendingLinks(ctx, pageOptions())
+	default:
+		return nil, fmt.Errorf("unknown trends type %q (want tags, posts, or links)", kind)
+	}
+}
+
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline renders daily usage oldest to newest. The API lists history
+// newest first.
+func sparkline(history []mastodon.TagHistory) (line string, total, accounts int) {
+	values := make([]int, len(history))
+	peak := 0
+	for i, h := range history {
+		uses, _ := strconv.Atoi(h.Uses)
+		people, _ := strconv.Atoi(h.Accounts)
+		values[len(history)-1-i] = uses
+		total += uses
+		accounts += people
+		peak = max(peak, uses)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		i := 0
+		if peak > 0 {
+			i = v * (len(sparkBars) - 1) / peak
+		}
+		b.WriteRune(sparkBars[i])
+	}
+	return b.String(), total, accounts
+}
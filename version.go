@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// Build metadata, set with -ldflags "-X main.version=... -X main.commit=...
+// -X main.date=..." as goreleaser and make build do. Builds without them,
+// such as go install, fall back to what the Go toolchain recorded.
+var (
+	version string
+	commit  string
+	date    string
+)
+
+// releasesURL is where version --check looks for the latest release.
+const releasesURL = "https://api.github.com/repos/patelhiren/mastodon-scout/releases/latest"
+
+// VersionInfo is the result of the version command.
+type VersionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+	// Latest is the newest release on GitHub, with --check.
+	Latest          *Release `json:"latest,omitempty"`
+	UpdateAvailable bool     `json:"update_available"`
+}
+
+// Release is a published release.
+type Release struct {
+	Version     string `json:"version"`
+	URL         string `json:"url"`
+	PublishedAt string `json:"published_at"`
+}
+
+// buildVersion is the version the binary was built as, or "devel".
+func buildVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "devel"
+}
+
+// buildInfo collects the build metadata: the ldflags values, and otherwise
+// the VCS details the toolchain stamped into the binary.
+func buildInfo() VersionInfo {
+	v := VersionInfo{
+		Version:   buildVersion(),
+		Commit:    commit,
+		Date:      date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return v
+	}
+	modified := false
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if v.Commit == "" {
+				v.Commit = s.Value
+			}
+		case "vcs.time":
+			if v.Date == "" {
+				v.Date = s.Value
+			}
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	if modified && commit == "" && v.Commit != "" {
+		v.Commit += "-dirty"
+	}
+	return v
+}
+
+// runVersion reports the build, and with --check how it compares to the
+// latest release.
+func runVersion() (interface{}, error) {
+	v := buildInfo()
+	if !*flagCheck {
+		return v, nil
+	}
+	ctx, cancel := requestContext()
+	defer cancel()
+	latest, err := latestRelease(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("checking for updates: %w", err)
+	}
+	v.Latest = latest
+	v.UpdateAvailable = newerVersion(latest.Version, v.Version)
+	return v, nil
+}
+
+// latestRelease asks GitHub for the newest published release.
+func latestRelease(ctx context.Context) (*Release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, releasesURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("no releases published")
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("GET %s: %s", releasesURL, resp.Status)
+	}
+	var release struct {
+		TagName     string `json:"tag_name"`
+		HTMLURL     string `json:"html_url"`
+		PublishedAt string `json:"published_at"`
+	}
+	if err := json.Unmarshal(body, &release); err != nil {
+		return nil, err
+	}
+	return &Release{Version: release.TagName, URL: release.HTMLURL, PublishedAt: release.PublishedAt}, nil
+}
+
+var (
+	semverPattern = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+	// pseudoPattern matches the versions Go gives untagged commits.
+	pseudoPattern = regexp.MustCompile(`\d{14}-[0-9a-f]{12}`)
+)
+
+// newerVersion reports whether latest is a later release than current.
+// Development builds are never out of date, since they aren't releases.
+func newerVersion(latest, current string) bool {
+	l := semverPattern.FindStringSubmatch(latest)
+	c := semverPattern.FindStringSubmatch(current)
+	if l == nil || c == nil || pseudoPattern.MatchString(current) {
+		return false
+	}
+	for i := 1; i <= 3; i++ {
+		ln, _ := strconv.Atoi(l[i])
+		cn, _ := strconv.Atoi(c[i])
+		if ln != cn {
+			return ln > cn
+		}
+	}
+	// A release is newer than its own pre-releases.
+	return c[4] != "" && (l[4] == "" || strings.Compare(l[4], c[4]) > 0)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+const (
+	// defaultWatchInterval is how often --watch polls without a value.
+	defaultWatchInterval = time.Minute
+	// minWatchInterval keeps watchers well inside the API rate limit.
+	minWatchInterval = 10 * time.Second
+)
+
+// watchInterval is the --watch flag. It behaves like a boolean flag, so
+// --watch alone polls every minute and --watch=2m sets the interval.
+type watchInterval time.Duration
+
+func (w *watchInterval) String() string {
+	if *w == 0 {
+		return ""
+	}
+	return time.Duration(*w).String()
+}
+
+func (w *watchInterval) Set(v string) error {
+	switch v {
+	case "true":
+		*w = watchInterval(defaultWatchInterval)
+		return nil
+	case "false":
+		*w = 0
+		return nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return errors.New("want a duration such as 30s or 5m")
+	}
+	if d < minWatchInterval {
+		return fmt.Errorf("interval must be at least %s", minWatchInterval)
+	}
+	*w = watchInterval(d)
+	return nil
+}
+
+func (w *watchInterval) IsBoolFlag() bool { return true }
+
+// runWatch polls cmd until interrupted, printing only the items newer than
+// the last one seen. The newest ID is saved in a state file after every
+// poll, so the next run of the same command picks up where this one left
+// off. A first run prints the latest page.
+func runWatch(client *mastodon.Client, cmd *command, args []string, command, title string) error {
+	if cmd.Name == "search" && *flagSearchType != mastodon.SearchStatuses {
+		return errors.New("--watch only works when searching statuses")
+	}
+	key := watchKey(title)
+	state := loadWatchState()
+	since := *flagSinceID
+	if since == "" {
+		since = state[key]
+	}
+	for {
+		*flagSinceID = since
+		ctx, cancel := requestContext()
+		data, err := cmd.Run(ctx, client, args)
+		if err == nil {
+			archiveItems(data)
+			var newest string
+			data, newest = unseenItems(data, since)
+			data = sortResults(activeFilter.apply(data))
+			if len(resultItems(data)) > 0 {
+				if *flagDownloadMedia != "" {
+					err = downloadMedia(ctx, data, *flagDownloadMedia)
+				}
+				printResult(command, title, data)
+				forwardItems(data)
+			}
+			if newest != "" {
+				since = newest
+				state[key] = since
+				if serr := saveWatchState(state); serr != nil {
+					notef("Warning: %v\n", serr)
+				}
+			}
+		}
+		cancel()
+		if interrupted() {
+			// The state is saved after every poll, so the next watch picks
+			// up where this one stopped.
+			return nil
+		}
+		if err != nil {
+			notef("Poll failed (%v); retrying in %s\n", err, time.Duration(flagWatch))
+		}
+		select {
+		case <-time.After(time.Duration(flagWatch)):
+		case <-runContext.Done():
+			return nil
+		}
+	}
+}
+
+// unseenItems drops the items of data that are not newer than since (the
+// search API doesn't honor since_id) and puts the rest oldest first, so
+// successive polls read in order. It also returns the newest ID in data.
+func unseenItems(data interface{}, since string) (interface{}, string) {
+	var newest string
+	switch v := data.(type) {
+	case []mastodon.Status:
+		v, newest = unseen(v, since, func(s mastodon.Status) string { return s.ID })
+		return v, newest
+	case []mastodon.Notification:
+		v, newest = unseen(v, since, func(n mastodon.Notification) string { return n.ID })
+		return v, newest
+	case mastodon.SearchResult:
+		v.Statuses, newest = unseen(v.Statuses, since, func(s mastodon.Status) string { return s.ID })
+		return v, newest
+	}
+	return data, ""
+}
+
+func unseen[T any](items []T, since string, id func(T) string) ([]T, string) {
+	var newest string
+	fresh := []T{}
+	for i := len(items) - 1; i >= 0; i-- {
+		itemID := id(items[i])
+		if compareIDs(itemID, newest) > 0 {
+			newest = itemID
+		}
+		if since == "" || compareIDs(itemID, since) > 0 {
+			fresh = append(fresh, items[i])
+		}
+	}
+	return fresh, newest
+}
+
+// compareIDs orders Mastodon IDs, which are decimal strings of varying
+// length, returning -1, 0, or 1.
+func compareIDs(a, b string) int {
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// watchKey identifies a watched command line on an instance and account in
+// the state file.
+func watchKey(title string) string {
+	key := *flagInstanceURL
+	if activeAccount != nil {
+		key += " " + activeAccount.Name
+	}
+	return key + " " + title
+}
+
+// watchStatePath is watch.json in $XDG_STATE_HOME/mastodon-scout, or
+// ~/.local/state/mastodon-scout.
+func watchStatePath() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("locating home directory: %w", err)
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, appName, "watch.json"), nil
+}
+
+// loadWatchState reads the last seen IDs by watch key. A missing or
+// unreadable file starts afresh.
+func loadWatchState() map[string]string {
+	state := map[string]string{}
+	path, err := watchStatePath()
+	if err != nil {
+		return state
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &state)
+	}
+	return state
+}
+
+func saveWatchState(state map[string]string) error {
+	path, err := watchStatePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("saving watch state: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("saving watch state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/patelhiren/mastodon-scout/pkg/mastodon"
+)
+
+// Limits of the chat services' message formats.
+const (
+	slackTextLength     = 3000
+	discordTextLength   = 4096
+	discordAuthorLength = 256
+	discordTitleLength  = 256
+)
+
+// validateWebhookFormat checks --webhook-format.
+func validateWebhookFormat() error {
+	switch *flagWebhookFormat {
+	case "json", "slack", "discord":
+		return nil
+	}
+	return fmt.Errorf("invalid --webhook-format %q (want json, slack, or discord)", *flagWebhookFormat)
+}
+
+// webhookPayload renders an item as the raw API JSON, or as a Slack or
+// Discord message.
+func webhookPayload(item interface{}) ([]byte, error) {
+	var post *mastodon.Status
+	headline := ""
+	switch v := item.(type) {
+	case mastodon.Status:
+		post = &v
+	case mastodon.Notification:
+		post = v.Status
+		headline = ansiPattern.ReplaceAllString(notificationHeadline(v), "")
+	}
+	switch *flagWebhookFormat {
+	case "slack":
+		return json.Marshal(slackMessage(headline, post))
+	case "discord":
+		return json.Marshal(discordMessage(headline, post))
+	}
+	return json.Marshal(item)
+}
+
+// ansiPattern matches the SGR escapes paint adds to text output.
+var ansiPattern = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// webhookBody is a post's text for chat messages, with a content warning
+// in front and the text withheld like in text output.
+func webhookBody(post mastodon.Status) string {
+	text := post.ContentText()
+	if post.SpoilerText == "" {
+		return text
+	}
+	if cwHidden(post) {
+		return "⚠️ CW: " + post.SpoilerText
+	}
+	return "⚠️ CW: " + post.SpoilerText + "\n\n" + text
+}
+
+// webhookCounts is the replies, boosts, and favourites line of a message.
+func webhookCounts(post mastodon.Status) string {
+	return fmt.Sprintf("💬 %d · 🔁 %d · ⭐ %d", post.RepliesCount, post.ReblogsCount, post.FavouritesCount)
+}
+
+// webhookAuthor names a post's author as "Display Name (@acct)".
+func webhookAuthor(post mastodon.Status) string {
+	if post.Account.DisplayName == "" {
+		return "@" + post.Account.Acct
+	}
+	return fmt.Sprintf("%s (@%s)", post.Account.DisplayName, post.Account.Acct)
+}
+
+// slackMessage builds an incoming-webhook message with Block Kit sections.
+// text is the notification fallback Slack shows where blocks can't render.
+func slackMessage(headline string, s *mastodon.Status) map[string]interface{} {
+	var blocks []map[string]interface{}
+	mrkdwn := func(text string) map[string]interface{} {
+		return map[string]interface{}{"type": "mrkdwn", "text": text}
+	}
+	fallback := headline
+	if headline != "" {
+		blocks = append(blocks, map[string]interface{}{"type": "context", "elements": []interface{}{mrkdwn(slackEscape(headline))}})
+	}
+	if s != nil {
+		post, boostedBy := resolvePost(*s)
+		body := webhookBody(post)
+		if fallback == "" {
+			fallback = "@" + post.Account.Acct + ": " + truncate(strings.Join(strings.Fields(body), " "), 150)
+		}
+		author := fmt.Sprintf("*%s*", slackEscape(webhookAuthor(post)))
+		if post.Account.URL != "" {
+			author = fmt.Sprintf("*<%s|%s>*", post.Account.URL, slackEscape(webhookAuthor(post)))
+		}
+		if boostedBy != "" {
+			author = fmt.Sprintf("🔁 @%s boosted %s", slackEscape(boostedBy), author)
+		}
+		text := author
+		if body != "" {
+			text += "\n" + slackEscape(body)
+		}
+		blocks = append(blocks, map[string]interface{}{"type": "section", "text": mrkdwn(truncate(text, slackTextLength))})
+		footer := webhookCounts(post)
+		if post.URL != "" {
+			footer += fmt.Sprintf(" · <%s|View post>", post.URL)
+		}
+		blocks = append(blocks, map[string]interface{}{"type": "context", "elements": []interface{}{mrkdwn(footer)}})
+	}
+	return map[string]interface{}{"text": slackEscape(fallback), "blocks": blocks}
+}
+
+// slackEscape escapes the characters Slack's mrkdwn treats as markup.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// discordMessage builds an execute-webhook payload with one embed.
+func discordMessage(headline string, s *mastodon.Status) map[string]interface{} {
+	if s == nil {
+		return map[string]interface{}{"content": headline}
+	}
+	post, boostedBy := resolvePost(*s)
+	// Discord rejects empty URLs, so only set the ones the post has.
+	author := map[string]interface{}{"name": truncate(webhookAuthor(post), discordAuthorLength)}
+	if post.Account.URL != "" {
+		author["url"] = post.Account.URL
+	}
+	if post.Account.Avatar != "" {
+		author["icon_url"] = post.Account.Avatar
+	}
+	embed := map[string]interface{}{
+		"author":      author,
+		"description": truncate(webhookBody(post), discordTextLength),
+		"footer":      map[string]interface{}{"text": webhookCounts(post)},
+		"timestamp":   post.CreatedAt,
+	}
+	if post.URL != "" {
+		embed["url"] = post.URL
+		embed["title"] = truncate("Post by @"+post.Account.Acct, discordTitleLength)
+	}
+	for _, m := range post.MediaAttachments {
+		if m.Type == "image" && !post.Sensitive {
+			embed["image"] = map[string]interface{}{"url": m.URL}
+			break
+		}
+	}
+	content := headline
+	if boostedBy != "" && content == "" {
+		content = "🔁 @" + boostedBy + " boosted"
+	}
+	return map[string]interface{}{"content": content, "embeds": []interface{}{embed}}
+}
+
+// postWebhook POSTs one post or notification to --webhook-url in
+// --webhook-format. The
+// X-Mastodon-Scout-Event header says "status" or the notification type, and
+// with a secret X-Mastodon-Scout-Signature carries "sha256=" and the hex
+// HMAC-SHA256 of the body, like GitHub's webhook signatures.
+func postWebhook(item interface{}) error {
+	event := "status"
+	if n, ok := item.(mastodon.Notification); ok {
+		event = n.Type
+	}
+	body, err := webhookPayload(item)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := requestContext()
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, *flagWebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Mastodon-Scout-Event", event)
+	if secret := webhookSecret(); secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		req.Header.Set("X-Mastodon-Scout-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("POST %s: %s", *flagWebhookURL, resp.Status)
+	}
+	return nil
+}
+
+// webhookSecret is --webhook-secret, or MASTODON_WEBHOOK_SECRET so the
+// secret needn't appear in the process list.
+func webhookSecret() string {
+	if *flagWebhookSecret != "" {
+		return *flagWebhookSecret
+	}
+	return os.Getenv("MASTODON_WEBHOOK_SECRET")
+}